@@ -0,0 +1,116 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// alertSink forwards the matches of queries with an alert rule to a notification sink
+// only when the number of matches within the sliding window exceeds the count of the rule.
+// The alert is sent once with all matches of the window and is rearmed
+// as soon as the number of matches falls back to the count.
+type alertSink struct {
+	next  Sink
+	query func(name string) *config.Query
+
+	mu      sync.Mutex
+	windows map[string]*alertWindow
+}
+
+type alertWindow struct {
+	matches []alertMatch
+	firing  bool
+}
+
+type alertMatch struct {
+	time   time.Time
+	player PlayerExtended
+}
+
+// alertFilter wraps a notification sink in case any query has an alert rule.
+func (cli *CLI) alertFilter(s Sink) Sink {
+	for _, q := range cli.cfg.Queries() {
+		if q.AlertRule != nil {
+			return &alertSink{
+				next:    s,
+				query:   cli.cfg.Query,
+				windows: make(map[string]*alertWindow, 1),
+			}
+		}
+	}
+	return s
+}
+
+func (s *alertSink) Send(players PlayerExtendedList) {
+	// the windows are based on the timestamps of the matches, e.g. of scheduled scans
+	sorted := slices.Clone(players)
+	sortPlayers(sorted)
+	now := time.Now()
+
+	s.mu.Lock()
+	notify := make(PlayerExtendedList, 0, len(sorted))
+	for _, p := range sorted {
+		q := s.query(p.Query)
+		if q == nil || q.AlertRule == nil {
+			notify = append(notify, p)
+			continue
+		}
+
+		w, found := s.windows[p.Query]
+		if !found {
+			w = &alertWindow{}
+			s.windows[p.Query] = w
+		}
+
+		t := now
+		if p.Time != nil {
+			t = *p.Time
+		}
+		alert := w.add(q.AlertRule, t, p)
+		if len(alert) > 0 {
+			slog.Info("alert triggered", "query", p.Query, "rule", q.AlertRule.String(), "matches", len(alert))
+			notify = append(notify, alert...)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(notify) > 0 {
+		s.next.Send(notify)
+	}
+}
+
+func (s *alertSink) Close() error {
+	return s.next.Close()
+}
+
+// add appends the match to the window and returns the matches of the window
+// in case the count of the rule was exceeded.
+func (w *alertWindow) add(rule *config.AlertRule, t time.Time, p PlayerExtended) PlayerExtendedList {
+	w.matches = append(w.matches, alertMatch{time: t, player: p})
+
+	start := t.Add(-rule.Window)
+	expired := 0
+	for expired < len(w.matches) && w.matches[expired].time.Before(start) {
+		expired++
+	}
+	w.matches = slices.Delete(w.matches, 0, expired)
+
+	if len(w.matches) <= rule.Count {
+		w.firing = false
+		return nil
+	}
+	if w.firing {
+		return nil
+	}
+	w.firing = true
+
+	alert := make(PlayerExtendedList, 0, len(w.matches))
+	for _, m := range w.matches {
+		alert = append(alert, m.player)
+	}
+	return alert
+}
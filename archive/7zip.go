@@ -1,12 +1,10 @@
 package archive
 
 import (
-	"os"
-
 	"github.com/bodgit/sevenzip"
 )
 
-func Walk7Zip(file *os.File, fileSize int64, walkFunc WalkFunc) error {
+func Walk7Zip(file ArchiveFile, fileSize int64, walkFunc WalkFunc) error {
 	zfs, err := sevenzip.NewReader(file, fileSize)
 	if err != nil {
 		return err
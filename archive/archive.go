@@ -2,6 +2,7 @@ package archive
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/fs"
@@ -14,10 +15,29 @@ var (
 	ErrUnsupportedArchive = fmt.Errorf("unsupported archive")
 )
 
+// Formats are the supported archive formats, compressed formats are tar archives.
+var Formats = []string{"7z", "tar", "tar.bz2", "tar.gz", "tar.lz", "tar.xz", "tar.zst", "zip"}
+
 // WalkFunc defines the function in order to efficiently walk over the archive
 type WalkFunc func(path string, info fs.FileInfo, r io.Reader, err error) error
 
-func Walk(path string, walkcFunc WalkFunc) error {
+// ReadLimitFunc is called with the number of bytes after every read of the raw archive,
+// it blocks in order to throttle the reads.
+type ReadLimitFunc func(ctx context.Context, n int) error
+
+// WalkOption configures the walk over an archive.
+type WalkOption func(cf *contextFile)
+
+// WithReadLimit throttles the reads of the raw archive with the given function.
+func WithReadLimit(limit ReadLimitFunc) WalkOption {
+	return func(cf *contextFile) {
+		cf.limit = limit
+	}
+}
+
+// Walk iterates over all members of the archive at path.
+// Reading from the archive fails as soon as the context is canceled.
+func Walk(ctx context.Context, path string, walkcFunc WalkFunc, opts ...WalkOption) error {
 
 	f, err := os.Open(path)
 	if err != nil {
@@ -39,23 +59,28 @@ func Walk(path string, walkcFunc WalkFunc) error {
 		return fmt.Errorf("could not seek to start of file: %w", err)
 	}
 
+	cf := &contextFile{ctx: ctx, f: f}
+	for _, opt := range opts {
+		opt(cf)
+	}
+
 	switch mime.Extension() {
 	case ".7z":
-		return Walk7Zip(f, stat.Size(), walkcFunc)
+		return Walk7Zip(cf, stat.Size(), walkcFunc)
 	case ".gz":
-		return WalkTarGzip(f, walkcFunc)
+		return WalkTarGzip(cf, walkcFunc)
 	case ".tar":
-		return WalkTar(f, walkcFunc)
+		return WalkTar(cf, walkcFunc)
 	case ".zip":
-		return WalkZip(f, stat.Size(), walkcFunc)
+		return WalkZip(cf, stat.Size(), walkcFunc)
 	case ".xz":
-		return WalkTarXz(f, walkcFunc)
+		return WalkTarXz(cf, walkcFunc)
 	case ".zst":
-		return WalkTarZstd(f, walkcFunc)
+		return WalkTarZstd(cf, walkcFunc)
 	case ".bz2":
-		return WalkTarBzip2(f, walkcFunc)
+		return WalkTarBzip2(cf, walkcFunc)
 	case ".lz":
-		return WalkTarLz(f, walkcFunc)
+		return WalkTarLz(cf, walkcFunc)
 	}
 	return fmt.Errorf("%w: %s", ErrUnsupportedArchive, mime.Extension())
 }
@@ -79,3 +104,44 @@ func NewFile(fi io.Reader, size int64) (File, error) {
 
 	return bytes.NewReader(buf.Bytes()), nil
 }
+
+// ArchiveFile is the raw archive that is passed to the format specific walk functions.
+type ArchiveFile interface {
+	io.Reader
+	io.ReaderAt
+}
+
+// contextFile aborts any read operation as soon as the context is canceled.
+type contextFile struct {
+	ctx   context.Context
+	f     *os.File
+	limit ReadLimitFunc
+}
+
+func (cf *contextFile) Read(p []byte) (int, error) {
+	if err := context.Cause(cf.ctx); err != nil {
+		return 0, err
+	}
+	n, err := cf.f.Read(p)
+	return n, cf.throttle(n, err)
+}
+
+func (cf *contextFile) ReadAt(p []byte, off int64) (int, error) {
+	if err := context.Cause(cf.ctx); err != nil {
+		return 0, err
+	}
+	n, err := cf.f.ReadAt(p, off)
+	return n, cf.throttle(n, err)
+}
+
+// throttle waits for the read limit, read errors take precedence.
+func (cf *contextFile) throttle(n int, err error) error {
+	if cf.limit == nil || n == 0 {
+		return err
+	}
+	limitErr := cf.limit(cf.ctx, n)
+	if err != nil {
+		return err
+	}
+	return limitErr
+}
@@ -2,10 +2,9 @@ package archive
 
 import (
 	"compress/bzip2"
-	"os"
 )
 
-func WalkTarBzip2(file *os.File, walkFunc WalkFunc) error {
+func WalkTarBzip2(file ArchiveFile, walkFunc WalkFunc) error {
 	r := bzip2.NewReader(file)
 	return WalkTar(r, walkFunc)
 }
@@ -2,10 +2,9 @@ package archive
 
 import (
 	"compress/gzip"
-	"os"
 )
 
-func WalkTarGzip(file *os.File, walkFunc WalkFunc) error {
+func WalkTarGzip(file ArchiveFile, walkFunc WalkFunc) error {
 
 	r, err := gzip.NewReader(file)
 	if err != nil {
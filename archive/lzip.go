@@ -1,12 +1,10 @@
 package archive
 
 import (
-	"os"
-
 	"github.com/sorairolake/lzip-go"
 )
 
-func WalkTarLz(file *os.File, walkFunc WalkFunc) error {
+func WalkTarLz(file ArchiveFile, walkFunc WalkFunc) error {
 	r, err := lzip.NewReader(file)
 	if err != nil {
 		return err
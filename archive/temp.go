@@ -0,0 +1,101 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// MaxMemoryFileSize is the size up to which archive files are buffered in memory.
+// Larger files are extracted into the temp directory.
+const MaxMemoryFileSize = 64 * 1024 * 1024
+
+var (
+	ErrTempSpaceExceeded = errors.New("temp space exceeded")
+)
+
+// TempSpace limits the disk space that is used for extracting large archive files.
+// It is safe for concurrent use.
+type TempSpace struct {
+	dir     string
+	maxSize int64
+
+	mu   sync.Mutex
+	used int64
+}
+
+// NewTempSpace extracts files into dir, the system temp dir in case dir is empty.
+// A maxSize of 0 does not limit the used disk space.
+func NewTempSpace(dir string, maxSize int64) *TempSpace {
+	return &TempSpace{
+		dir:     dir,
+		maxSize: maxSize,
+	}
+}
+
+// NewFile buffers small files in memory and extracts large files into the temp directory.
+// The returned cleanup function must be called when the file is not needed anymore.
+func (t *TempSpace) NewFile(r io.Reader, size int64) (f File, cleanup func() error, err error) {
+	if size <= MaxMemoryFileSize {
+		f, err = NewFile(r, size)
+		return f, func() error { return nil }, err
+	}
+
+	err = t.reserve(size)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the reservation is released here on errors and by cleanup otherwise
+	defer func() {
+		if err != nil {
+			t.release(size)
+		}
+	}()
+
+	tmp, err := os.CreateTemp(t.dir, "twlog-who-said-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	remove := func() error {
+		return errors.Join(tmp.Close(), os.Remove(tmp.Name()))
+	}
+
+	written, err := io.Copy(tmp, io.LimitReader(r, size))
+	if err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("failed to extract file: %w", err), remove())
+	}
+	if written != size {
+		return nil, nil, errors.Join(fmt.Errorf("could not extract file from archive: size mismatch: expected %d, got %d", size, written), remove())
+	}
+
+	_, err = tmp.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, nil, errors.Join(err, remove())
+	}
+
+	cleanup = func() error {
+		defer t.release(size)
+		return remove()
+	}
+	return tmp, cleanup, nil
+}
+
+func (t *TempSpace) reserve(size int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.maxSize > 0 && t.used+size > t.maxSize {
+		return fmt.Errorf("%w: extracting %d bytes would exceed the limit of %d bytes, %d bytes are in use", ErrTempSpaceExceeded, size, t.maxSize, t.used)
+	}
+	t.used += size
+	return nil
+}
+
+func (t *TempSpace) release(size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.used -= size
+}
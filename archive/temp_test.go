@@ -0,0 +1,83 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestTempSpaceReleasesFailedExtractions(t *testing.T) {
+	const size = MaxMemoryFileSize + 1
+	tests := []struct {
+		name string
+		r    io.Reader
+	}{
+		{
+			name: "short reader",
+			r:    strings.NewReader("2024-01-01 12:00:00 I chat: 0:-2:nameless tee: hello"),
+		},
+		{
+			name: "read error",
+			r:    iotest.ErrReader(errors.New("unexpected EOF")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			ts := NewTempSpace(dir, 2*size)
+
+			_, _, err := ts.NewFile(tt.r, size)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if ts.used != 0 {
+				t.Fatalf("used = %d, want 0", ts.used)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Fatalf("expected the temp file to be removed, found %d files", len(entries))
+			}
+		})
+	}
+}
+
+func TestTempSpaceReleasesOnCleanup(t *testing.T) {
+	const size = MaxMemoryFileSize + 1
+	ts := NewTempSpace(t.TempDir(), size)
+
+	_, cleanup, err := ts.NewFile(io.LimitReader(zeroReader{}, size), size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.used != size {
+		t.Fatalf("used = %d, want %d", ts.used, size)
+	}
+
+	// the limit is reached until the file is cleaned up
+	_, _, err = ts.NewFile(io.LimitReader(zeroReader{}, size), size)
+	if !errors.Is(err, ErrTempSpaceExceeded) {
+		t.Fatalf("expected ErrTempSpaceExceeded, got %v", err)
+	}
+
+	err = cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.used != 0 {
+		t.Fatalf("used = %d, want 0", ts.used)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
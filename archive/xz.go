@@ -1,12 +1,10 @@
 package archive
 
 import (
-	"os"
-
 	"github.com/ulikunitz/xz"
 )
 
-func WalkTarXz(file *os.File, walkFunc WalkFunc) error {
+func WalkTarXz(file ArchiveFile, walkFunc WalkFunc) error {
 	r, err := xz.NewReader(file)
 	if err != nil {
 		return err
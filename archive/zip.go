@@ -2,10 +2,9 @@ package archive
 
 import (
 	"archive/zip"
-	"os"
 )
 
-func WalkZip(file *os.File, fileSize int64, walkFunc WalkFunc) error {
+func WalkZip(file ArchiveFile, fileSize int64, walkFunc WalkFunc) error {
 	zfs, err := zip.NewReader(file, fileSize)
 	if err != nil {
 		return err
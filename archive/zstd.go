@@ -1,12 +1,10 @@
 package archive
 
 import (
-	"os"
-
 	"github.com/klauspost/compress/zstd"
 )
 
-func WalkTarZstd(file *os.File, walkFunc WalkFunc) error {
+func WalkTarZstd(file ArchiveFile, walkFunc WalkFunc) error {
 	r, err := zstd.NewReader(file)
 	if err != nil {
 		return err
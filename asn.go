@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// asnRange is a range of IPs that is announced by an autonomous system.
+type asnRange struct {
+	first, last  netip.Addr
+	asn          uint32
+	organization string
+}
+
+// asnDatabase maps IPs to autonomous systems, the ranges are sorted and do not overlap.
+type asnDatabase []asnRange
+
+// loadASNDatabase reads an ip2asn database of iptoasn.com from a local file or http(s) URL.
+// The database is tab separated with the columns range_start, range_end, AS_number, country_code and AS_description.
+// Gzip compressed databases are decompressed transparently.
+func loadASNDatabase(ctx context.Context, source string) (asnDatabase, error) {
+	f, err := openSource(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open asn database %s: %w", source, err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress asn database %s: %w", source, err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	db, err := parseASNDatabase(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse asn database %s: %w", source, err)
+	}
+	slog.Info("loaded asn database", "source", source, "ranges", len(db))
+	return db, nil
+}
+
+func parseASNDatabase(r io.Reader) (asnDatabase, error) {
+	db := make(asnDatabase, 0, 1<<16)
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		columns := strings.Split(line, "\t")
+		if len(columns) < 3 {
+			return nil, fmt.Errorf("line %d: expected at least 3 tab separated columns", lineNumber)
+		}
+
+		asn, err := config.ParseASN(columns[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if asn == 0 {
+			// not routed
+			continue
+		}
+
+		first, err := netip.ParseAddr(columns[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		last, err := netip.ParseAddr(columns[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		var organization string
+		if len(columns) >= 5 {
+			organization = columns[4]
+		}
+
+		db = append(db, asnRange{
+			first:        first.Unmap(),
+			last:         last.Unmap(),
+			asn:          asn,
+			organization: organization,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(db, func(a, b asnRange) int {
+		return a.first.Compare(b.first)
+	})
+	return db, nil
+}
+
+// Lookup returns the range of the autonomous system the IP belongs to.
+func (db asnDatabase) Lookup(ip string) (asnRange, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return asnRange{}, false
+	}
+	addr = addr.Unmap()
+
+	// index of the first range that starts after the IP
+	idx, _ := slices.BinarySearchFunc(db, addr, func(r asnRange, addr netip.Addr) int {
+		if r.first.Compare(addr) <= 0 {
+			return -1
+		}
+		return 1
+	})
+	if idx == 0 {
+		return asnRange{}, false
+	}
+
+	r := db[idx-1]
+	if r.last.Compare(addr) < 0 {
+		return asnRange{}, false
+	}
+	return r, true
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// auditEntry records who searched for what, as results expose the IPs of players.
+type auditEntry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`
+	SudoUser string    `json:"sudo_user,omitempty"`
+	Host     string    `json:"host"`
+	// Mode is one of scan, schedule or econ.
+	Mode      string       `json:"mode"`
+	SearchDir string       `json:"search_dir,omitempty"`
+	Queries   []auditQuery `json:"queries"`
+	Matches   int          `json:"matches"`
+	Duration  string       `json:"duration"`
+	Error     string       `json:"error,omitempty"`
+	// Started marks the entry that is written before matches are streamed, e.g. by workers,
+	// the entry with the number of matches follows once the scan completed.
+	Started bool `json:"started,omitempty"`
+}
+
+type auditQuery struct {
+	Name     string `json:"name,omitempty"`
+	Phrase   string `json:"phrase,omitempty"`
+	Patterns string `json:"patterns,omitempty"`
+}
+
+func (e auditEntry) String() string {
+	var sb strings.Builder
+	sb.WriteString(e.Time.Format(time.RFC3339) + " " + e.User)
+	if e.SudoUser != "" {
+		sb.WriteString(" (sudo " + e.SudoUser + ")")
+	}
+	if e.Started {
+		fmt.Fprintf(&sb, "@%s mode=%s started", e.Host, e.Mode)
+	} else {
+		fmt.Fprintf(&sb, "@%s mode=%s matches=%d duration=%s", e.Host, e.Mode, e.Matches, e.Duration)
+	}
+	if e.SearchDir != "" {
+		sb.WriteString(" dir=" + e.SearchDir)
+	}
+	for _, q := range e.Queries {
+		phrase := q.Phrase
+		if q.Patterns != "" {
+			phrase = "patterns:" + q.Patterns
+		}
+		if q.Name != "" {
+			fmt.Fprintf(&sb, " %s=%q", q.Name, phrase)
+		} else {
+			fmt.Fprintf(&sb, " phrase=%q", phrase)
+		}
+	}
+	if e.Error != "" {
+		fmt.Fprintf(&sb, " error=%q", e.Error)
+	}
+	return sb.String()
+}
+
+// audit appends an entry to the audit log, in case one is configured.
+func (cli *CLI) audit(mode string, start time.Time, matches int, runErr error) error {
+	if cli.cfg.AuditLog == "" {
+		return nil
+	}
+
+	entry := cli.newAuditEntry(mode, start)
+	entry.Matches = matches
+	entry.Duration = time.Since(start).Round(time.Millisecond).String()
+	if runErr != nil {
+		entry.Error = runErr.Error()
+	}
+	return cli.writeAuditEntry(entry)
+}
+
+// auditStarted appends the entry of a scan whose matches are streamed before it completes,
+// in case an audit log is configured.
+func (cli *CLI) auditStarted(mode string, start time.Time) error {
+	if cli.cfg.AuditLog == "" {
+		return nil
+	}
+
+	entry := cli.newAuditEntry(mode, start)
+	entry.Started = true
+	return cli.writeAuditEntry(entry)
+}
+
+func (cli *CLI) newAuditEntry(mode string, start time.Time) auditEntry {
+	entry := auditEntry{
+		Time:     start.UTC(),
+		SudoUser: os.Getenv("SUDO_USER"),
+		Mode:     mode,
+	}
+	if u, err := user.Current(); err == nil {
+		entry.User = u.Username
+	}
+	entry.Host, _ = os.Hostname()
+	if mode != "econ" {
+		entry.SearchDir, _ = filepath.Abs(cli.cfg.SearchDir)
+	}
+	for _, q := range cli.cfg.Queries() {
+		aq := auditQuery{Name: q.Name, Patterns: q.PatternsFile}
+		if q.PatternsFile == "" {
+			aq.Phrase = q.PhraseRegex
+		}
+		entry.Queries = append(entry.Queries, aq)
+	}
+	return entry
+}
+
+func (cli *CLI) writeAuditEntry(entry auditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit log entry: %w", err)
+	}
+
+	f, err := os.OpenFile(cli.cfg.AuditLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	return errors.Join(err, f.Close())
+}
+
+// NewAuditCmd prints the entries of an audit log.
+func NewAuditCmd() *cobra.Command {
+	cfg := config.NewAuditConfig()
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "review the audit log of executed searches",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		f, err := os.Open(cfg.Input)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		defer f.Close()
+
+		var since time.Time
+		if cfg.Since > 0 {
+			since = time.Now().Add(-cfg.Since)
+		}
+
+		w := cmd.OutOrStdout()
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		lineNumber := 0
+		for scanner.Scan() {
+			lineNumber++
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+
+			var entry auditEntry
+			err = json.Unmarshal(line, &entry)
+			if err != nil {
+				return fmt.Errorf("audit log line %d: %w", lineNumber, err)
+			}
+			if entry.Time.Before(since) {
+				continue
+			}
+			if cfg.User != "" && entry.User != cfg.User && entry.SudoUser != cfg.User {
+				continue
+			}
+
+			if cfg.Output == config.FormatJSON {
+				_, err = fmt.Fprintf(w, "%s\n", line)
+			} else {
+				_, err = fmt.Fprintln(w, entry)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	}
+	return cmd
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// NewBanFileCmd converts results or IP lists into ban commands
+// that can be appended to the bans.cfg of a DDNet or Teeworlds server.
+func NewBanFileCmd() *cobra.Command {
+	cfg := config.NewBanFileConfig()
+
+	cmd := &cobra.Command{
+		Use:   "banfile",
+		Short: "convert json results or an IP list into ban and ban_range commands in bans.cfg syntax",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
+		cmd.SilenceUsage = true
+
+		r := cmd.InOrStdin()
+		if cfg.Input != "-" {
+			f, err := os.Open(cfg.Input)
+			if err != nil {
+				return fmt.Errorf("failed to open input: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		bans, err := readBans(r)
+		if err != nil {
+			return err
+		}
+		return writeBanFile(cmd.OutOrStdout(), bans, cfg.Duration, cfg.ReasonTemplate)
+	}
+	return cmd
+}
+
+// ban is a single IP or a range of IPs together with the match it originates from.
+type ban struct {
+	First  netip.Addr
+	Last   netip.Addr
+	Player PlayerExtended
+}
+
+func (b ban) IsRange() bool {
+	return b.First != b.Last
+}
+
+// readBans reads json results of any output mode or a plain list of IPs and ranges.
+func readBans(r io.Reader) ([]ban, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return readJSONBans(data)
+	}
+	return readListBans(data)
+}
+
+func readJSONBans(data []byte) ([]ban, error) {
+	var players PlayerExtendedList
+	err := json.Unmarshal(data, &players)
+	if err != nil {
+		// json output of --ips-only
+		var ips []string
+		if json.Unmarshal(data, &ips) != nil {
+			return nil, fmt.Errorf("failed to parse json input: %w", err)
+		}
+		players = make(PlayerExtendedList, 0, len(ips))
+		for _, ip := range ips {
+			players = append(players, PlayerExtended{IP: ip})
+		}
+	}
+
+	bans := make([]ban, 0, len(players))
+	for idx, player := range players {
+		first, last, err := parseIPRange(player.IP)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", idx, err)
+		}
+		bans = append(bans, ban{First: first, Last: last, Player: player})
+	}
+	return bans, nil
+}
+
+func readListBans(data []byte) ([]ban, error) {
+	bans := make([]ban, 0, 16)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		first, last, err := parseIPRange(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		bans = append(bans, ban{First: first, Last: last, Player: PlayerExtended{IP: line}})
+	}
+	return bans, scanner.Err()
+}
+
+// parseIPRange parses an IP, a CIDR range or a first-last range.
+func parseIPRange(s string) (first, last netip.Addr, err error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.Contains(s, "/"):
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return first, last, err
+		}
+		prefix = prefix.Masked()
+		return prefix.Addr(), lastAddr(prefix), nil
+	case strings.Contains(s, "-"):
+		lhs, rhs, _ := strings.Cut(s, "-")
+		first, err = netip.ParseAddr(strings.TrimSpace(lhs))
+		if err != nil {
+			return first, last, err
+		}
+		last, err = netip.ParseAddr(strings.TrimSpace(rhs))
+		if err != nil {
+			return first, last, err
+		}
+		if last.Less(first) {
+			return first, last, fmt.Errorf("invalid range %s: first IP is greater than last IP", s)
+		}
+		return first, last, nil
+	default:
+		first, err = netip.ParseAddr(s)
+		return first, first, err
+	}
+}
+
+// lastAddr returns the last address of the prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(addr)*8; bit++ {
+		addr[bit/8] |= 1 << (7 - bit%8)
+	}
+	last, _ := netip.AddrFromSlice(addr)
+	return last
+}
+
+// writeBanFile writes one ban or ban_range command per unique IP or range.
+// The reason of the first entry of an IP or range is used.
+func writeBanFile(w io.Writer, bans []ban, duration int, reason *template.Template) error {
+	seen := make(map[[2]netip.Addr]struct{}, len(bans))
+	var sb strings.Builder
+	for _, b := range bans {
+		key := [2]netip.Addr{b.First, b.Last}
+		if _, found := seen[key]; found {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		sb.Reset()
+		err := reason.Execute(&sb, b.Player)
+		if err != nil {
+			return fmt.Errorf("failed to render ban reason for %s: %w", b.Player.IP, err)
+		}
+
+		var line string
+		if b.IsRange() {
+			line = fmt.Sprintf("ban_range %s %s %d", b.First, b.Last, duration)
+		} else {
+			line = fmt.Sprintf("ban %s %d", b.First, duration)
+		}
+		if sb.Len() > 0 {
+			line += " " + quoteConsoleArg(sb.String())
+		}
+
+		_, err = fmt.Fprintln(w, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quoteConsoleArg quotes a string argument of the server console.
+func quoteConsoleArg(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ", "\r", " ").Replace(s)
+	return `"` + s + `"`
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// benchPhraseRegex is searched for in case neither the flags nor the config file define a phrase or queries.
+const benchPhraseRegex = `https?://\S+`
+
+// NewBenchCmd generates a synthetic log corpus and measures the scan throughput with the settings of the config file.
+func NewBenchCmd(ctx context.Context) *cobra.Command {
+	cfg := config.NewBenchConfig()
+
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "generate synthetic log files and measure the scan throughput in lines and MB per second with the current settings",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		dir := cfg.Dir
+		if dir == "" {
+			tmp, err := os.MkdirTemp("", "twlog-bench-")
+			if err != nil {
+				return fmt.Errorf("failed to create temp dir: %w", err)
+			}
+			defer os.RemoveAll(tmp)
+			dir = tmp
+		} else {
+			err := os.MkdirAll(dir, 0o755)
+			if err != nil {
+				return fmt.Errorf("failed to create bench dir: %w", err)
+			}
+		}
+
+		cli, err := newBenchCLI(ctx, cmd, cfg, dir)
+		if err != nil {
+			return err
+		}
+
+		lines, err := generateBenchFiles(ctx, dir, cfg)
+		if err != nil {
+			return err
+		}
+
+		stats := &ScanStats{}
+		start := time.Now()
+		players, err := cli.scan(ctx, stats, time.Time{})
+		if err != nil {
+			return err
+		}
+
+		result := newBenchResult(stats, lines, len(players), cli.cfg.Concurrency, time.Since(start))
+		if cfg.Output == config.FormatText {
+			_, err := fmt.Fprintln(cmd.OutOrStdout(), result)
+			return err
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal json result: %w", err)
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s\n", data)
+		return err
+	}
+	return cmd
+}
+
+// newBenchCLI returns a CLI with the settings of the config file that scans the bench dir.
+func newBenchCLI(ctx context.Context, cmd *cobra.Command, cfg config.BenchConfig, dir string) (*CLI, error) {
+	scanCfg := config.NewConfig()
+	var args []string
+	if cmd.Flags().Changed("config-file") {
+		args = []string{"--config-file", cfg.ConfigFile}
+	}
+	err := scanCfg.LoadFile(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// the generated files replace the files of the config file
+	defaults := config.NewConfig()
+	scanCfg.SearchDir = dir
+	scanCfg.FileRegex = defaults.FileRegex
+	scanCfg.IncludeArchives = false
+	scanCfg.SkipDuplicateFiles = false
+
+	if cfg.PhraseRegex != "" || (scanCfg.PhraseRegex == "" && scanCfg.QueriesFile == "" && scanCfg.Preset == "" && scanCfg.PatternsFile == "") {
+		scanCfg.PhraseRegex = cmp.Or(cfg.PhraseRegex, benchPhraseRegex)
+		scanCfg.QueriesFile = ""
+		scanCfg.Preset = ""
+		scanCfg.PatternsFile = ""
+	}
+	if cfg.Concurrency > 0 {
+		scanCfg.Concurrency = cfg.Concurrency
+	}
+
+	err = scanCfg.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid settings of config file %s: %w", scanCfg.ConfigFile, err)
+	}
+
+	cli := &CLI{
+		ctx:         ctx,
+		CancelCause: func(error) {},
+		cfg:         scanCfg,
+	}
+	cli.setupLogging(cmd.ErrOrStderr())
+	return cli, nil
+}
+
+var (
+	benchNicknames = []string{
+		"nameless tee", "brainless tee", "Alice", "Bob", "[ABC] Carol", "Dave", "xXx_Eve_xXx", "Mallory",
+		"Trent", "Peggy", "Victor", "Walter", "Oscar", "Sybil", "Zoe", "Yuki",
+	}
+	benchMessages = []string{
+		"gg", "hello", "hi all", "nice shot", "wait for me", "who wants to play a 1on1?", "lag",
+		"can someone help me with this part", "kill me pls", "thanks", "afk", "brb", "omg", "lol",
+		"which map is next?", "the hammer is op", "let's go to the freeze", "wb",
+	}
+	benchAdvertisements = []string{
+		"visit https://bot.example/now for free skins", "join http://spam.example.org",
+	}
+)
+
+// generateBenchFiles writes the log files of the configured size and returns the number of lines.
+func generateBenchFiles(ctx context.Context, dir string, cfg config.BenchConfig) (int64, error) {
+	rng := rand.New(rand.NewPCG(cfg.Seed, cfg.Seed))
+	size := cfg.SizeBytes / int64(cfg.Files)
+
+	var lines int64
+	for idx := range cfg.Files {
+		err := checkShutDown(ctx)
+		if err != nil {
+			return lines, err
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("bench-%03d.log", idx))
+		fileLines, err := generateBenchFile(path, size, cfg.ChatDensity, rng)
+		if err != nil {
+			return lines, fmt.Errorf("failed to generate %s: %w", path, err)
+		}
+		lines += fileLines
+	}
+	return lines, nil
+}
+
+// generateBenchFile writes log lines until the file reaches the size.
+// One out of 100 chat messages is an advertisement with a URL.
+func generateBenchFile(path string, size int64, chatDensity float64, rng *rand.Rand) (lines int64, err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+
+	w := bufio.NewWriterSize(f, 256*1024)
+	var (
+		t       = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		written int64
+		// connected maps the client IDs to their nicknames
+		connected = make(map[int]string, 16)
+	)
+	write := func(format string, a ...any) error {
+		n, err := fmt.Fprintf(w, "%s I "+format+"\n", append([]any{t.Format(time.DateTime)}, a...)...)
+		written += int64(n)
+		lines++
+		return err
+	}
+
+	err = write("datafile: loading. filename='maps/ctf5.map'")
+	for err == nil && written < size {
+		t = t.Add(time.Duration(rng.IntN(3)) * time.Second)
+		id := rng.IntN(16)
+		nickname, online := connected[id]
+
+		switch {
+		case online && rng.Float64() < chatDensity:
+			message := benchMessages[rng.IntN(len(benchMessages))]
+			if rng.IntN(100) == 0 {
+				message = benchAdvertisements[rng.IntN(len(benchAdvertisements))]
+			}
+			err = write("chat: %d:-2:%s: %s", id, nickname, message)
+		case !online:
+			connected[id] = benchNicknames[rng.IntN(len(benchNicknames))]
+			err = write("server: player has entered the game. ClientID=%d addr=<{%d.%d.%d.%d:%d}>",
+				id, 1+rng.IntN(223), rng.IntN(256), rng.IntN(256), 1+rng.IntN(254), 1024+rng.IntN(60000))
+		case rng.IntN(20) == 0:
+			delete(connected, id)
+			err = write("game: leave player='%d:%s'", id, nickname)
+		default:
+			err = write("game: kill killer='%d:%s' victim='%d:%s' weapon=%d special=0",
+				id, nickname, id, nickname, rng.IntN(6))
+		}
+	}
+	if err != nil {
+		return lines, err
+	}
+	return lines, w.Flush()
+}
+
+// BenchResult is the throughput of a scan of the generated log files.
+type BenchResult struct {
+	Files          int64   `json:"files"`
+	Bytes          int64   `json:"bytes"`
+	Lines          int64   `json:"lines"`
+	Matches        int     `json:"matches"`
+	Concurrency    int     `json:"concurrency"`
+	Seconds        float64 `json:"seconds"`
+	LinesPerSecond float64 `json:"lines_per_second"`
+	MBPerSecond    float64 `json:"mb_per_second"`
+}
+
+func newBenchResult(stats *ScanStats, lines int64, matches, concurrency int, d time.Duration) BenchResult {
+	seconds := d.Seconds()
+	bytes := stats.Bytes.Load()
+	return BenchResult{
+		Files:          stats.Files.Load(),
+		Bytes:          bytes,
+		Lines:          lines,
+		Matches:        matches,
+		Concurrency:    concurrency,
+		Seconds:        seconds,
+		LinesPerSecond: float64(lines) / seconds,
+		MBPerSecond:    float64(bytes) / 1e6 / seconds,
+	}
+}
+
+func (r BenchResult) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "files=%d bytes=%d lines=%d matches=%d concurrency=%d\n", r.Files, r.Bytes, r.Lines, r.Matches, r.Concurrency)
+	fmt.Fprintf(&sb, "duration=%.3fs lines/s=%.0f MB/s=%.2f", r.Seconds, r.LinesPerSecond, r.MBPerSecond)
+	return sb.String()
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// checkpointVersion is incremented whenever checkpoints of older versions cannot be resumed.
+const checkpointVersion = 1
+
+// scanCheckpoint is the progress of a long scan, so that a crash or reboot does not lose
+// the matches of the files that were already scanned. --resume continues the scan from it.
+type scanCheckpoint struct {
+	Version   int                    `json:"version"`
+	Updated   time.Time              `json:"updated"`
+	SearchDir string                 `json:"search_dir"`
+	Queries   []config.ManifestQuery `json:"queries"`
+	// Files are the completely scanned log files and archives.
+	Files []string `json:"files"`
+	// Matches are the matches of the completed files before any filters are applied.
+	Matches PlayerExtendedList `json:"matches"`
+
+	path      string
+	mu        sync.Mutex
+	completed map[string]struct{}
+	// dirty is set when files were completed since the last save
+	dirty bool
+}
+
+// newCheckpoint returns an empty checkpoint of the current scan, or the checkpoint of the previous scan in case of --resume.
+// A nil checkpoint is returned when no checkpoint is configured.
+func (cli *CLI) newCheckpoint() (*scanCheckpoint, error) {
+	if cli.cfg.CheckpointFile == "" {
+		return nil, nil
+	}
+
+	searchDir, err := filepath.Abs(cli.cfg.SearchDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path of search dir: %w", err)
+	}
+	cp := &scanCheckpoint{
+		Version:   checkpointVersion,
+		SearchDir: searchDir,
+		Queries:   cli.cfg.ManifestQueries(),
+		Files:     []string{},
+		Matches:   PlayerExtendedList{},
+		path:      cli.cfg.CheckpointFile,
+		completed: make(map[string]struct{}),
+	}
+	if !cli.cfg.Resume {
+		return cp, nil
+	}
+
+	data, err := os.ReadFile(cp.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// resuming is the default of recurring jobs, the first run has nothing to resume
+		slog.Info("no checkpoint to resume, starting a new scan", "checkpoint", cp.path)
+		return cp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var previous scanCheckpoint
+	err = json.Unmarshal(data, &previous)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", cp.path, err)
+	}
+	if previous.Version != checkpointVersion {
+		return nil, fmt.Errorf("checkpoint %s has version %d, expected version %d", cp.path, previous.Version, checkpointVersion)
+	}
+	if previous.SearchDir != cp.SearchDir {
+		return nil, fmt.Errorf("checkpoint %s was written for the search dir %s", cp.path, previous.SearchDir)
+	}
+	recorded, _ := json.Marshal(previous.Queries)
+	current, _ := json.Marshal(cp.Queries)
+	if !bytes.Equal(recorded, current) {
+		return nil, fmt.Errorf("checkpoint %s was written for other queries", cp.path)
+	}
+
+	cp.Files = previous.Files
+	cp.Matches = previous.Matches
+	for _, file := range cp.Files {
+		cp.completed[file] = struct{}{}
+	}
+	slog.Info("resuming scan", "checkpoint", cp.path, "completed", len(cp.Files), "matches", len(cp.Matches), "updated", previous.Updated)
+	return cp, nil
+}
+
+// skip removes the completed files and returns the number of removed files.
+func (cp *scanCheckpoint) skip(files []string) ([]string, int) {
+	if cp == nil {
+		return files, 0
+	}
+	before := len(files)
+	files = slices.DeleteFunc(files, func(file string) bool {
+		_, ok := cp.completed[file]
+		return ok
+	})
+	return files, before - len(files)
+}
+
+// complete records the file or archive as completely scanned with its matches.
+func (cp *scanCheckpoint) complete(file string, players PlayerExtendedList) {
+	if cp == nil {
+		return
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.completed[file] = struct{}{}
+	cp.Files = append(cp.Files, file)
+	cp.Matches = append(cp.Matches, players...)
+	cp.dirty = true
+}
+
+// save replaces the checkpoint file, in case files were completed since the last save.
+// The file is replaced atomically, so that a crash while saving keeps the previous checkpoint.
+func (cp *scanCheckpoint) save() error {
+	if cp == nil {
+		return nil
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if !cp.dirty {
+		return nil
+	}
+
+	cp.Updated = time.Now().UTC()
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	tmp := cp.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	err = os.Rename(tmp, cp.path)
+	if err != nil {
+		return fmt.Errorf("failed to replace checkpoint: %w", err)
+	}
+	cp.dirty = false
+	slog.Debug("saved checkpoint", "checkpoint", cp.path, "completed", len(cp.Files), "matches", len(cp.Matches))
+	return nil
+}
+
+// remove deletes the checkpoint after the results of a complete scan were printed.
+func (cp *scanCheckpoint) remove() error {
+	if cp == nil {
+		return nil
+	}
+	err := os.Remove(cp.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return nil
+}
+
+// saveCheckpoints saves the checkpoint at the interval until the returned function is called.
+func saveCheckpoints(ctx context.Context, cp *scanCheckpoint, interval time.Duration) (stop func()) {
+	if cp == nil {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := cp.save()
+				if err != nil {
+					slog.Warn("failed to save checkpoint", "error", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"cmp"
+	"net/netip"
+	"slices"
+)
+
+// cidrBlock is either a collapsed network or a single IP of the aggregated IP list.
+type cidrBlock struct {
+	prefix netip.Prefix
+	// text is the original IP of single addresses, which may contain a port
+	text string
+}
+
+// aggregateCIDR collapses the IPs of /24 IPv4 and /48 IPv6 networks with at least minHits distinct addresses
+// into the smallest CIDR block that contains them, e.g. as candidates for range bans.
+// IPs of networks with fewer hits are kept, IPs that cannot be parsed, e.g. redacted ones, are appended unchanged.
+func aggregateCIDR(ips StringList, minHits int) StringList {
+	var (
+		networks = make(map[string][]netip.Addr, len(ips))
+		texts    = make(map[netip.Addr]string, len(ips))
+		invalid  = make(StringList, 0)
+	)
+	for _, ip := range ips {
+		addrPort, err := parseIPOrAddrPort(ip)
+		if err != nil {
+			invalid = append(invalid, ip)
+			continue
+		}
+		addr := addrPort.Addr()
+		if _, found := texts[addr]; found {
+			// same address with another port
+			continue
+		}
+		texts[addr] = ip
+		network := networkOf(addr.String())
+		networks[network] = append(networks[network], addr)
+	}
+
+	blocks := make([]cidrBlock, 0, len(texts))
+	for _, addrs := range networks {
+		if len(addrs) < minHits {
+			for _, addr := range addrs {
+				blocks = append(blocks, cidrBlock{
+					prefix: netip.PrefixFrom(addr, addr.BitLen()),
+					text:   texts[addr],
+				})
+			}
+			continue
+		}
+		first, last := slices.MinFunc(addrs, netip.Addr.Compare), slices.MaxFunc(addrs, netip.Addr.Compare)
+		blocks = append(blocks, cidrBlock{prefix: commonPrefix(first, last)})
+	}
+	slices.SortFunc(blocks, func(a, b cidrBlock) int {
+		return cmp.Or(
+			a.prefix.Addr().Compare(b.prefix.Addr()),
+			cmp.Compare(a.prefix.Bits(), b.prefix.Bits()),
+		)
+	})
+
+	result := make(StringList, 0, len(blocks)+len(invalid))
+	for _, block := range blocks {
+		if block.text != "" {
+			result = append(result, block.text)
+			continue
+		}
+		result = append(result, block.prefix.String())
+	}
+	return append(result, invalid...)
+}
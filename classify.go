@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+const (
+	// classifyBatchSize is the maximum number of messages per request to an http classifier
+	classifyBatchSize = 100
+	classifyTimeout   = 30 * time.Second
+)
+
+// classifyRequest is the body that is posted to http classifiers.
+type classifyRequest struct {
+	Texts []string `json:"texts"`
+}
+
+// classifyResponse contains the labels and their scores of every text of the request in the same order.
+type classifyResponse struct {
+	Results []struct {
+		Labels map[string]float64 `json:"labels"`
+	} `json:"results"`
+}
+
+// classify tags the players with the labels of the classifier and their toxicity, the highest score of their labels.
+// Matches that cannot be classified, e.g. due to an unreachable classifier, keep a toxicity of zero.
+func (cli *CLI) classify(ctx context.Context, players PlayerExtendedList) {
+	if cli.cfg.Classifier != nil {
+		for idx := range players {
+			players[idx].setLabels(classifyText(cli.cfg.Classifier, players[idx].Text))
+		}
+		return
+	}
+
+	client := &http.Client{Timeout: classifyTimeout}
+	for start := 0; start < len(players); start += classifyBatchSize {
+		batch := players[start:min(start+classifyBatchSize, len(players))]
+		texts := make([]string, 0, len(batch))
+		for _, p := range batch {
+			texts = append(texts, p.Text)
+		}
+
+		labels, err := postClassify(ctx, client, cli.cfg.ClassifyURL, texts)
+		if err != nil {
+			slog.Error("failed to classify matches", "url", cli.cfg.ClassifyURL, "matches", len(batch), "error", err)
+			continue
+		}
+		for idx := range batch {
+			batch[idx].setLabels(labels[idx])
+		}
+	}
+}
+
+// classifyText returns the labels of the word list whose patterns match the text,
+// unless the text matches one of the global or label specific unless patterns.
+func classifyText(c *config.Classifier, text string) map[string]float64 {
+	for _, re := range c.UnlessRegexps {
+		if re.MatchString(text) {
+			return nil
+		}
+	}
+
+	var labels map[string]float64
+	for _, l := range c.Labels {
+		matches := slices.ContainsFunc(l.PatternRegexps, func(re *regexp.Regexp) bool { return re.MatchString(text) })
+		if !matches || slices.ContainsFunc(l.UnlessRegexps, func(re *regexp.Regexp) bool { return re.MatchString(text) }) {
+			continue
+		}
+		if labels == nil {
+			labels = make(map[string]float64, 1)
+		}
+		labels[l.Name] = max(labels[l.Name], l.Score)
+	}
+	return labels
+}
+
+func postClassify(ctx context.Context, client *http.Client, url string, texts []string) ([]map[string]float64, error) {
+	body, err := json.Marshal(classifyRequest{Texts: texts})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("unexpected status code: %s: %s", resp.Status, msg)
+	}
+
+	var result classifyResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("invalid classifier response: %w", err)
+	}
+	if len(result.Results) != len(texts) {
+		return nil, fmt.Errorf("classifier returned %d results for %d texts", len(result.Results), len(texts))
+	}
+
+	labels := make([]map[string]float64, 0, len(texts))
+	for _, r := range result.Results {
+		labels = append(labels, r.Labels)
+	}
+	return labels, nil
+}
+
+// setLabels sets the labels and the toxicity, which is the highest score of the labels.
+func (p *PlayerExtended) setLabels(labels map[string]float64) {
+	if len(labels) == 0 {
+		return
+	}
+	p.Labels = labels
+	p.Toxicity = slices.Max(slices.Collect(maps.Values(labels)))
+}
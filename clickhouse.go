@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clickhouseRow is an inserted match.
+type clickhouseRow struct {
+	Time       string            `json:"time"`
+	Query      string            `json:"query"`
+	Server     string            `json:"server"`
+	File       string            `json:"file"`
+	Archive    string            `json:"archive"`
+	Member     string            `json:"member"`
+	Nickname   string            `json:"nickname"`
+	ClientID   int               `json:"client_id"`
+	IP         string            `json:"ip"`
+	Hostname   string            `json:"hostname"`
+	VPN        bool              `json:"vpn"`
+	Text       string            `json:"text"`
+	Line       int               `json:"line"`
+	ByteOffset int64             `json:"byte_offset"`
+	Fields     map[string]string `json:"fields"`
+}
+
+// clickhouseInserter inserts matches into clickhouse via its http interface.
+type clickhouseInserter struct {
+	url      string
+	table    string
+	username string
+	password string
+	client   *http.Client
+
+	mu           sync.Mutex
+	tableCreated bool
+}
+
+func newClickhouseInserter(url, table, username, password string) *clickhouseInserter {
+	return &clickhouseInserter{
+		url:      strings.TrimRight(url, "/") + "/",
+		table:    table,
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: sinkTimeout},
+	}
+}
+
+// Post inserts the matches with a single insert statement.
+// Matches without timestamp are inserted with the current time.
+func (c *clickhouseInserter) Post(ctx context.Context, players PlayerExtendedList) error {
+	err := c.createTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, p := range players {
+		t := now
+		if p.Time != nil {
+			t = *p.Time
+		}
+		fields := p.Fields
+		if fields == nil {
+			fields = Fields{}
+		}
+
+		err = enc.Encode(clickhouseRow{
+			Time:       t.Format(time.RFC3339Nano),
+			Query:      p.Query,
+			Server:     p.Server,
+			File:       p.File,
+			Archive:    p.Archive,
+			Member:     p.Member,
+			Nickname:   p.Nickname,
+			ClientID:   p.ID,
+			IP:         p.IP,
+			Hostname:   p.Hostname,
+			VPN:        p.VPN,
+			Text:       p.Text,
+			Line:       p.Line,
+			ByteOffset: p.Offset,
+			Fields:     fields,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.exec(ctx, fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", c.table), &body)
+	if err != nil {
+		return fmt.Errorf("failed to insert matches into clickhouse: %w", err)
+	}
+	return nil
+}
+
+// createTable creates a MergeTree table that is partitioned by month.
+func (c *clickhouseInserter) createTable(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tableCreated {
+		return nil
+	}
+
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	time        DateTime64(3),
+	inserted_at DateTime DEFAULT now(),
+	query       LowCardinality(String),
+	server      LowCardinality(String),
+	file        String,
+	archive     String,
+	member      String,
+	nickname    String,
+	client_id   Int32,
+	ip          String,
+	hostname    String,
+	vpn         Bool,
+	text        String,
+	line        UInt32,
+	byte_offset UInt64,
+	fields      Map(String, String)
+) ENGINE = MergeTree
+PARTITION BY toYYYYMM(time)
+ORDER BY (server, time)`, c.table)
+
+	err := c.exec(ctx, query, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create clickhouse table %s: %w", c.table, err)
+	}
+	c.tableCreated = true
+	return nil
+}
+
+func (c *clickhouseInserter) exec(ctx context.Context, query string, body io.Reader) error {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("date_time_input_format", "best_effort")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"?"+params.Encode(), body)
+	if err != nil {
+		return err
+	}
+	if c.username != "" {
+		req.Header.Set("X-ClickHouse-User", c.username)
+		req.Header.Set("X-ClickHouse-Key", c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code: %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
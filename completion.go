@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+const maxRecentSearchDirs = 16
+
+// registerCompletions adds dynamic shell completions for flag values.
+// The completion subcommand itself is provided by cobra.
+func (cli *CLI) registerCompletions(cmd *cobra.Command) {
+	_ = cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(
+		[]string{config.FormatText, config.FormatJSON},
+		cobra.ShellCompDirectiveNoFileComp,
+	))
+
+	_ = cmd.RegisterFlagCompletionFunc("order", cobra.FixedCompletions(
+		config.Orders,
+		cobra.ShellCompDirectiveNoFileComp,
+	))
+
+	_ = cmd.RegisterFlagCompletionFunc("preset", func(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		// the config file of the completed command line
+		cfg := config.NewConfig()
+		_ = cfg.LoadFile(changedFlagArgs(cmd))
+		presets, err := config.Presets(cfg.ConfigFile)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return presets, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	_ = cmd.RegisterFlagCompletionFunc("search-dir", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		dirs := recentSearchDirs()
+		if len(dirs) == 0 {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		}
+		return dirs, cobra.ShellCompDirectiveDefault
+	})
+
+	yamlFiles := func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return []string{"yaml", "yml"}, cobra.ShellCompDirectiveFilterFileExt
+	}
+	_ = cmd.RegisterFlagCompletionFunc("queries", yamlFiles)
+	_ = cmd.RegisterFlagCompletionFunc("config-file", yamlFiles)
+}
+
+// recentSearchDirsFile returns the path of the file that contains the recently used search dirs.
+func recentSearchDirsFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "twlog-who-said", "search-dirs"), nil
+}
+
+// recentSearchDirs returns the recently used search dirs, most recent first.
+func recentSearchDirs() []string {
+	path, err := recentSearchDirsFile()
+	if err != nil {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	dirs := make([]string, 0, maxRecentSearchDirs)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		dir := strings.TrimSpace(scanner.Text())
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// addRecentSearchDir stores dir as the most recently used search dir.
+func addRecentSearchDir(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	path, err := recentSearchDirsFile()
+	if err != nil {
+		return err
+	}
+
+	dirs := recentSearchDirs()
+	dirs = slices.DeleteFunc(dirs, func(d string) bool {
+		return d == dir
+	})
+	dirs = append([]string{dir}, dirs...)
+	if len(dirs) > maxRecentSearchDirs {
+		dirs = dirs[:maxRecentSearchDirs]
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strings.Join(dirs, "\n")+"\n"), 0o600)
+}
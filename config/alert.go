@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var alertRuleRegex = regexp.MustCompile(`^\s*count\s*(>=|>)\s*(\d+)\s+in\s+(\S+)\s*$`)
+
+// AlertRule notifies moderators only once the number of matches of a query
+// within the sliding window exceeds the count.
+type AlertRule struct {
+	Count  int
+	Window time.Duration
+}
+
+// ParseAlertRule parses rules of the form 'count > 10 in 5m' or 'count >= 10 in 1h'.
+func ParseAlertRule(s string) (*AlertRule, error) {
+	m := alertRuleRegex.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("invalid alert rule %q: must be of the form 'count > 10 in 5m'", s)
+	}
+
+	count, err := strconv.Atoi(m[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid alert rule %q: %w", s, err)
+	}
+	if m[1] == ">=" {
+		count--
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("invalid alert rule %q: count must be at least 1", s)
+	}
+
+	window, err := time.ParseDuration(m[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid alert rule %q: %w", s, err)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("invalid alert rule %q: window must be greater than 0", s)
+	}
+	return &AlertRule{
+		Count:  count,
+		Window: window,
+	}, nil
+}
+
+func (r *AlertRule) String() string {
+	return fmt.Sprintf("count > %d in %s", r.Count, r.Window)
+}
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseASN parses an autonomous system number with or without AS prefix, e.g. 16276 or AS16276.
+func ParseASN(s string) (uint32, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > 2 && strings.EqualFold(s[:2], "as") {
+		s = s[2:]
+	}
+	asn, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid asn %q", s)
+	}
+	return uint32(asn), nil
+}
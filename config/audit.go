@@ -0,0 +1,40 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+func NewAuditConfig() AuditConfig {
+	return AuditConfig{
+		Output: FormatText,
+	}
+}
+
+// AuditConfig is the configuration of the audit subcommand.
+type AuditConfig struct {
+	Input  string        `koanf:"input" short:"i" description:"audit log file written via --audit-log"`
+	User   string        `koanf:"user" short:"u" description:"only print searches of this user"`
+	Since  time.Duration `koanf:"since" description:"only print searches of the given duration, e.g. 168h for the last week"`
+	Output string        `koanf:"output" short:"o" description:"output format, one of 'json' or 'text'"`
+}
+
+func (cfg *AuditConfig) Validate() error {
+	if cfg.Input == "" {
+		return errors.New("input is required")
+	}
+
+	if cfg.Since < 0 {
+		return errors.New("since must not be negative")
+	}
+
+	allowed := []string{FormatJSON, FormatText}
+	lOutput := strings.ToLower(cfg.Output)
+	if !isOneOf(lOutput, allowed...) {
+		return fmt.Errorf("invalid output format %q: must be one of %v", cfg.Output, allowed)
+	}
+	cfg.Output = lOutput
+	return nil
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"text/template"
+)
+
+func NewBanFileConfig() BanFileConfig {
+	return BanFileConfig{
+		Input:    "-",
+		Duration: 60,
+		Reason:   "{{.Nickname}}",
+	}
+}
+
+// BanFileConfig is the configuration of the banfile subcommand.
+type BanFileConfig struct {
+	Input          string             `koanf:"input" short:"i" description:"json results or list of IPs, CIDR ranges and first-last ranges, - for stdin"`
+	Duration       int                `koanf:"duration" short:"d" description:"ban duration in minutes"`
+	Reason         string             `koanf:"reason" short:"r" description:"ban reason template, e.g. '{{.Nickname}}: {{.Text}}', fields: IP, Nickname, Text, Query, Server, File"`
+	ReasonTemplate *template.Template `koanf:"-"`
+}
+
+func (cfg *BanFileConfig) Validate() error {
+	if cfg.Input == "" {
+		return errors.New("input is required")
+	}
+
+	if cfg.Duration < 0 {
+		return errors.New("duration must not be negative")
+	}
+
+	var err error
+	cfg.ReasonTemplate, err = template.New("reason").Option("missingkey=zero").Parse(cfg.Reason)
+	if err != nil {
+		return fmt.Errorf("invalid reason template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+func NewBenchConfig() BenchConfig {
+	return BenchConfig{
+		Size:        "256MB",
+		Files:       8,
+		ChatDensity: 0.3,
+		Seed:        1,
+		ConfigFile:  DefaultConfigFile(),
+		Output:      FormatText,
+	}
+}
+
+// BenchConfig is the configuration of the bench subcommand.
+type BenchConfig struct {
+	Size        string  `koanf:"size" description:"total size of the generated log files, e.g. 1GB"`
+	SizeBytes   int64   `koanf:"-"`
+	Files       int     `koanf:"files" description:"number of generated log files the size is split into"`
+	ChatDensity float64 `koanf:"chat.density" description:"ratio of chat messages among the generated log lines between 0 and 1, the remaining lines are joins, leaves and other server messages"`
+	Seed        uint64  `koanf:"seed" description:"seed of the generated log lines, the same seed and size generate the same files"`
+	Dir         string  `koanf:"dir" description:"directory that the log files are generated in and kept, a temporary directory that is removed afterwards by default"`
+	// PhraseRegex and Concurrency override the settings of the config file.
+	PhraseRegex  string         `koanf:"phrase.regex" short:"p" description:"regex to search for, defaults to the phrase or queries of the config file or a URL regex"`
+	PhraseRegexp *regexp.Regexp `koanf:"-"`
+	Concurrency  int            `koanf:"concurrency" short:"t" description:"number of concurrent workers to use, defaults to the concurrency of the config file or the number of CPUs"`
+	ConfigFile   string         `koanf:"config.file" description:"yaml config file whose scan settings are benchmarked"`
+	Output       string         `koanf:"output" short:"o" description:"output format, one of 'json' or 'text'"`
+}
+
+func (cfg *BenchConfig) Validate() error {
+	var err error
+	cfg.SizeBytes, err = ParseByteSize(cfg.Size)
+	if err != nil {
+		return err
+	}
+	if cfg.SizeBytes <= 0 {
+		return errors.New("size must be greater than 0")
+	}
+
+	if cfg.Files < 1 {
+		return errors.New("files must be at least 1")
+	}
+
+	if cfg.ChatDensity < 0 || cfg.ChatDensity > 1 {
+		return errors.New("chat density must be between 0 and 1")
+	}
+
+	if cfg.PhraseRegex != "" {
+		cfg.PhraseRegexp, err = regexp.Compile(cfg.PhraseRegex)
+		if err != nil {
+			return fmt.Errorf("invalid phrase regex: %w", err)
+		}
+	}
+
+	if cfg.Concurrency < 0 {
+		return errors.New("concurrency must not be negative")
+	}
+
+	allowed := []string{FormatJSON, FormatText}
+	lOutput := strings.ToLower(cfg.Output)
+	if !isOneOf(lOutput, allowed...) {
+		return fmt.Errorf("invalid output format %q: must be one of %v", cfg.Output, allowed)
+	}
+	cfg.Output = lOutput
+	return nil
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ClanTagPlaceholder is the part of a clan tag layout that stands for the tag itself.
+const ClanTagPlaceholder = "ABC"
+
+// ParseClanTags compiles clan tag layouts like [ABC] or ABC| into regexes that match the tag
+// with up to six characters at the start of a nickname. Layouts that start and end with a
+// delimiter, e.g. brackets, also match at the end of a nickname.
+func ParseClanTags(layouts []string) ([]*regexp.Regexp, error) {
+	const tag = `\S{1,6}?`
+	patterns := make([]*regexp.Regexp, 0, len(layouts))
+	for _, layout := range layouts {
+		before, after, found := strings.Cut(layout, ClanTagPlaceholder)
+		if !found || strings.Contains(after, ClanTagPlaceholder) {
+			return nil, fmt.Errorf("invalid clan tag %q: must contain the placeholder %s exactly once", layout, ClanTagPlaceholder)
+		}
+		if before == "" && after == "" {
+			return nil, fmt.Errorf("invalid clan tag %q: must contain a delimiter before or after the placeholder %s", layout, ClanTagPlaceholder)
+		}
+
+		expr := regexp.QuoteMeta(before) + tag + regexp.QuoteMeta(after)
+		if before != "" && after != "" {
+			expr = `^` + expr + `\s*|\s*` + expr + `$`
+		} else {
+			expr = `^` + expr + `\s*`
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid clan tag %q: %w", layout, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
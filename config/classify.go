@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+)
+
+// Classifier is a word list that tags messages with labels and scores.
+type Classifier struct {
+	Labels []ClassifierLabel `koanf:"labels"`
+	// Unless are patterns of quoted or self-directed usage that cancel all labels,
+	// e.g. (?i)^"[^"]*"$ for quoted messages.
+	Unless        []string         `koanf:"unless"`
+	UnlessRegexps []*regexp.Regexp `koanf:"-"`
+}
+
+// ClassifierLabel is assigned with its score to messages that match one of its patterns and none of its unless patterns.
+type ClassifierLabel struct {
+	Name           string           `koanf:"name"`
+	Score          float64          `koanf:"score"`
+	Patterns       []string         `koanf:"patterns"`
+	PatternRegexps []*regexp.Regexp `koanf:"-"`
+	Unless         []string         `koanf:"unless"`
+	UnlessRegexps  []*regexp.Regexp `koanf:"-"`
+}
+
+// LoadClassifier reads a yaml classification word list.
+func LoadClassifier(path string) (*Classifier, error) {
+	k := koanf.New(".")
+	err := k.Load(file.Provider(path), yaml.Parser())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load classification file: %w", err)
+	}
+
+	var c Classifier
+	err = k.Unmarshal("", &c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse classification file: %w", err)
+	}
+
+	if len(c.Labels) == 0 {
+		return nil, fmt.Errorf("classification file %s does not contain any labels", path)
+	}
+
+	c.UnlessRegexps, err = compileAll(c.Unless)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid unless regex: %w", path, err)
+	}
+
+	for idx := range c.Labels {
+		l := &c.Labels[idx]
+		if l.Name == "" {
+			return nil, fmt.Errorf("%s: label %d: name is required", path, idx)
+		}
+		if l.Score <= 0 || l.Score > 1 {
+			return nil, fmt.Errorf("%s: label %s: score must be greater than 0 and at most 1", path, l.Name)
+		}
+		if len(l.Patterns) == 0 {
+			return nil, fmt.Errorf("%s: label %s: patterns are required", path, l.Name)
+		}
+		l.PatternRegexps, err = compileAll(l.Patterns)
+		if err != nil {
+			return nil, fmt.Errorf("%s: label %s: invalid pattern: %w", path, l.Name, err)
+		}
+		l.UnlessRegexps, err = compileAll(l.Unless)
+		if err != nil {
+			return nil, fmt.Errorf("%s: label %s: invalid unless regex: %w", path, l.Name, err)
+		}
+	}
+	return &c, nil
+}
+
+func compileAll(exprs []string) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps, nil
+}
+
+// validateClassify loads the classification word list, unless the classifier is an http endpoint.
+func (cfg *Config) validateClassify() error {
+	if cfg.MinToxicity < 0 || cfg.MinToxicity > 1 {
+		return errors.New("min toxicity must be between 0 and 1")
+	}
+	if cfg.Classify == "" {
+		if cfg.MinToxicity > 0 {
+			return errors.New("min toxicity requires classify")
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(cfg.Classify, "http://") || strings.HasPrefix(cfg.Classify, "https://") {
+		cfg.ClassifyURL = cfg.Classify
+		return nil
+	}
+
+	var err error
+	cfg.Classifier, err = LoadClassifier(cfg.Classify)
+	return err
+}
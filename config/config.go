@@ -1,56 +1,410 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
 	"os"
 	"regexp"
 	"runtime"
 	"strings"
+	"text/template"
+	"time"
+
+	"github.com/expr-lang/expr/vm"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	FormatJSON     = "json"
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatDOT      = "dot"
+	FormatGraphML  = "graphml"
+	FormatCSV      = "csv"
+	FormatNDJSON   = "ndjson"
+)
+
+// ErrRegexRequired is returned when neither a regex, queries file, preset nor patterns file is configured.
+var ErrRegexRequired = errors.New("regex is required")
+
+const (
+	OrderName     = "name"
+	OrderNewest   = "newest"
+	OrderOldest   = "oldest"
+	OrderLargest  = "largest"
+	OrderSmallest = "smallest"
+)
+
+const (
+	OverlapDedupe = "dedupe"
+	OverlapKeep   = "keep"
+)
+
+// OverlapModes are the supported handlings of matches in overlapping rotated log files.
+var OverlapModes = []string{OverlapDedupe, OverlapKeep}
+
+const (
+	InvalidUTF8Replace = "replace"
+	InvalidUTF8Skip    = "skip"
+	InvalidUTF8Raw     = "raw"
+)
+
+// InvalidUTF8Policies are the supported handlings of log lines with invalid UTF-8.
+var InvalidUTF8Policies = []string{InvalidUTF8Replace, InvalidUTF8Skip, InvalidUTF8Raw}
+
+const (
+	IPFormatStripPort = "strip-port"
+	IPFormatKeepPort  = "keep-port"
+	IPFormatAnonymize = "anonymize-last-octet"
+)
+
+// IPFormats are the supported formats of IPs in the outputs.
+var IPFormats = []string{IPFormatStripPort, IPFormatKeepPort, IPFormatAnonymize}
+
+// Orders are the supported orders in which files are dispatched to the workers.
+var Orders = []string{OrderName, OrderNewest, OrderOldest, OrderLargest, OrderSmallest}
+
+const (
+	SortTime = "time"
+	SortName = "name"
+	SortIP   = "ip"
+	SortFile = "file"
+)
+
+// SortKeys are the supported keys that the matches can be sorted by.
+var SortKeys = []string{SortTime, SortName, SortIP, SortFile}
+
+// GroupKeys are the supported keys that the matches can be grouped by.
+var GroupKeys = []string{SortName, SortIP, SortFile}
+
+const (
+	RedactPartial = "partial"
+	RedactHash    = "hash"
+	RedactFull    = "full"
+)
+
+// RedactModes are the supported modes of IP redaction.
+var RedactModes = []string{RedactPartial, RedactHash, RedactFull}
+
+const (
+	EventChat    = "chat"
+	EventKill    = "kill"
+	EventPickup  = "pickup"
+	EventConnect = "connect"
+	EventVote    = "vote"
+	EventRcon    = "rcon"
+	EventBan     = "ban"
+	EventFinish  = "finish"
+	EventOther   = "other"
+)
+
+// EventTypes are the types of log lines that the phrase regex can be applied to.
+var EventTypes = []string{EventChat, EventKill, EventPickup, EventConnect, EventVote, EventRcon, EventBan, EventFinish, EventOther}
+
+const (
+	// EventsBans searches the bans, kicks and mutes instead of the chat.
+	EventsBans = "bans"
+	// EventsFinishes searches the race finishes of ddnet servers instead of the chat.
+	EventsFinishes = "finishes"
 )
 
+// EventsModes are the search modes that replace the chat search.
+var EventsModes = map[string]string{
+	EventsBans:     EventBan,
+	EventsFinishes: EventFinish,
+}
+
+// GraphFormats are the supported formats of the graph output.
+var GraphFormats = []string{FormatDOT, FormatGraphML, FormatJSON}
+
 const (
-	FormatJSON = "json"
-	FormatText = "text"
+	// VerbosityInfo logs which files are skipped and the progress of scheduled scans.
+	VerbosityInfo = 1
+	// VerbosityDebug additionally logs every file that is opened.
+	VerbosityDebug = 2
 )
 
 func NewConfig() Config {
 	return Config{
-		SearchDir:    ".",
-		FileRegex:    `.*\.log$`,
-		Deduplicate:  false,
-		Output:       FormatText,
-		ArchiveRegex: `\.(7z|bz2|gz|tar|xz|zip|xz|zst|lz)$`,
-		Concurrency:  max(1, runtime.NumCPU()),
+		SearchDir:          ".",
+		FileRegex:          `.*\.log$`,
+		Deduplicate:        false,
+		Output:             FormatText,
+		ArchiveRegex:       `\.(7z|bz2|gz|tar|xz|zip|xz|zst|lz)$`,
+		Concurrency:        max(1, runtime.NumCPU()),
+		ConfigFile:         DefaultConfigFile(),
+		LogFormat:          FormatText,
+		MaxTempSize:        "0",
+		Order:              OrderName,
+		RotationOverlap:    OverlapKeep,
+		InvalidUTF8:        InvalidUTF8Replace,
+		IPFormat:           IPFormatStripPort,
+		EventType:          EventChat,
+		ClanTags:           "[ABC],(ABC),{ABC},<ABC>,ABC|",
+		DNSTimeout:         2 * time.Second,
+		DNSConcurrency:     16,
+		MasterURL:          "https://master1.ddnet.org/ddnet/15/servers.json",
+		NotifyInterval:     5 * time.Second,
+		WebhookRetries:     3,
+		MaxReadBandwidth:   "0",
+		ReadRetries:        3,
+		CheckpointInterval: 5 * time.Minute,
+		ReadRetryDelay:     time.Second,
+		ElasticsearchIndex: "twlog-matches",
+		PostgresTable:      "twlog_matches",
+		ClickhouseTable:    "twlog_matches",
+		NATSSubject:        "twlog.matches",
 	}
 }
 
 type Config struct {
-	PhraseRegex     string         `koanf:"phrase.regex" short:"p" description:"regex to search for that a player said"`
-	PhraseRegexp    *regexp.Regexp `koanf:"-"`
-	SearchDir       string         `koanf:"search.dir" short:"d" description:"directory to search for files recursively"`
-	FileRegex       string         `koanf:"file.regex" short:"f" description:"regex to match files in the search dir"`
-	FileRegexp      *regexp.Regexp `koanf:"-"`
-	Deduplicate     bool           `koanf:"deduplicate" short:"D" description:"deduplicate objects based on all fields"`
-	Extended        bool           `koanf:"extended" short:"e" description:"add two additional fields, file and id to the output"`
-	IPsOnly         bool           `koanf:"ips.only" short:"i" description:"only print IP addresses"`
-	Output          string         `koanf:"output" short:"o" description:"output format, one of 'json' or 'text'"`
-	ArchiveRegex    string         `koanf:"archive.regex" short:"a" description:"regex to match archive files in the search dir"`
-	ArchiveRegexp   *regexp.Regexp `koanf:"-"`
-	IncludeArchives bool           `koanf:"include.archive" short:"A" description:"search inside archive files"`
-	Concurrency     int            `koanf:"concurrency" short:"t" description:"number of concurrent workers to use"`
+	PhraseRegex           string             `koanf:"phrase.regex" short:"p" description:"regex to search for that a player said"`
+	PhraseRegexp          *regexp.Regexp     `koanf:"-"`
+	NameRegex             string             `koanf:"name.regex" short:"n" description:"regex that the nickname of a match must match, confusable and invisible characters are normalized before matching"`
+	DetectLanguage        bool               `koanf:"detect.language" description:"add the detected ISO 639-1 language of the message to the matches, e.g. de, which is a best guess for short messages"`
+	MinMessageLength      int                `koanf:"min.message.length" description:"only keep matches whose message has at least this many characters, e.g. 2 to exclude one character spam"`
+	MaxMessageLength      int                `koanf:"max.message.length" description:"only keep matches whose message has at most this many characters, 0 for unlimited"`
+	WordCount             string             `koanf:"word.count" description:"number of words that the message of a match must have, e.g. 3 for exactly three, 20- for at least 20 to find pasted walls of text, -5 for at most five or 2-5"`
+	MinWords              int                `koanf:"-"`
+	MaxWords              int                `koanf:"-"`
+	Language              string             `koanf:"language" description:"comma separated ISO 639-1 languages that the detected language of a match must be one of, e.g. de,fr, implies --detect-language"`
+	LanguageList          []string           `koanf:"-"`
+	Classify              string             `koanf:"classify" description:"yaml word list with labels, scores and unless patterns or URL of an http classifier that tags every match with labels and a toxicity score"`
+	Classifier            *Classifier        `koanf:"-"`
+	ClassifyURL           string             `koanf:"-"`
+	MinToxicity           float64            `koanf:"min.toxicity" description:"only keep matches whose toxicity score of the classifier is at least this value between 0 and 1"`
+	NameRegexp            *regexp.Regexp     `koanf:"-"`
+	StripClanTags         bool               `koanf:"strip.clan.tags" description:"remove clan tags from nicknames before filtering and grouping, so that [xyz] Player and Player are the same player, extended output keeps the raw nickname"`
+	ClanTags              string             `koanf:"clan.tags" description:"comma separated layouts of the clan tags removed by --strip-clan-tags, ABC stands for a tag of up to six characters"`
+	ClanTagRegexps        []*regexp.Regexp   `koanf:"-"`
+	Map                   string             `koanf:"map" description:"regex that the map of a match must match, e.g. '^ctf5$'"`
+	MapRegexp             *regexp.Regexp     `koanf:"-"`
+	ClientVersionRegex    string             `koanf:"client.version.regex" description:"regex that the client version announced by the player must match, e.g. to find known cheat clients"`
+	ClientVersionRegexp   *regexp.Regexp     `koanf:"-"`
+	EventType             string             `koanf:"event.type" description:"comma separated types of log lines the phrase regex is applied to, any of 'chat', 'kill', 'pickup', 'connect', 'vote', 'rcon', 'ban', 'finish' or 'other'"`
+	EventTypeList         []string           `koanf:"-"`
+	Events                string             `koanf:"events" description:"search mode that replaces the chat search, 'bans' searches ban, kick and mute lines and adds their action, actor, reason and duration, 'finishes' searches race finishes and adds their finish time, the phrase regex is optional"`
+	MaxFinishTime         time.Duration      `koanf:"max.finish.time" description:"only print race finishes that are faster than this duration, e.g. 25s"`
+	BestFinishes          bool               `koanf:"best.finishes" description:"print the fastest finish and the number of finishes per map and player instead of the matches, requires --events finishes"`
+	SearchDir             string             `koanf:"search.dir" short:"d" description:"directory to search for files recursively"`
+	FileRegex             string             `koanf:"file.regex" short:"f" description:"regex to match files in the search dir"`
+	FileRegexp            *regexp.Regexp     `koanf:"-"`
+	Deduplicate           bool               `koanf:"deduplicate" short:"D" description:"deduplicate objects based on all fields"`
+	Extended              bool               `koanf:"extended" short:"e" description:"add two additional fields, file and id to the output"`
+	IPsOnly               bool               `koanf:"ips.only" short:"i" description:"only print the unique IP addresses sorted by address, one per line"`
+	IPFormat              string             `koanf:"ip.format" description:"format of the IPs in all outputs, one of 'strip-port', 'keep-port' or 'anonymize-last-octet', which zeroes the last octet of IPv4 and the last 64 bits of IPv6 addresses"`
+	AggregateCIDR         int                `koanf:"aggregate.cidr" description:"collapse the IPs of --ips-only into the smallest CIDR block per /24 IPv4 or /48 IPv6 network with at least this many distinct addresses, e.g. as candidates for range bans, 0 disables the aggregation"`
+	Output                string             `koanf:"output" short:"o" description:"output format, one of 'json' or 'text'"`
+	Raw                   bool               `koanf:"raw" description:"do not escape control characters and ANSI escape sequences of nicknames and messages in the text output"`
+	InvalidUTF8           string             `koanf:"invalid.utf8" description:"handling of log lines with invalid UTF-8, 'replace' replaces the invalid bytes with U+FFFD, 'skip' skips the lines and 'raw' additionally adds the original message base64 encoded to the json output"`
+	ArchiveRegex          string             `koanf:"archive.regex" short:"a" description:"regex to match archive files in the search dir"`
+	ArchiveRegexp         *regexp.Regexp     `koanf:"-"`
+	IncludeArchives       bool               `koanf:"include.archive" short:"A" description:"search inside archive files"`
+	SkipDuplicateFiles    bool               `koanf:"skip.duplicate.files" description:"skip files and archive members whose size and first and last 64KiB equal an already scanned file, e.g. logs that exist both loose and inside of backup archives"`
+	RotationOverlap       string             `koanf:"rotation.overlap" description:"handling of matches with the same timestamp and content in multiple files, e.g. after copytruncate log rotation, one of 'keep' or 'dedupe', which reports them only for a single file"`
+	Concurrency           int                `koanf:"concurrency" short:"t" description:"number of concurrent workers to use"`
+	QueriesFile           string             `koanf:"queries" short:"q" description:"yaml file with named queries that are evaluated in a single pass"`
+	PatternsFile          string             `koanf:"patterns" description:"word list file with one category:severity:regex per line that replaces the phrase regex"`
+	MinSeverity           int                `koanf:"min.severity" description:"only print matches with at least this severity"`
+	Scores                bool               `koanf:"scores" description:"print the sum of the severities of the matches per player instead of the matches"`
+	CountOnly             bool               `koanf:"count.only" description:"print only the number of matches per file and archive member like grep -c, the output format can also be 'csv'"`
+	SortBy                string             `koanf:"sort.by" description:"sort the matches by one of 'time', 'name', 'ip' or 'file' instead of the scan order"`
+	GroupBy               string             `koanf:"group.by" description:"group the matches by one of 'name', 'ip' or 'file' with a header per group in the text output and nested objects in the json output"`
+	MaxPerPlayer          int                `koanf:"max.per.player" description:"print at most this many matches per query and nickname, the matches of capped players show the true number of matches of the player, 0 for unlimited"`
+	CollapseDummies       bool               `koanf:"collapse.dummies" description:"attribute the matches of dummies to the player that connected first from the same IP in scores, graphs and sql queries"`
+	SQL                   string             `koanf:"sql" description:"sql query over the table matches of all results that replaces the output, e.g. 'SELECT nickname, count(*) FROM matches GROUP BY 1'"`
+	Graph                 string             `koanf:"graph" description:"print the nicknames and IPs of all matches as graph instead of the matches, one of 'dot', 'graphml' or 'json'"`
+	Schedule              string             `koanf:"schedule" description:"cron expression, keeps running and scans newly modified files whenever it fires"`
+	ScheduleSpec          cron.Schedule      `koanf:"-"`
+	ConfigFile            string             `koanf:"config.file" description:"yaml config file with default values and presets"`
+	Preset                string             `koanf:"preset" short:"P" description:"name of the preset from the config file to run"`
+	DryRun                bool               `koanf:"dry.run" description:"list the files that would be scanned and estimate the scan duration"`
+	Verbosity             int                `koanf:"verbose" flag:"false" description:"log verbosity, -v logs skipped files, -vv logs every opened file"`
+	Quiet                 bool               `koanf:"quiet" description:"only log errors"`
+	LogFormat             string             `koanf:"log.format" description:"format of the diagnostics on stderr, one of 'json' or 'text'"`
+	StatusInterval        time.Duration      `koanf:"status.interval" description:"log a status line with the scanned files, bytes per second and matches so far at this interval during scans, e.g. 30s, also when --quiet is set"`
+	GrepExitCodes         bool               `koanf:"grep.exit.codes" description:"exit with 0 when matches were found, 1 when none were found and 2 on errors"`
+	FollowSymlinks        bool               `koanf:"follow.symlinks" description:"follow symbolic links to files and directories in the search dir"`
+	OneFileSystem         bool               `koanf:"one.file.system" description:"do not descend into directories on other file systems than the search dir"`
+	MaxDepth              int                `koanf:"max.depth" description:"maximum number of directory levels below the search dir to descend into, 0 for unlimited"`
+	TempDir               string             `koanf:"temp.dir" description:"directory that large archive files are extracted to, defaults to the system temp dir"`
+	MaxTempSize           string             `koanf:"max.temp.size" description:"maximum disk space used for extracting archive files, e.g. 10GB, 0 for unlimited"`
+	MaxTempBytes          int64              `koanf:"-"`
+	WithPosition          bool               `koanf:"with.position" description:"add the line number and byte offset of each match to the extended output"`
+	RelativeTime          bool               `koanf:"relative.time" description:"show the timestamps of matches relative to now in the extended text output, e.g. 3 days ago"`
+	Hashes                bool               `koanf:"hashes" description:"add the sha256 of the matched line and of its source file to the extended output, e.g. to verify quoted evidence against the original logs later"`
+	RDNS                  bool               `koanf:"rdns" description:"resolve the IPs of matches to hostnames via reverse DNS lookups"`
+	DNSTimeout            time.Duration      `koanf:"dns.timeout" description:"timeout of a single reverse DNS lookup"`
+	DNSConcurrency        int                `koanf:"dns.concurrency" description:"maximum number of concurrent reverse DNS lookups"`
+	VPNLists              string             `koanf:"vpn.lists" description:"comma separated files or URLs with IP ranges of VPNs, proxies and data centers, one IP or CIDR per line"`
+	VPNListSources        []string           `koanf:"-"`
+	FlagVPNOnly           bool               `koanf:"flag.vpn.only" description:"only print matches whose IP is located in the vpn lists"`
+	KnownBans             string             `koanf:"known.bans" description:"comma separated bans.cfg files or directories with .cfg files, matches whose IP is already banned are flagged"`
+	KnownBansPaths        []string           `koanf:"-"`
+	UnbannedOnly          bool               `koanf:"unbanned.only" description:"only print matches whose IP is not banned in the known bans"`
+	ASNDatabase           string             `koanf:"asn.database" description:"local file or URL of an iptoasn.com ip2asn tsv database, optionally gzip compressed, the IPs of matches are mapped to their ASN and organization"`
+	ASNExclude            string             `koanf:"asn.exclude" description:"comma separated ASNs whose matches are not printed, e.g. 16276,AS24940"`
+	ASNExcludeList        []uint32           `koanf:"-"`
+	Where                 string             `koanf:"where" description:"expression that every match must fulfill, e.g. 'ip startsWith \"84.\" && len(text) > 20'"`
+	WhereProgram          *vm.Program        `koanf:"-"`
+	Script                string             `koanf:"script" description:"lua script whose function match(m) is called for every match and returns nil or false to drop it, true to keep it or the modified match"`
+	Online                bool               `koanf:"online" description:"look up on which servers of the master server list the nicknames of matches are currently online"`
+	MasterURL             string             `koanf:"master.url" description:"URL of the ddnet http master server list"`
+	EconAddress           string             `koanf:"econ.address" description:"host:port of a server's econ, the queries are applied to its live output instead of the log files"`
+	EconPassword          string             `koanf:"econ.password" description:"password of the server's econ"`
+	EconResponse          string             `koanf:"econ.response" description:"console command template that is executed on every live match, player controlled fields must be quoted with the quote function, e.g. 'kick {{.ID}}' or 'say {{quote .Nickname}} was kicked'"`
+	EconResponseTemplate  *template.Template `koanf:"-"`
+	NotifyDiscord         string             `koanf:"notify.discord" description:"discord webhook URL that matches are posted to"`
+	NotifyTelegram        string             `koanf:"notify.telegram" description:"telegram bot token and chat id of the form <token>:<chat id> that matches are sent to"`
+	TelegramToken         string             `koanf:"-"`
+	TelegramChatID        string             `koanf:"-"`
+	NotifyInterval        time.Duration      `koanf:"notify.interval" description:"minimum interval between two notifications, matches in between are batched"`
+	Alert                 string             `koanf:"alert" description:"notify only once the matches of a query within a sliding window exceed a count, e.g. 'count > 10 in 5m', requires an econ address or a schedule"`
+	WebhookURL            string             `koanf:"webhook.url" description:"URL that batches of matches are posted to as json"`
+	WebhookHeaders        string             `koanf:"webhook.headers" description:"semicolon separated http headers of the webhook requests, e.g. 'Authorization: Bearer <token>'"`
+	WebhookHTTPHeaders    http.Header        `koanf:"-"`
+	WebhookTemplate       string             `koanf:"webhook.template" description:"template of the webhook request body, the dot is the list of matches, e.g. '{\"text\": {{json .}}}'"`
+	WebhookBodyTemplate   *template.Template `koanf:"-"`
+	WebhookRetries        int                `koanf:"webhook.retries" description:"number of retries with exponential backoff of failed webhook requests"`
+	MetricsAddress        string             `koanf:"metrics.address" description:"address that prometheus metrics are served at under /metrics, e.g. :9100"`
+	StreamAddress         string             `koanf:"stream.address" description:"address that live matches are streamed at as server-sent events under /matches, e.g. :8471, requires an econ address or a schedule"`
+	StreamToken           string             `koanf:"stream.token" description:"bearer token that clients of the match stream must send, required unless the stream address is a loopback address"`
+	ElasticsearchURL      string             `koanf:"elasticsearch.url" description:"elasticsearch or opensearch URL that matches are bulk indexed into"`
+	ElasticsearchIndex    string             `koanf:"elasticsearch.index" description:"elasticsearch index of the matches"`
+	ElasticsearchUsername string             `koanf:"elasticsearch.username" description:"elasticsearch basic auth username"`
+	ElasticsearchPassword string             `koanf:"elasticsearch.password" description:"elasticsearch basic auth password"`
+	LokiURL               string             `koanf:"loki.url" description:"grafana loki URL that matches are pushed to with server, query and player labels"`
+	LokiTenant            string             `koanf:"loki.tenant" description:"loki tenant id, sent as X-Scope-OrgID header"`
+	PostgresDSN           string             `koanf:"postgres.dsn" description:"postgres connection string, matches are inserted into the postgres table"`
+	PostgresTable         string             `koanf:"postgres.table" description:"postgres table of the matches, created in case it does not exist"`
+	ClickhouseURL         string             `koanf:"clickhouse.url" description:"clickhouse http interface URL, matches are inserted into the clickhouse table"`
+	ClickhouseTable       string             `koanf:"clickhouse.table" description:"clickhouse table of the matches, created in case it does not exist"`
+	ClickhouseUsername    string             `koanf:"clickhouse.username" description:"clickhouse username"`
+	ClickhousePassword    string             `koanf:"clickhouse.password" description:"clickhouse password"`
+	NATSURL               string             `koanf:"nats.url" description:"nats server URL, every match is published as json event"`
+	NATSSubject           string             `koanf:"nats.subject" description:"nats subject that matches are published to"`
+	RedactIPs             string             `koanf:"redact.ips" description:"mask IPs in all outputs and sinks, one of 'partial', 'hash' or 'full'"`
+	RedactKey             string             `koanf:"redact.key" description:"secret key of the hash redaction, hashes of a random key are only consistent within a single process"`
+	AuditLog              string             `koanf:"audit.log" description:"append-only json lines file that records who searched for what and how many matches were found"`
+	StatsFile             string             `koanf:"stats.file" description:"json file that the files, bytes, durations, errors and match counts of the scan are written to, {time} is replaced with the start time of the scan"`
+	SaveManifest          string             `koanf:"save.manifest" description:"json file that the queries, configuration without secrets, scanned files with their sha256 and the tool version are written to after a complete scan, in order to reproduce it with --replay, {time} is replaced with the start time of the scan"`
+	Replay                string             `koanf:"replay" description:"manifest file of --save-manifest whose configuration, queries and files are scanned again, fails in case a file or query changed, flags override the recorded configuration"`
+	ReplayManifest        *Manifest          `koanf:"-"`
+	Workers               string             `koanf:"workers" description:"comma separated URLs of workers that scan their local search dir instead of this host, e.g. http://storage1:8470"`
+	WorkerURLs            []string           `koanf:"-"`
+	WorkerListen          string             `koanf:"worker.listen" description:"run as worker that scans the local search dir for the queries of a coordinator, e.g. :8470"`
+	WorkerToken           string             `koanf:"worker.token" description:"shared secret of the coordinator and its workers"`
+	ServerIDRegex         string             `koanf:"server.id.regex" description:"regex applied to the file path that extracts the server of a match, the first capture group or the whole match"`
+	ServerIDRegexp        *regexp.Regexp     `koanf:"-"`
+	Order                 string             `koanf:"order" description:"order in which files are scanned, one of 'name', 'newest', 'oldest', 'largest' or 'smallest'"`
+	MaxReadBandwidth      string             `koanf:"max.read.bandwidth" description:"maximum number of bytes per second that all workers read from log files and archives in total, e.g. 100MB/s, 0 for unlimited"`
+	MaxReadBytesPerSecond int64              `koanf:"-"`
+	IONice                string             `koanf:"io.nice" description:"io scheduling priority of the process like ionice, 'idle' only reads when no other process uses the disk, 0 to 7 is the best effort priority where 7 is the lowest, windows only supports background priority"`
+	IONiceIdle            bool               `koanf:"-"`
+	IONiceLevel           int                `koanf:"-"`
+	ReadRetries           int                `koanf:"read.retries" description:"number of times a file is read again after transient read errors of network file systems, e.g. NFS timeouts or SMB disconnects, before it fails"`
+	ReadRetryDelay        time.Duration      `koanf:"read.retry.delay" description:"delay before the first read retry, it doubles with every further retry"`
+	Timeout               time.Duration      `koanf:"timeout" description:"stop the scan of files after this duration, e.g. 30m, print the matches found so far and mark the stats file as truncated, 0 for unlimited"`
+	CheckpointFile        string             `koanf:"checkpoint.file" description:"json file that the completed files and their matches are periodically written to during the scan, so that --resume can continue an interrupted scan, it is removed after the results were printed"`
+	CheckpointInterval    time.Duration      `koanf:"checkpoint.interval" description:"interval at which the checkpoint is written"`
+	Resume                bool               `koanf:"resume" description:"continue the scan of the checkpoint file, the completed files are skipped and their matches are printed together with the new ones, a missing checkpoint starts a new scan"`
+	OpenWith              string             `koanf:"open.with" description:"editor command that opens the source file of a match picked interactively after the scan, {file} and {line} are replaced, e.g. 'code --goto {file}:{line}' or 'vim +{line} {file}'"`
+	OpenWithArgs          []string           `koanf:"-"`
+
+	queries []*Query
 }
 
 func (cfg *Config) Validate() error {
-	if cfg.PhraseRegex == "" {
-		return errors.New("regex is required")
+	if cfg.Events != "" {
+		lEvents := strings.ToLower(cfg.Events)
+		eventType, found := EventsModes[lEvents]
+		if !found {
+			return fmt.Errorf("invalid events %q: must be one of %v", cfg.Events, []string{EventsBans, EventsFinishes})
+		}
+		if cfg.QueriesFile != "" || cfg.Preset != "" || cfg.PatternsFile != "" || cfg.WorkerListen != "" {
+			return errors.New("events are mutually exclusive with queries file, preset, patterns file and worker listen")
+		}
+		cfg.Events = lEvents
+		cfg.EventType = eventType
+		if cfg.PhraseRegex == "" {
+			// every sanction
+			cfg.PhraseRegex = ".*"
+		}
 	}
 
-	re, err := regexp.Compile(cfg.PhraseRegex)
+	sources := 0
+	for _, s := range []string{cfg.PhraseRegex, cfg.QueriesFile, cfg.Preset, cfg.PatternsFile} {
+		if s != "" {
+			sources++
+		}
+	}
+	if cfg.WorkerListen != "" {
+		// workers receive their queries from the coordinator
+		if sources > 0 {
+			return errors.New("worker listen is mutually exclusive with regex, queries file, preset and patterns file")
+		}
+		if cfg.WorkerToken == "" {
+			return errors.New("worker listen requires a worker token")
+		}
+		if cfg.EconAddress != "" || cfg.Schedule != "" || cfg.DryRun || cfg.Workers != "" {
+			return errors.New("worker listen, econ address, schedule, dry run and workers are mutually exclusive")
+		}
+	} else if sources == 0 {
+		return ErrRegexRequired
+	}
+	if sources > 1 {
+		return errors.New("regex, queries file, preset and patterns file are mutually exclusive")
+	}
+
+	if cfg.MinSeverity < 0 {
+		return errors.New("min severity must not be negative")
+	}
+
+	if cfg.MaxPerPlayer > 0 && (cfg.Scores || cfg.CountOnly || cfg.IPsOnly) {
+		return errors.New("max per player, scores, count only and ips only flags are mutually exclusive")
+	}
+
+	if cfg.Scores && cfg.IPsOnly {
+		return errors.New("scores and ips only are mutually exclusive")
+	}
+
+	if cfg.MaxFinishTime < 0 {
+		return errors.New("max finish time must not be negative")
+	}
+
+	if cfg.BestFinishes {
+		if cfg.Events != EventsFinishes {
+			return errors.New("best finishes require --events finishes")
+		}
+		if cfg.Scores || cfg.IPsOnly || cfg.CountOnly || cfg.GroupBy != "" {
+			return errors.New("best finishes, scores, count only, group by and ips only are mutually exclusive")
+		}
+	}
+
+	var (
+		re  *regexp.Regexp
+		err error
+	)
+	if cfg.PhraseRegex != "" {
+		re, err = regexp.Compile(cfg.PhraseRegex)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+		cfg.PhraseRegexp = re
+	}
+
+	cfg.SortBy, cfg.GroupBy, err = validateSortAndGroup(cfg.SortBy, cfg.GroupBy)
 	if err != nil {
-		return fmt.Errorf("invalid regex: %w", err)
+		return err
+	}
+	if cfg.GroupBy != "" && (cfg.Scores || cfg.CountOnly) {
+		return errors.New("group by, scores and count only are mutually exclusive")
 	}
-	cfg.PhraseRegexp = re
 
 	if cfg.SearchDir == "" {
 		return errors.New("search dir is required")
@@ -68,13 +422,53 @@ func (cfg *Config) Validate() error {
 		return errors.New("file regex is required")
 	}
 
-	re, err = regexp.Compile(cfg.FileRegex)
+	re, err = compilePathRegex(cfg.FileRegex)
 	if err != nil {
 		return fmt.Errorf("invalid file regex: %w", err)
 	}
 	cfg.FileRegexp = re
 
+	cfg.EventTypeList, err = parseEventTypes(splitList(cfg.EventType))
+	if err != nil {
+		return err
+	}
+
+	cfg.NameRegexp = nil
+	if cfg.NameRegex != "" {
+		cfg.NameRegexp, err = regexp.Compile(cfg.NameRegex)
+		if err != nil {
+			return fmt.Errorf("invalid name regex: %w", err)
+		}
+	}
+
+	cfg.ClanTagRegexps = nil
+	if cfg.StripClanTags {
+		cfg.ClanTagRegexps, err = ParseClanTags(splitList(cfg.ClanTags))
+		if err != nil {
+			return err
+		}
+	}
+
+	cfg.MapRegexp = nil
+	if cfg.Map != "" {
+		cfg.MapRegexp, err = regexp.Compile(cfg.Map)
+		if err != nil {
+			return fmt.Errorf("invalid map regex: %w", err)
+		}
+	}
+
+	cfg.ClientVersionRegexp = nil
+	if cfg.ClientVersionRegex != "" {
+		cfg.ClientVersionRegexp, err = regexp.Compile(cfg.ClientVersionRegex)
+		if err != nil {
+			return fmt.Errorf("invalid client version regex: %w", err)
+		}
+	}
+
 	allowed := []string{FormatJSON, FormatText}
+	if cfg.CountOnly {
+		allowed = append(allowed, FormatCSV)
+	}
 	lOutput := strings.ToLower(cfg.Output)
 	if !isOneOf(lOutput, allowed...) {
 		return fmt.Errorf("invalid output format %q: must be one of %v", cfg.Output, allowed)
@@ -85,21 +479,494 @@ func (cfg *Config) Validate() error {
 		return errors.New("extended and ips only flags are mutually exclusive")
 	}
 
+	if cfg.WithPosition && cfg.IPsOnly {
+		return errors.New("with position and ips only flags are mutually exclusive")
+	}
+
+	if cfg.RelativeTime && cfg.IPsOnly {
+		return errors.New("relative time and ips only flags are mutually exclusive")
+	}
+
+	if cfg.Hashes && cfg.IPsOnly {
+		return errors.New("hashes and ips only flags are mutually exclusive")
+	}
+
+	if cfg.AggregateCIDR < 0 {
+		return errors.New("aggregate cidr must not be negative")
+	}
+	if cfg.AggregateCIDR > 0 && !cfg.IPsOnly && cfg.QueriesFile == "" {
+		return errors.New("aggregate cidr requires the ips only flag")
+	}
+
+	logFormats := []string{FormatJSON, FormatText}
+	lLogFormat := strings.ToLower(cfg.LogFormat)
+	if !isOneOf(lLogFormat, logFormats...) {
+		return fmt.Errorf("invalid log format %q: must be one of %v", cfg.LogFormat, logFormats)
+	}
+	cfg.LogFormat = lLogFormat
+
+	if cfg.Quiet && cfg.Verbosity > 0 {
+		return errors.New("quiet and verbose flags are mutually exclusive")
+	}
+
+	if cfg.StatusInterval < 0 {
+		return errors.New("status interval must not be negative")
+	}
+
 	if cfg.IncludeArchives || cfg.ArchiveRegex != "" {
-		re, err = regexp.Compile(cfg.ArchiveRegex)
+		re, err = compilePathRegex(cfg.ArchiveRegex)
 		if err != nil {
 			return fmt.Errorf("invalid archive regex: %w", err)
 		}
 		cfg.ArchiveRegexp = re
 	}
 
+	if cfg.ServerIDRegex != "" {
+		re, err = compilePathRegex(cfg.ServerIDRegex)
+		if err != nil {
+			return fmt.Errorf("invalid server id regex: %w", err)
+		}
+		cfg.ServerIDRegexp = re
+	}
+
+	if cfg.DNSTimeout <= 0 {
+		return errors.New("dns timeout must be greater than 0")
+	}
+
+	if cfg.DNSConcurrency < 1 {
+		return errors.New("dns concurrency must be greater than 0")
+	}
+
+	cfg.VPNListSources = splitList(cfg.VPNLists)
+	if cfg.FlagVPNOnly && len(cfg.VPNListSources) == 0 {
+		return errors.New("flag vpn only requires vpn lists")
+	}
+
+	if cfg.RedactIPs != "" {
+		lRedact := strings.ToLower(cfg.RedactIPs)
+		if !isOneOf(lRedact, RedactModes...) {
+			return fmt.Errorf("invalid redact ips %q: must be one of %v", cfg.RedactIPs, RedactModes)
+		}
+		cfg.RedactIPs = lRedact
+	}
+
+	cfg.KnownBansPaths = splitList(cfg.KnownBans)
+	if cfg.UnbannedOnly && len(cfg.KnownBansPaths) == 0 {
+		return errors.New("unbanned only requires known bans")
+	}
+
+	excludedASNs := splitList(cfg.ASNExclude)
+	cfg.ASNExcludeList = make([]uint32, 0, len(excludedASNs))
+	for _, asn := range excludedASNs {
+		n, err := ParseASN(asn)
+		if err != nil {
+			return fmt.Errorf("invalid asn exclude: %w", err)
+		}
+		cfg.ASNExcludeList = append(cfg.ASNExcludeList, n)
+	}
+	if len(cfg.ASNExcludeList) > 0 && cfg.ASNDatabase == "" {
+		return errors.New("asn exclude requires an asn database")
+	}
+
+	if (cfg.SortBy != "" || cfg.GroupBy != "") && (cfg.EconAddress != "" || cfg.SQL != "" || cfg.Graph != "") {
+		return errors.New("sort by and group by are not supported with econ address, sql and graph")
+	}
+
+	if (cfg.SaveManifest != "" || cfg.Replay != "") && (cfg.EconAddress != "" || cfg.WorkerListen != "" || cfg.Workers != "") {
+		return errors.New("save manifest and replay require a scan of local files, they are not supported with econ address, worker listen and workers")
+	}
+	if cfg.Replay != "" && cfg.Schedule != "" {
+		return errors.New("replay and schedule are mutually exclusive")
+	}
+
+	err = cfg.validateClassify()
+	if err != nil {
+		return err
+	}
+
+	if cfg.MinMessageLength < 0 || cfg.MaxMessageLength < 0 {
+		return errors.New("min and max message length must not be negative")
+	}
+	if cfg.MaxMessageLength > 0 && cfg.MinMessageLength > cfg.MaxMessageLength {
+		return errors.New("min message length must not be greater than max message length")
+	}
+	if cfg.WordCount != "" {
+		cfg.MinWords, cfg.MaxWords, err = ParseCountRange(cfg.WordCount)
+		if err != nil {
+			return fmt.Errorf("invalid word count: %w", err)
+		}
+	}
+
+	cfg.LanguageList = splitList(strings.ToLower(cfg.Language))
+	cfg.DetectLanguage = cfg.DetectLanguage || len(cfg.LanguageList) > 0
+
+	if cfg.MaxPerPlayer < 0 {
+		return errors.New("max per player must not be negative")
+	}
+	if cfg.MaxPerPlayer > 0 && cfg.EconAddress != "" {
+		return errors.New("max per player is not supported with econ address")
+	}
+
+	if cfg.StatsFile != "" && (cfg.EconAddress != "" || cfg.WorkerListen != "") {
+		return errors.New("stats file requires a scan of files, it is not supported with econ address or worker listen")
+	}
+
+	if cfg.StreamAddress != "" && cfg.EconAddress == "" && cfg.Schedule == "" {
+		return errors.New("stream address requires an econ address or a schedule")
+	}
+	if cfg.StreamAddress != "" && cfg.StreamToken == "" && !isLoopbackAddress(cfg.StreamAddress) {
+		// the matches contain the IPs of the players
+		return errors.New("stream address requires a stream token unless it is a loopback address")
+	}
+
+	if cfg.SQL != "" && (cfg.EconAddress != "" || cfg.WorkerListen != "") {
+		return errors.New("sql requires a scan of files, it is not supported with econ address or worker listen")
+	}
+
+	if cfg.Graph != "" {
+		lGraph := strings.ToLower(cfg.Graph)
+		if !isOneOf(lGraph, GraphFormats...) {
+			return fmt.Errorf("invalid graph format %q: must be one of %v", cfg.Graph, GraphFormats)
+		}
+		cfg.Graph = lGraph
+		if cfg.SQL != "" {
+			return errors.New("graph and sql are mutually exclusive")
+		}
+		if cfg.EconAddress != "" || cfg.WorkerListen != "" {
+			return errors.New("graph requires a scan of files, it is not supported with econ address or worker listen")
+		}
+	}
+
+	cfg.MaxReadBytesPerSecond, err = ParseBandwidth(cfg.MaxReadBandwidth)
+	if err != nil {
+		return fmt.Errorf("invalid max read bandwidth: %w", err)
+	}
+
+	cfg.IONiceIdle, cfg.IONiceLevel, err = ParseIONice(cfg.IONice)
+	if err != nil {
+		return err
+	}
+
+	if cfg.ReadRetries < 0 {
+		return errors.New("read retries must not be negative")
+	}
+	if cfg.ReadRetryDelay < 0 {
+		return errors.New("read retry delay must not be negative")
+	}
+
+	if cfg.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+	if cfg.Timeout > 0 && (cfg.EconAddress != "" || cfg.WorkerListen != "") {
+		return errors.New("timeout requires a scan of files, it is not supported with econ address or worker listen")
+	}
+
+	if cfg.CheckpointFile != "" {
+		if cfg.CheckpointInterval <= 0 {
+			return errors.New("checkpoint interval must be greater than 0")
+		}
+		if cfg.EconAddress != "" || cfg.WorkerListen != "" || cfg.Workers != "" || cfg.Schedule != "" || cfg.Replay != "" {
+			return errors.New("checkpoint file requires a single scan of local files, it is not supported with econ address, worker listen, workers, schedule or replay")
+		}
+	}
+	if cfg.Resume && cfg.CheckpointFile == "" {
+		return errors.New("resume requires a checkpoint file")
+	}
+
+	cfg.OpenWithArgs = nil
+	if cfg.OpenWith != "" {
+		if !strings.Contains(cfg.OpenWith, "{file}") {
+			return errors.New("open with command must contain {file}")
+		}
+		if cfg.EconAddress != "" || cfg.WorkerListen != "" || cfg.Schedule != "" {
+			return errors.New("open with requires a single scan of files, it is not supported with econ address, worker listen or schedule")
+		}
+		cfg.OpenWithArgs, err = ParseCommandTemplate(cfg.OpenWith)
+		if err != nil {
+			return fmt.Errorf("invalid open with command: %w", err)
+		}
+	}
+
+	if cfg.Where != "" {
+		cfg.WhereProgram, err = CompileWhere(cfg.Where)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.Online && cfg.MasterURL == "" {
+		return errors.New("online lookups require a master url")
+	}
+
+	if cfg.NotifyTelegram != "" {
+		// the token itself contains a colon
+		idx := strings.LastIndex(cfg.NotifyTelegram, ":")
+		if idx <= 0 || idx == len(cfg.NotifyTelegram)-1 {
+			return errors.New("notify telegram must be of the form <token>:<chat id>")
+		}
+		cfg.TelegramToken, cfg.TelegramChatID = cfg.NotifyTelegram[:idx], cfg.NotifyTelegram[idx+1:]
+	}
+
+	if cfg.NotifyInterval <= 0 {
+		return errors.New("notify interval must be greater than 0")
+	}
+
+	cfg.WebhookHTTPHeaders, err = parseHeaders(cfg.WebhookHeaders)
+	if err != nil {
+		return fmt.Errorf("invalid webhook headers: %w", err)
+	}
+
+	if cfg.WebhookTemplate != "" {
+		cfg.WebhookBodyTemplate, err = template.New("webhook").Funcs(template.FuncMap{
+			"json": func(v any) (string, error) {
+				data, err := json.Marshal(v)
+				return string(data), err
+			},
+		}).Parse(cfg.WebhookTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid webhook template: %w", err)
+		}
+	}
+
+	if cfg.WebhookRetries < 0 {
+		return errors.New("webhook retries must not be negative")
+	}
+
+	if cfg.ElasticsearchURL != "" && cfg.ElasticsearchIndex == "" {
+		return errors.New("elasticsearch index is required")
+	}
+
+	if cfg.PostgresDSN != "" && cfg.PostgresTable == "" {
+		return errors.New("postgres table is required")
+	}
+
+	if cfg.ClickhouseURL != "" && !clickhouseTableRegex.MatchString(cfg.ClickhouseTable) {
+		return fmt.Errorf("invalid clickhouse table %q: must be of the form table or database.table", cfg.ClickhouseTable)
+	}
+
+	if cfg.NATSURL != "" && cfg.NATSSubject == "" {
+		return errors.New("nats subject is required")
+	}
+
 	if cfg.Concurrency < 1 {
 		return errors.New("concurrency must be greater than 0")
 	}
 
+	if cfg.MaxDepth < 0 {
+		return errors.New("max depth must not be negative")
+	}
+
+	lInvalidUTF8 := strings.ToLower(cfg.InvalidUTF8)
+	if !isOneOf(lInvalidUTF8, InvalidUTF8Policies...) {
+		return fmt.Errorf("invalid invalid utf8 policy %q: must be one of %v", cfg.InvalidUTF8, InvalidUTF8Policies)
+	}
+	cfg.InvalidUTF8 = lInvalidUTF8
+
+	lIPFormat := strings.ToLower(cfg.IPFormat)
+	if !isOneOf(lIPFormat, IPFormats...) {
+		return fmt.Errorf("invalid ip format %q: must be one of %v", cfg.IPFormat, IPFormats)
+	}
+	cfg.IPFormat = lIPFormat
+	if cfg.IPFormat != IPFormatStripPort && cfg.RedactIPs != "" {
+		return errors.New("ip format and redact ips are mutually exclusive")
+	}
+
+	lOverlap := strings.ToLower(cfg.RotationOverlap)
+	if !isOneOf(lOverlap, OverlapModes...) {
+		return fmt.Errorf("invalid rotation overlap %q: must be one of %v", cfg.RotationOverlap, OverlapModes)
+	}
+	cfg.RotationOverlap = lOverlap
+
+	lOrder := strings.ToLower(cfg.Order)
+	if !isOneOf(lOrder, Orders...) {
+		return fmt.Errorf("invalid order %q: must be one of %v", cfg.Order, Orders)
+	}
+	cfg.Order = lOrder
+
+	if cfg.TempDir != "" {
+		fi, err := os.Stat(cfg.TempDir)
+		if err != nil {
+			return fmt.Errorf("invalid temp dir: %w", err)
+		}
+		if !fi.IsDir() {
+			return errors.New("temp dir is not a directory")
+		}
+	}
+
+	cfg.MaxTempBytes, err = ParseByteSize(cfg.MaxTempSize)
+	if err != nil {
+		return fmt.Errorf("invalid max temp size: %w", err)
+	}
+
+	cfg.WorkerURLs = splitList(cfg.Workers)
+	for _, u := range cfg.WorkerURLs {
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			return fmt.Errorf("invalid worker url %q: must be an http(s) URL", u)
+		}
+	}
+	if len(cfg.WorkerURLs) > 0 && cfg.WorkerToken == "" {
+		return errors.New("workers require a worker token")
+	}
+	if len(cfg.WorkerURLs) > 0 && cfg.Schedule != "" {
+		// scheduled runs only report the new lines of local files
+		return errors.New("workers and schedule are mutually exclusive")
+	}
+
+	if cfg.EconAddress != "" {
+		if cfg.Schedule != "" || cfg.DryRun || len(cfg.WorkerURLs) > 0 {
+			return errors.New("econ address, schedule, dry run and workers are mutually exclusive")
+		}
+		if cfg.EconPassword == "" {
+			return errors.New("econ password is required")
+		}
+	}
+
+	if cfg.EconResponse != "" {
+		if cfg.EconAddress == "" {
+			return errors.New("econ response requires an econ address")
+		}
+		cfg.EconResponseTemplate, err = template.New("response").Option("missingkey=zero").Funcs(template.FuncMap{
+			"quote": EconQuote,
+		}).Parse(cfg.EconResponse)
+		if err != nil {
+			return fmt.Errorf("invalid econ response template: %w", err)
+		}
+	}
+
+	if cfg.Schedule != "" {
+		cfg.ScheduleSpec, err = cron.ParseStandard(cfg.Schedule)
+		if err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
+		}
+	}
+
+	if cfg.QueriesFile != "" {
+		cfg.queries, err = cfg.loadQueries(cfg.QueriesFile)
+		if err != nil {
+			return err
+		}
+	} else if cfg.Preset != "" {
+		q, err := cfg.loadPreset(cfg.ConfigFile, cfg.Preset)
+		if err != nil {
+			return err
+		}
+		cfg.queries = []*Query{q}
+	} else if cfg.PatternsFile != "" {
+		q := &Query{PatternsFile: cfg.PatternsFile}
+		err = cfg.prepareQuery(q)
+		if err != nil {
+			return err
+		}
+		cfg.queries = []*Query{q}
+	} else if cfg.PhraseRegex != "" {
+		cfg.queries = []*Query{{
+			PhraseRegex:   cfg.PhraseRegex,
+			PhraseRegexp:  cfg.PhraseRegexp,
+			Format:        cfg.Output,
+			Deduplicate:   cfg.Deduplicate,
+			Extended:      cfg.Extended || cfg.WithPosition || cfg.RelativeTime || cfg.Hashes,
+			IPsOnly:       cfg.IPsOnly,
+			AggregateCIDR: cfg.AggregateCIDR,
+			WithPosition:  cfg.WithPosition,
+			RelativeTime:  cfg.RelativeTime,
+			Hashes:        cfg.Hashes,
+			MinSeverity:   cfg.MinSeverity,
+			Scores:        cfg.Scores,
+			CountOnly:     cfg.CountOnly,
+			SortBy:        cfg.SortBy,
+			GroupBy:       cfg.GroupBy,
+			MaxPerPlayer:  cfg.MaxPerPlayer,
+			Alert:         cfg.Alert,
+			EventTypes:    cfg.EventTypeList,
+		}}
+		if cfg.Alert != "" {
+			cfg.queries[0].AlertRule, err = ParseAlertRule(cfg.Alert)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, q := range cfg.queries {
+		if q.AlertRule == nil {
+			continue
+		}
+		if cfg.EconAddress == "" && cfg.Schedule == "" {
+			return errors.New("alert rules require an econ address or a schedule")
+		}
+		if cfg.NotifyDiscord == "" && cfg.NotifyTelegram == "" && cfg.WebhookURL == "" && q.Webhook == "" && q.Discord == "" {
+			return errors.New("alert rules require discord, telegram or webhook notifications")
+		}
+	}
+
+	if cfg.ReplayManifest != nil {
+		return cfg.validateReplay()
+	}
 	return nil
 }
 
+// clickhouseTableRegex restricts table names, as they are part of the sql statements.
+var clickhouseTableRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+
+// parseHeaders parses semicolon separated "Key: Value" pairs.
+func parseHeaders(s string) (http.Header, error) {
+	headers := make(http.Header)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, found := strings.Cut(entry, ":")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("header %q must be of the form 'Key: Value'", entry)
+		}
+		headers.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+// splitList splits a comma separated list and removes empty entries.
+func splitList(s string) []string {
+	list := make([]string, 0, 1)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// parseEventTypes validates the event types, chat messages are the default.
+func parseEventTypes(types []string) ([]string, error) {
+	if len(types) == 0 {
+		return []string{EventChat}, nil
+	}
+	parsed := make([]string, 0, len(types))
+	for _, t := range types {
+		lType := strings.ToLower(t)
+		if !isOneOf(lType, EventTypes...) {
+			return nil, fmt.Errorf("invalid event type %q: must be one of %v", t, EventTypes)
+		}
+		parsed = append(parsed, lType)
+	}
+	return parsed, nil
+}
+
+// isLoopbackAddress returns true in case the host of the listen address only accepts local connections.
+// An empty host listens on all interfaces.
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
+
 func isOneOf(s string, values ...string) bool {
 	for _, v := range values {
 		if s == v {
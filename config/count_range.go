@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseCountRange parses a count like 3, a range like 2-5 or an open range like 20- or -5.
+// The maximum of open ranges without upper bound is zero.
+func ParseCountRange(s string) (minCount, maxCount int, err error) {
+	s = strings.TrimSpace(s)
+	lower, upper, isRange := strings.Cut(s, "-")
+	if !isRange {
+		upper = lower
+	}
+
+	parse := func(v string) (int, error) {
+		if v == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%q is not a non negative integer", v)
+		}
+		return n, nil
+	}
+
+	minCount, err = parse(strings.TrimSpace(lower))
+	if err != nil {
+		return 0, 0, err
+	}
+	maxCount, err = parse(strings.TrimSpace(upper))
+	if err != nil {
+		return 0, 0, err
+	}
+	if lower == "" && upper == "" {
+		return 0, 0, errors.New("count or range is required")
+	}
+	if upper != "" && maxCount == 0 {
+		// zero is used for ranges without upper bound
+		return 0, 0, errors.New("upper bound must be greater than zero")
+	}
+	if maxCount > 0 && minCount > maxCount {
+		return 0, 0, fmt.Errorf("lower bound %d is greater than upper bound %d", minCount, maxCount)
+	}
+	return minCount, maxCount, nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func NewDiffConfig() DiffConfig {
+	return DiffConfig{
+		Output: FormatJSON,
+	}
+}
+
+// DiffConfig is the configuration of the diff subcommand.
+type DiffConfig struct {
+	Old    string `koanf:"old" description:"json results of the previous scan"`
+	New    string `koanf:"new" description:"json results of the current scan, - for stdin"`
+	Output string `koanf:"output" short:"o" description:"output format, one of 'json' or 'text'"`
+}
+
+func (cfg *DiffConfig) Validate() error {
+	if cfg.Old == "" || cfg.New == "" {
+		return errors.New("old and new results are required")
+	}
+
+	if cfg.Old == "-" {
+		return errors.New("old results must be a file, only the new results can be read from stdin")
+	}
+
+	allowed := []string{FormatJSON, FormatText}
+	lOutput := strings.ToLower(cfg.Output)
+	if !isOneOf(lOutput, allowed...) {
+		return fmt.Errorf("invalid output format %q: must be one of %v", cfg.Output, allowed)
+	}
+	cfg.Output = lOutput
+	return nil
+}
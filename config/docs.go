@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	DocsFormatMan      = "man"
+	DocsFormatMarkdown = "markdown"
+)
+
+var DocsFormats = []string{DocsFormatMan, DocsFormatMarkdown}
+
+func NewDocsConfig() DocsConfig {
+	return DocsConfig{
+		Dir:    "docs",
+		Format: DocsFormatMan + "," + DocsFormatMarkdown,
+		Name:   appName,
+	}
+}
+
+// DocsConfig is the configuration of the docs subcommand.
+type DocsConfig struct {
+	Dir        string   `koanf:"dir" short:"d" description:"directory that the man pages and the flag reference are written to, created in case it does not exist"`
+	Format     string   `koanf:"format" short:"f" description:"comma separated formats, any of 'man' for section 1 man pages or 'markdown' for a single flag reference"`
+	FormatList []string `koanf:"-"`
+	Name       string   `koanf:"name" description:"program name in the documentation, which may differ from the name of the binary, e.g. in distribution packages"`
+}
+
+func (cfg *DocsConfig) Validate() error {
+	if cfg.Dir == "" {
+		return errors.New("dir is required")
+	}
+	if cfg.Name == "" {
+		return errors.New("name is required")
+	}
+
+	cfg.FormatList = splitList(cfg.Format)
+	if len(cfg.FormatList) == 0 {
+		return errors.New("format is required")
+	}
+	for _, format := range cfg.FormatList {
+		if !isOneOf(format, DocsFormats...) {
+			return fmt.Errorf("invalid format %q: must be one of %v", format, DocsFormats)
+		}
+	}
+	return nil
+}
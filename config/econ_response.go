@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// EconQuote returns the value as a single quoted argument of a console command, so that
+// player controlled texts like nicknames and messages cannot inject further commands.
+func EconQuote(v string) string {
+	v = strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\r", " ",
+		"\n", " ",
+	).Replace(v)
+	return `"` + v + `"`
+}
+
+// CheckEconCommand rejects rendered console commands that would execute more than one command,
+// which happens with line breaks or with semicolons outside of quoted arguments.
+func CheckEconCommand(command string) error {
+	if strings.ContainsAny(command, "\r\n") {
+		return errors.New("econ command must not contain line breaks")
+	}
+
+	quoted := false
+	for i := 0; i < len(command); i++ {
+		switch command[i] {
+		case '\\':
+			if quoted {
+				// skip the escaped character
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return errors.New("econ command must not contain semicolons outside of quotes, use the quote function for player controlled fields")
+			}
+		}
+	}
+	if quoted {
+		return errors.New("econ command contains an unterminated quote")
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestEconQuote(t *testing.T) {
+	tpl := template.Must(template.New("response").Funcs(template.FuncMap{
+		"quote": EconQuote,
+	}).Parse(`say {{quote .}} was kicked`))
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "nameless tee", want: `say "nameless tee" was kicked`},
+		{text: `a"; shutdown; "`, want: `say "a\"; shutdown; \"" was kicked`},
+		{text: `a\"; shutdown`, want: `say "a\\\"; shutdown" was kicked`},
+		{text: "a\nshutdown", want: `say "a shutdown" was kicked`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var sb strings.Builder
+			err := tpl.Execute(&sb, tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sb.String() != tt.want {
+				t.Fatalf("got %s, want %s", sb.String(), tt.want)
+			}
+			err = CheckEconCommand(sb.String())
+			if err != nil {
+				t.Fatalf("quoted command was rejected: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckEconCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		wantErr bool
+	}{
+		{command: "kick 3"},
+		{command: `say "a;b"`},
+		{command: `say "a\";b"`},
+		{command: "kick 3; shutdown", wantErr: true},
+		{command: `kick 3 "x"; shutdown`, wantErr: true},
+		{command: `say hi"; shutdown`, wantErr: true},
+		{command: "kick 3\nshutdown", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			err := CheckEconCommand(tt.command)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckEconCommand() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
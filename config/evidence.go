@@ -0,0 +1,34 @@
+package config
+
+import (
+	"errors"
+)
+
+func NewEvidenceConfig() EvidenceConfig {
+	return EvidenceConfig{
+		Input:   "-",
+		Context: 5,
+	}
+}
+
+// EvidenceConfig is the configuration of the export-evidence subcommand.
+type EvidenceConfig struct {
+	Input   string `koanf:"input" short:"i" description:"extended json results with positions (-e -o json --with-position), - for stdin"`
+	Output  string `koanf:"output" short:"o" description:"zip file the evidence package is written to, must not exist yet"`
+	Context int    `koanf:"context" short:"C" description:"number of lines before and after each matched line that are included"`
+}
+
+func (cfg *EvidenceConfig) Validate() error {
+	if cfg.Input == "" {
+		return errors.New("input is required")
+	}
+
+	if cfg.Output == "" {
+		return errors.New("output is required")
+	}
+
+	if cfg.Context < 0 {
+		return errors.New("context must not be negative")
+	}
+	return nil
+}
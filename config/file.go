@@ -0,0 +1,160 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/providers/structs"
+	"github.com/knadh/koanf/v2"
+	"github.com/spf13/pflag"
+)
+
+const (
+	appName = "twlog-who-said"
+
+	// EnvPrefix is the prefix of all environment variables, e.g. TWLOG_PHRASE_REGEX
+	EnvPrefix = "TWLOG_"
+
+	configFileFlag = "config-file"
+	configFileEnv  = EnvPrefix + "CONFIG_FILE"
+)
+
+// DefaultConfigFile returns the path of the yaml config file in the user's config directory,
+// e.g. ~/.config/twlog-who-said/config.yaml
+func DefaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, appName, "config.yaml")
+}
+
+// LoadFile reads the yaml config file on top of the current (default) values.
+// The file is located via the --config-file flag in args, the TWLOG_CONFIG_FILE environment variable
+// or the default location, in that order. A missing file at the default location is not an error.
+//
+// Its values become the defaults that environment variables and flags override, so the file needs to be
+// loaded before the flags are registered, e.g. again after the command line was parsed.
+func (cfg *Config) LoadFile(args []string) error {
+	path := cfg.ConfigFile
+	explicit := false
+
+	flags := pflag.NewFlagSet(appName, pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.SetOutput(io.Discard)
+	flagPath := flags.String(configFileFlag, "", "")
+	_ = flags.Parse(args) // best effort, the actual flag parsing reports errors
+
+	if *flagPath != "" {
+		path, explicit = *flagPath, true
+	} else if envPath := os.Getenv(configFileEnv); envPath != "" {
+		path, explicit = envPath, true
+	}
+	cfg.ConfigFile = path
+
+	if !explicit {
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+	}
+
+	k, err := loadConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = k.UnmarshalWithConf("", cfg, koanf.UnmarshalConf{
+		FlatPaths: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	cfg.ConfigFile = path
+	return nil
+}
+
+// YAML returns the configuration in the format of the config file, secrets that are set are redacted.
+func (cfg *Config) YAML() ([]byte, error) {
+	k := koanf.New(".")
+	err := k.Load(structs.ProviderWithDelim(cfg, "koanf", "."), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range k.All() {
+		if value != "" && IsSecret(key, value) {
+			err = k.Set(key, RedactedValue)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return k.Marshal(yaml.Parser())
+}
+
+// loadConfigFile parses the yaml config file at path.
+func loadConfigFile(path string) (*koanf.Koanf, error) {
+	if path == "" {
+		return nil, errors.New("config file path is empty")
+	}
+
+	k := koanf.New(".")
+	err := k.Load(file.Provider(path), yaml.Parser())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("config file %s does not exist", path)
+		}
+		return nil, fmt.Errorf("failed to load config file: %w", err)
+	}
+	return k, nil
+}
+
+// Presets returns the sorted names of the presets that are defined in the config file.
+func Presets(path string) ([]string, error) {
+	k, err := loadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	names := k.MapKeys("presets")
+	slices.Sort(names)
+	return names, nil
+}
+
+// loadPreset returns the named preset of the config file as query.
+// Presets support the same fields as the entries of a queries file.
+func (cfg *Config) loadPreset(path, name string) (*Query, error) {
+	if strings.Contains(name, ".") {
+		return nil, fmt.Errorf("invalid preset name %q: must not contain dots", name)
+	}
+
+	k, err := loadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := "presets." + name
+	if !k.Exists(key) {
+		return nil, fmt.Errorf("preset %s not found in config file %s", name, path)
+	}
+
+	var q Query
+	err = k.Unmarshal(key, &q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse preset %s: %w", name, err)
+	}
+	q.Name = name
+
+	err = cfg.prepareQuery(&q)
+	if err != nil {
+		return nil, fmt.Errorf("preset %s: %w", name, err)
+	}
+	return &q, nil
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+func NewGDPRConfig() GDPRConfig {
+	return GDPRConfig{
+		SearchDir:    ".",
+		FileRegex:    `.*\.log$`,
+		ArchiveRegex: `\.(7z|bz2|gz|tar|xz|zip|zst|lz)$`,
+		Replacement:  "[redacted]",
+	}
+}
+
+// GDPRConfig is the configuration of the gdpr subcommand.
+type GDPRConfig struct {
+	SearchDir     string         `koanf:"search.dir" short:"d" description:"directory to search for files recursively"`
+	FileRegex     string         `koanf:"file.regex" short:"f" description:"regex to match files in the search dir and in archives"`
+	FileRegexp    *regexp.Regexp `koanf:"-"`
+	ArchiveRegex  string         `koanf:"archive.regex" short:"a" description:"regex to match archive files in the search dir whose files are searched as well, empty to skip archives"`
+	ArchiveRegexp *regexp.Regexp `koanf:"-"`
+	Name          string         `koanf:"name" short:"n" description:"nickname of the player, every occurrence is reported"`
+	IP            string         `koanf:"ip" description:"IP of the player, every occurrence is reported"`
+	Output        string         `koanf:"output" short:"o" description:"directory that redacted copies of the affected files are written to, the original files are not modified"`
+	Replacement   string         `koanf:"replacement" description:"text that replaces the nickname and IP in the redacted copies"`
+}
+
+func (cfg *GDPRConfig) Validate() error {
+	if cfg.Name == "" && cfg.IP == "" {
+		return errors.New("name or ip is required")
+	}
+
+	if cfg.IP != "" {
+		_, err := netip.ParseAddr(cfg.IP)
+		if err != nil {
+			return fmt.Errorf("invalid ip: %w", err)
+		}
+	}
+
+	var err error
+	cfg.FileRegexp, err = compilePathRegex(cfg.FileRegex)
+	if err != nil {
+		return fmt.Errorf("invalid file regex: %w", err)
+	}
+
+	if cfg.ArchiveRegex != "" {
+		cfg.ArchiveRegexp, err = compilePathRegex(cfg.ArchiveRegex)
+		if err != nil {
+			return fmt.Errorf("invalid archive regex: %w", err)
+		}
+	}
+
+	if cfg.Output != "" {
+		searchDir, err := filepath.Abs(cfg.SearchDir)
+		if err != nil {
+			return fmt.Errorf("invalid search dir: %w", err)
+		}
+		output, err := filepath.Abs(cfg.Output)
+		if err != nil {
+			return fmt.Errorf("invalid output dir: %w", err)
+		}
+		if searchDir == output {
+			return errors.New("output dir must not be the search dir, the original files must not be modified")
+		}
+
+		fi, err := os.Stat(output)
+		if err == nil && !fi.IsDir() {
+			return errors.New("output is not a directory")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+func NewImpersonationConfig() ImpersonationConfig {
+	return ImpersonationConfig{
+		SearchDir:   ".",
+		FileRegex:   `.*\.log$`,
+		MinSessions: 3,
+		Output:      FormatText,
+	}
+}
+
+// ImpersonationConfig is the configuration of the impersonation subcommand.
+type ImpersonationConfig struct {
+	SearchDir   string         `koanf:"search.dir" short:"d" description:"directory to search for files recursively"`
+	FileRegex   string         `koanf:"file.regex" short:"f" description:"regex to match files in the search dir"`
+	FileRegexp  *regexp.Regexp `koanf:"-"`
+	Name        string         `koanf:"name" short:"n" description:"only report sessions of this nickname"`
+	MinSessions int            `koanf:"min.sessions" description:"number of earlier sessions of a nickname that are required to flag a session from unknown IPs"`
+	Output      string         `koanf:"output" short:"o" description:"output format, one of 'json' or 'text'"`
+}
+
+func (cfg *ImpersonationConfig) Validate() error {
+	var err error
+	cfg.FileRegexp, err = compilePathRegex(cfg.FileRegex)
+	if err != nil {
+		return fmt.Errorf("invalid file regex: %w", err)
+	}
+
+	if cfg.MinSessions < 1 {
+		return errors.New("min sessions must be at least 1")
+	}
+
+	allowed := []string{FormatJSON, FormatText}
+	lOutput := strings.ToLower(cfg.Output)
+	if !isOneOf(lOutput, allowed...) {
+		return fmt.Errorf("invalid output format %q: must be one of %v", cfg.Output, allowed)
+	}
+	cfg.Output = lOutput
+	return nil
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func NewJoinFloodConfig() JoinFloodConfig {
+	return JoinFloodConfig{
+		SearchDir:    ".",
+		FileRegex:    `.*\.log$`,
+		Window:       10 * time.Second,
+		MinJoins:     5,
+		MinGenerated: 0.5,
+		BanDuration:  60,
+		Output:       FormatText,
+	}
+}
+
+// JoinFloodConfig is the configuration of the joinflood subcommand.
+type JoinFloodConfig struct {
+	SearchDir    string         `koanf:"search.dir" short:"d" description:"directory to search for files recursively"`
+	FileRegex    string         `koanf:"file.regex" short:"f" description:"regex to match files in the search dir"`
+	FileRegexp   *regexp.Regexp `koanf:"-"`
+	Window       time.Duration  `koanf:"window" description:"time window in which the joins of a flood happen"`
+	MinJoins     int            `koanf:"min.joins" description:"minimum number of joins from the same /24 network within the window"`
+	MinGenerated float64        `koanf:"min.generated" description:"minimum ratio of generated looking nicknames among the named joins of a flood, 0 to ignore the nicknames"`
+	BanRanges    bool           `koanf:"ban.ranges" description:"print ban_range commands of the flooding ranges in bans.cfg syntax instead of the floods"`
+	BanDuration  int            `koanf:"ban.duration" description:"ban duration in minutes of the ban_range commands"`
+	Output       string         `koanf:"output" short:"o" description:"output format, one of 'json' or 'text'"`
+}
+
+func (cfg *JoinFloodConfig) Validate() error {
+	var err error
+	cfg.FileRegexp, err = compilePathRegex(cfg.FileRegex)
+	if err != nil {
+		return fmt.Errorf("invalid file regex: %w", err)
+	}
+
+	if cfg.Window <= 0 {
+		return errors.New("window must be greater than 0")
+	}
+
+	if cfg.MinJoins < 2 {
+		return errors.New("min joins must be at least 2")
+	}
+
+	if cfg.MinGenerated < 0 || cfg.MinGenerated > 1 {
+		return errors.New("min generated must be between 0 and 1")
+	}
+
+	if cfg.BanDuration < 0 {
+		return errors.New("ban duration must not be negative")
+	}
+
+	allowed := []string{FormatJSON, FormatText}
+	lOutput := strings.ToLower(cfg.Output)
+	if !isOneOf(lOutput, allowed...) {
+		return fmt.Errorf("invalid output format %q: must be one of %v", cfg.Output, allowed)
+	}
+	cfg.Output = lOutput
+	return nil
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/providers/structs"
+	"github.com/knadh/koanf/v2"
+	"github.com/spf13/pflag"
+)
+
+const (
+	replayFlag = "replay"
+	replayEnv  = EnvPrefix + "REPLAY"
+)
+
+// Manifest records a scan with its configuration and files, so that it can be reproduced with --replay.
+type Manifest struct {
+	Version  string    `json:"version"`
+	Revision string    `json:"revision,omitempty"`
+	Created  time.Time `json:"created"`
+	// Args are the command line arguments with the values of secret flags redacted.
+	Args []string `json:"args"`
+	// Config is the effective configuration without secrets, e.g. passwords and tokens.
+	Config  map[string]any  `json:"config"`
+	Queries []ManifestQuery `json:"queries"`
+	Files   []ManifestFile  `json:"files"`
+	Matches int             `json:"matches"`
+}
+
+// ManifestQuery is a query with its phrase regex after resolving patterns files and presets.
+type ManifestQuery struct {
+	Name       string   `json:"name,omitempty"`
+	Phrase     string   `json:"phrase"`
+	File       string   `json:"file,omitempty"`
+	EventTypes []string `json:"event_types,omitempty"`
+}
+
+// ManifestFile is a scanned log file or archive.
+type ManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// manifestExcludes are the configuration keys that are not recorded, as they
+// only control how the manifest is written and read. Secrets are never recorded.
+var manifestExcludes = []string{
+	"config.file", "save.manifest", "replay", "dry.run",
+	"checkpoint.file", "checkpoint.interval", "resume",
+}
+
+// ManifestConfig returns the configuration values that are recorded in a manifest.
+func (cfg *Config) ManifestConfig() (map[string]any, error) {
+	k := koanf.New(".")
+	err := k.Load(structs.ProviderWithDelim(cfg, "koanf", "."), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	values := k.All()
+	for key, value := range values {
+		if IsSecret(key, value) || slices.Contains(manifestExcludes, key) {
+			delete(values, key)
+		}
+	}
+	return values, nil
+}
+
+// ManifestQueries returns the queries in the format of a manifest.
+func (cfg *Config) ManifestQueries() []ManifestQuery {
+	queries := make([]ManifestQuery, 0, len(cfg.queries))
+	for _, q := range cfg.queries {
+		queries = append(queries, ManifestQuery{
+			Name:       q.Name,
+			Phrase:     q.PhraseRegex,
+			File:       q.FileRegex,
+			EventTypes: q.EventTypes,
+		})
+	}
+	return queries
+}
+
+// ReadManifest reads a manifest file of --save-manifest.
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// LoadReplay reads the configuration of the manifest of the --replay flag in args or the TWLOG_REPLAY
+// environment variable on top of the current values.
+// Like the config file, it needs to be loaded before the flags are registered, so that flags and
+// environment variables override the recorded values, e.g. the output format or secrets.
+// The args are usually the flags that were parsed by cobra.
+func (cfg *Config) LoadReplay(args []string) error {
+	flags := pflag.NewFlagSet(appName, pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.SetOutput(io.Discard)
+	flagPath := flags.String(replayFlag, "", "")
+	_ = flags.Parse(args) // best effort, the actual flag parsing reports errors
+
+	path := *flagPath
+	if path == "" {
+		path = os.Getenv(replayEnv)
+	}
+	if path == "" {
+		return nil
+	}
+
+	m, err := ReadManifest(path)
+	if err != nil {
+		return err
+	}
+
+	k := koanf.New(".")
+	err = k.Load(confmap.Provider(m.Config, "."), nil)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration of manifest %s: %w", path, err)
+	}
+	err = k.UnmarshalWithConf("", cfg, koanf.UnmarshalConf{
+		FlatPaths: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to parse configuration of manifest %s: %w", path, err)
+	}
+	cfg.Replay = path
+	cfg.ReplayManifest = m
+	return nil
+}
+
+// validateReplay returns an error in case the queries differ from the queries of the replayed manifest,
+// e.g. because the queries file or the patterns file changed in the meantime.
+func (cfg *Config) validateReplay() error {
+	recorded := cfg.ReplayManifest.Queries
+	current := cfg.ManifestQueries()
+	if len(recorded) != len(current) {
+		return fmt.Errorf("replay: manifest contains %d queries, the configuration %d", len(recorded), len(current))
+	}
+	for idx := range recorded {
+		a, b := recorded[idx], current[idx]
+		if a.Name != b.Name || a.Phrase != b.Phrase || a.File != b.File || !slices.Equal(a.EventTypes, b.EventTypes) {
+			return fmt.Errorf("replay: query %q differs from the query %q of the manifest", b.Name, a.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func NewMergeConfig() MergeConfig {
+	return MergeConfig{
+		Output: FormatJSON,
+	}
+}
+
+// MergeConfig is the configuration of the merge subcommand.
+type MergeConfig struct {
+	Input       string   `koanf:"input" short:"i" description:"comma separated json or ndjson result files, - for stdin"`
+	Inputs      []string `koanf:"-"`
+	Deduplicate bool     `koanf:"deduplicate" short:"D" description:"deduplicate objects based on all fields"`
+	Output      string   `koanf:"output" short:"o" description:"output format, one of 'json' or 'text'"`
+}
+
+func (cfg *MergeConfig) Validate() error {
+	cfg.Inputs = splitList(cfg.Input)
+	if len(cfg.Inputs) == 0 {
+		return errors.New("input is required")
+	}
+
+	allowed := []string{FormatJSON, FormatText}
+	lOutput := strings.ToLower(cfg.Output)
+	if !isOneOf(lOutput, allowed...) {
+		return fmt.Errorf("invalid output format %q: must be one of %v", cfg.Output, allowed)
+	}
+	cfg.Output = lOutput
+	return nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// ParseCommandTemplate splits a command line like code --goto {file}:{line} into its arguments.
+// Arguments may be quoted with single or double quotes, no shell is involved.
+func ParseCommandTemplate(s string) ([]string, error) {
+	var (
+		args    []string
+		sb      strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			sb.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				sb.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, sb.String())
+				sb.Reset()
+				inArg = false
+			}
+		default:
+			sb.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, sb.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("command is empty")
+	}
+	return args, nil
+}
@@ -0,0 +1,16 @@
+package config
+
+import (
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// compilePathRegex compiles a regex that is matched against file paths.
+// File names on windows are case insensitive, e.g. server.LOG is a log file as well.
+func compilePathRegex(expr string) (*regexp.Regexp, error) {
+	if runtime.GOOS == "windows" && !strings.HasPrefix(expr, "(?i)") {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
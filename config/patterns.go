@@ -0,0 +1,94 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Pattern is a regex of a word list together with the category and severity of its matches.
+type Pattern struct {
+	Category string
+	Severity int
+	Regexp   *regexp.Regexp
+}
+
+// LoadPatterns reads a pattern file with one category:severity:regex per line, e.g. spam:1:(?i)free skins.
+// Empty lines and lines starting with # are ignored.
+func LoadPatterns(path string) ([]Pattern, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open patterns file: %w", err)
+	}
+	defer f.Close()
+
+	patterns := make([]Pattern, 0, 16)
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// the regex itself may contain colons
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("%s:%d: expected category:severity:regex", path, lineNumber)
+		}
+
+		severity, err := strconv.Atoi(parts[1])
+		if err != nil || severity < 0 {
+			return nil, fmt.Errorf("%s:%d: invalid severity %q: must be a non negative integer", path, lineNumber, parts[1])
+		}
+
+		re, err := regexp.Compile(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid regex: %w", path, lineNumber, err)
+		}
+
+		patterns = append(patterns, Pattern{
+			Category: parts[0],
+			Severity: severity,
+			Regexp:   re,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read patterns file: %w", err)
+	}
+
+	if len(patterns) == 0 {
+		return nil, errors.New("patterns file does not contain any patterns")
+	}
+	return patterns, nil
+}
+
+// patternsRegex combines all patterns into a single regex that matches whenever any pattern matches.
+func patternsRegex(patterns []Pattern) string {
+	alternatives := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		alternatives = append(alternatives, "(?:"+p.Regexp.String()+")")
+	}
+	return strings.Join(alternatives, "|")
+}
+
+// Classify returns the category and severity of a chat message that matched the phrase regex of the query.
+// In case of patterns, the matching pattern with the highest severity wins.
+func (q *Query) Classify(chat string) (category string, severity int) {
+	if len(q.Patterns) == 0 {
+		return q.Category, q.Severity
+	}
+
+	found := false
+	for _, p := range q.Patterns {
+		if (!found || p.Severity > severity) && p.Regexp.MatchString(chat) {
+			category, severity, found = p.Category, p.Severity, true
+		}
+	}
+	return category, severity
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func NewPopulationConfig() PopulationConfig {
+	return PopulationConfig{
+		SearchDir:      ".",
+		FileRegex:      `.*\.log$`,
+		Interval:       time.Hour,
+		BucketTimezone: "UTC",
+		Output:         FormatCSV,
+	}
+}
+
+// PopulationConfig is the configuration of the population subcommand.
+type PopulationConfig struct {
+	SearchDir      string         `koanf:"search.dir" short:"d" description:"directory to search for files recursively"`
+	FileRegex      string         `koanf:"file.regex" short:"f" description:"regex to match files in the search dir"`
+	FileRegexp     *regexp.Regexp `koanf:"-"`
+	ServerIDRegex  string         `koanf:"server.id.regex" description:"regex applied to the file path that extracts the server, the first capture group or the whole match, files without server are counted together"`
+	ServerIDRegexp *regexp.Regexp `koanf:"-"`
+	Interval       time.Duration  `koanf:"interval" description:"length of the time buckets of the time series"`
+	BucketTimezone string         `koanf:"bucket.timezone" description:"IANA time zone whose midnight the time buckets are aligned to and in which their boundaries are printed, e.g. Europe/Berlin or Local, days of multiples of 24h intervals follow daylight saving time"`
+	BucketLocation *time.Location `koanf:"-"`
+	Output         string         `koanf:"output" short:"o" description:"output format, one of 'csv' or 'json'"`
+}
+
+func (cfg *PopulationConfig) Validate() error {
+	var err error
+	cfg.FileRegexp, err = compilePathRegex(cfg.FileRegex)
+	if err != nil {
+		return fmt.Errorf("invalid file regex: %w", err)
+	}
+
+	cfg.ServerIDRegexp = nil
+	if cfg.ServerIDRegex != "" {
+		cfg.ServerIDRegexp, err = compilePathRegex(cfg.ServerIDRegex)
+		if err != nil {
+			return fmt.Errorf("invalid server id regex: %w", err)
+		}
+	}
+
+	if cfg.Interval <= 0 {
+		return errors.New("interval must be greater than 0")
+	}
+
+	cfg.BucketLocation, err = time.LoadLocation(cfg.BucketTimezone)
+	if err != nil {
+		return fmt.Errorf("invalid bucket timezone: %w", err)
+	}
+
+	allowed := []string{FormatCSV, FormatJSON}
+	lOutput := strings.ToLower(cfg.Output)
+	if !isOneOf(lOutput, allowed...) {
+		return fmt.Errorf("invalid output format %q: must be one of %v", cfg.Output, allowed)
+	}
+	cfg.Output = lOutput
+	return nil
+}
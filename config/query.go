@@ -0,0 +1,308 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+)
+
+// Query is a named search that is evaluated together with other queries
+// in a single pass over all log files.
+type Query struct {
+	Name         string         `koanf:"name"`
+	PhraseRegex  string         `koanf:"phrase"`
+	PhraseRegexp *regexp.Regexp `koanf:"-"`
+	// PatternsFile replaces the phrase regex with the patterns of a word list file.
+	PatternsFile string    `koanf:"patterns"`
+	Patterns     []Pattern `koanf:"-"`
+	// Category and Severity classify the matches of a query without patterns.
+	Category string `koanf:"category"`
+	Severity int    `koanf:"severity"`
+	// MinSeverity drops matches with a lower severity.
+	MinSeverity int `koanf:"min_severity"`
+	// Scores prints the aggregated severities per player instead of the matches.
+	Scores bool `koanf:"scores"`
+	// CountOnly prints the number of matches per file instead of the matches.
+	CountOnly bool `koanf:"count_only"`
+	// SortBy and GroupBy reorganize the matches, the scan order is kept by default.
+	SortBy  string `koanf:"sort_by"`
+	GroupBy string `koanf:"group_by"`
+	// MaxPerPlayer caps the printed matches per nickname, 0 for unlimited.
+	MaxPerPlayer int `koanf:"max_per_player"`
+	// EventTypes are the types of log lines the phrase regex is applied to, chat messages by default.
+	EventTypes []string `koanf:"event_types"`
+	// FileRegex narrows down the files of the search dir that the query is applied to.
+	FileRegex   string         `koanf:"file"`
+	FileRegexp  *regexp.Regexp `koanf:"-"`
+	OutputFile  string         `koanf:"output"`
+	Format      string         `koanf:"format"`
+	Deduplicate bool           `koanf:"deduplicate"`
+	Extended    bool           `koanf:"extended"`
+	IPsOnly     bool           `koanf:"ips_only"`
+	// AggregateCIDR collapses the IPs of networks with at least this many addresses into CIDR blocks, requires ips only.
+	AggregateCIDR int `koanf:"aggregate_cidr"`
+	// WithPosition adds the line number and byte offset of each match and implies the extended output.
+	WithPosition bool `koanf:"with_position"`
+	// RelativeTime shows the timestamps relative to now in the text output and implies the extended output.
+	RelativeTime bool `koanf:"relative_time"`
+	// Hashes adds the sha256 of the matched line and of its source file and implies the extended output.
+	Hashes bool `koanf:"hashes"`
+	// Mute excludes the query from notifications, e.g. discord and telegram.
+	Mute bool `koanf:"mute"`
+	// Threshold is the minimum number of matches of a notification batch that trigger a notification.
+	Threshold int `koanf:"threshold"`
+	// Alert notifies only once the matches within a sliding window exceed a count, e.g. 'count > 10 in 5m'.
+	Alert     string     `koanf:"alert"`
+	AlertRule *AlertRule `koanf:"-"`
+	// Webhook and Discord are URLs that only the matches of this query are posted to,
+	// in addition to the sinks of the flags.
+	Webhook string `koanf:"webhook"`
+	Discord string `koanf:"discord"`
+}
+
+func (q *Query) Validate() error {
+	if q.PatternsFile != "" {
+		if q.PhraseRegex != "" {
+			return errors.New("phrase regex and patterns file are mutually exclusive")
+		}
+		patterns, err := LoadPatterns(q.PatternsFile)
+		if err != nil {
+			return err
+		}
+		q.Patterns = patterns
+		q.PhraseRegex = patternsRegex(patterns)
+	}
+
+	if q.PhraseRegex == "" {
+		return errors.New("phrase regex is required")
+	}
+
+	eventTypes, err := parseEventTypes(q.EventTypes)
+	if err != nil {
+		return err
+	}
+	q.EventTypes = eventTypes
+
+	re, err := regexp.Compile(q.PhraseRegex)
+	if err != nil {
+		return fmt.Errorf("invalid phrase regex: %w", err)
+	}
+	q.PhraseRegexp = re
+
+	if q.FileRegex != "" {
+		re, err = compilePathRegex(q.FileRegex)
+		if err != nil {
+			return fmt.Errorf("invalid file regex: %w", err)
+		}
+		q.FileRegexp = re
+	}
+
+	allowed := []string{FormatJSON, FormatText, FormatNDJSON}
+	if q.CountOnly || q.IPsOnly {
+		allowed = append(allowed, FormatCSV)
+	}
+	lFormat := strings.ToLower(q.Format)
+	if !isOneOf(lFormat, allowed...) {
+		return fmt.Errorf("invalid output format %q: must be one of %v", q.Format, allowed)
+	}
+	q.Format = lFormat
+
+	if q.WithPosition && q.IPsOnly {
+		return errors.New("with position and ips only are mutually exclusive")
+	}
+	if q.RelativeTime && q.IPsOnly {
+		return errors.New("relative time and ips only are mutually exclusive")
+	}
+	if q.Hashes && q.IPsOnly {
+		return errors.New("hashes and ips only are mutually exclusive")
+	}
+	q.Extended = q.Extended || q.WithPosition || q.RelativeTime || q.Hashes
+
+	if q.Threshold < 0 {
+		return errors.New("threshold must not be negative")
+	}
+
+	if q.Alert != "" {
+		q.AlertRule, err = ParseAlertRule(q.Alert)
+		if err != nil {
+			return err
+		}
+	}
+
+	if q.Severity < 0 || q.MinSeverity < 0 {
+		return errors.New("severity and min severity must not be negative")
+	}
+
+	if q.Scores && q.IPsOnly {
+		return errors.New("scores and ips only are mutually exclusive")
+	}
+
+	if q.CountOnly && (q.Scores || q.IPsOnly) {
+		return errors.New("count only, scores and ips only are mutually exclusive")
+	}
+
+	q.SortBy, q.GroupBy, err = validateSortAndGroup(q.SortBy, q.GroupBy)
+	if err != nil {
+		return err
+	}
+	if q.GroupBy != "" && (q.Scores || q.CountOnly) {
+		return errors.New("group by, scores and count only are mutually exclusive")
+	}
+	if q.GroupBy != "" && q.Format == FormatCSV {
+		return errors.New("group by does not support the csv output format")
+	}
+
+	if q.MaxPerPlayer < 0 {
+		return errors.New("max per player must not be negative")
+	}
+	if q.MaxPerPlayer > 0 && (q.Scores || q.CountOnly || q.IPsOnly) {
+		return errors.New("max per player, scores, count only and ips only are mutually exclusive")
+	}
+
+	if q.Extended && q.IPsOnly {
+		return errors.New("extended and ips only are mutually exclusive")
+	}
+
+	if q.AggregateCIDR < 0 {
+		return errors.New("aggregate cidr must not be negative")
+	}
+	if q.AggregateCIDR > 0 && !q.IPsOnly {
+		return errors.New("aggregate cidr requires ips only")
+	}
+
+	for _, sink := range [][2]string{{"webhook", q.Webhook}, {"discord", q.Discord}} {
+		if sink[1] == "" {
+			continue
+		}
+		u, err := url.Parse(sink[1])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid %s url %q: must be an http or https url", sink[0], sink[1])
+		}
+	}
+	return nil
+}
+
+// validateSortAndGroup returns the lower case sort and group keys.
+func validateSortAndGroup(sortBy, groupBy string) (string, string, error) {
+	lSortBy := strings.ToLower(sortBy)
+	if lSortBy != "" && !isOneOf(lSortBy, SortKeys...) {
+		return "", "", fmt.Errorf("invalid sort by %q: must be one of %v", sortBy, SortKeys)
+	}
+	lGroupBy := strings.ToLower(groupBy)
+	if lGroupBy != "" && !isOneOf(lGroupBy, GroupKeys...) {
+		return "", "", fmt.Errorf("invalid group by %q: must be one of %v", groupBy, GroupKeys)
+	}
+	return lSortBy, lGroupBy, nil
+}
+
+// MatchesEvent returns true in case the query should be applied to log lines of the event type.
+func (q *Query) MatchesEvent(eventType string) bool {
+	return slices.Contains(q.EventTypes, eventType)
+}
+
+// MatchesFile returns true in case the query should be applied to the file at path.
+func (q *Query) MatchesFile(path string) bool {
+	return q.FileRegexp == nil || q.FileRegexp.MatchString(path)
+}
+
+// Queries returns the queries that are to be evaluated.
+// Without a queries file, the phrase, output and formatting flags form a single unnamed query
+// which is printed to stdout.
+func (cfg *Config) Queries() []*Query {
+	return cfg.queries
+}
+
+// SetQueries replaces the queries that are to be evaluated, e.g. with the queries of a coordinator.
+func (cfg *Config) SetQueries(queries []*Query) {
+	cfg.queries = queries
+}
+
+// Query returns the query with the given name, nil in case it does not exist.
+func (cfg *Config) Query(name string) *Query {
+	for _, q := range cfg.queries {
+		if q.Name == name {
+			return q
+		}
+	}
+	return nil
+}
+
+// loadQueries reads the named queries from a yaml file.
+// Formatting flags that are set on the command line act as defaults for all queries.
+func (cfg *Config) loadQueries(path string) ([]*Query, error) {
+	k := koanf.New(".")
+	err := k.Load(file.Provider(path), yaml.Parser())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load queries file: %w", err)
+	}
+
+	var queries []*Query
+	err = k.Unmarshal("queries", &queries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse queries file: %w", err)
+	}
+
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("queries file %s does not contain any queries", path)
+	}
+
+	names := make(map[string]struct{}, len(queries))
+	for idx, q := range queries {
+		if q.Name == "" {
+			return nil, fmt.Errorf("query %d: name is required", idx)
+		}
+		if _, found := names[q.Name]; found {
+			return nil, fmt.Errorf("query %s: duplicate name", q.Name)
+		}
+		names[q.Name] = struct{}{}
+
+		err = cfg.prepareQuery(q)
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %w", q.Name, err)
+		}
+	}
+	return queries, nil
+}
+
+// prepareQuery applies the formatting flags as defaults and validates the query.
+func (cfg *Config) prepareQuery(q *Query) error {
+	if q.Format == "" {
+		q.Format = cfg.Output
+	}
+	q.Deduplicate = q.Deduplicate || cfg.Deduplicate
+	q.Extended = q.Extended || cfg.Extended
+	q.IPsOnly = q.IPsOnly || cfg.IPsOnly
+	if q.AggregateCIDR == 0 && q.IPsOnly {
+		q.AggregateCIDR = cfg.AggregateCIDR
+	}
+	q.WithPosition = q.WithPosition || cfg.WithPosition
+	q.RelativeTime = q.RelativeTime || cfg.RelativeTime
+	q.Hashes = q.Hashes || cfg.Hashes
+	q.MinSeverity = max(q.MinSeverity, cfg.MinSeverity)
+	q.Scores = q.Scores || cfg.Scores
+	q.CountOnly = q.CountOnly || cfg.CountOnly
+	if q.SortBy == "" {
+		q.SortBy = cfg.SortBy
+	}
+	if q.GroupBy == "" {
+		q.GroupBy = cfg.GroupBy
+	}
+	if q.MaxPerPlayer == 0 && !q.Scores && !q.CountOnly && !q.IPsOnly {
+		q.MaxPerPlayer = cfg.MaxPerPlayer
+	}
+	if q.Alert == "" {
+		q.Alert = cfg.Alert
+	}
+	if len(q.EventTypes) == 0 {
+		q.EventTypes = cfg.EventTypeList
+	}
+
+	return q.Validate()
+}
@@ -0,0 +1,38 @@
+package config
+
+import "testing"
+
+func TestQueryValidateCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   Query
+		wantErr bool
+	}{
+		{
+			name:  "ips only",
+			query: Query{PhraseRegex: "x", IPsOnly: true, Format: "csv"},
+		},
+		{
+			name:  "count only",
+			query: Query{PhraseRegex: "x", CountOnly: true, Format: "csv"},
+		},
+		{
+			name:    "matches",
+			query:   Query{PhraseRegex: "x", Format: "csv"},
+			wantErr: true,
+		},
+		{
+			name:    "ips only grouped by ip",
+			query:   Query{PhraseRegex: "x", IPsOnly: true, GroupBy: "ip", Format: "csv"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
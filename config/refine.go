@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/expr-lang/expr/vm"
+)
+
+func NewRefineConfig() RefineConfig {
+	return RefineConfig{
+		Input:  "-",
+		Output: FormatJSON,
+	}
+}
+
+// RefineConfig is the configuration of the refine subcommand.
+type RefineConfig struct {
+	Input        string         `koanf:"input" short:"i" description:"json or ndjson result file of an earlier scan, - for stdin"`
+	OutputFile   string         `koanf:"output.file" short:"O" description:"file that the refined results are written to instead of stdout"`
+	Output       string         `koanf:"output" short:"o" description:"output format, one of 'json' or 'text'"`
+	PhraseRegex  string         `koanf:"phrase.regex" short:"p" description:"regex that the text of a match must match"`
+	PhraseRegexp *regexp.Regexp `koanf:"-"`
+	NameRegex    string         `koanf:"name.regex" short:"n" description:"regex that the nickname of a match must match, confusable and invisible characters are normalized before matching"`
+	NameRegexp   *regexp.Regexp `koanf:"-"`
+	IP           string         `koanf:"ip" description:"comma separated IPs or CIDR ranges that the IP of a match must be part of"`
+	IPPrefixes   []netip.Prefix `koanf:"-"`
+	Query        string         `koanf:"query" description:"comma separated names of the queries whose matches are kept"`
+	Queries      []string       `koanf:"-"`
+	Where        string         `koanf:"where" description:"expression that every match must fulfill, e.g. 'ip startsWith \"84.\" && len(text) > 20'"`
+	WhereProgram *vm.Program    `koanf:"-"`
+}
+
+func (cfg *RefineConfig) Validate() error {
+	if cfg.Input == "" {
+		return errors.New("input is required")
+	}
+
+	var err error
+	if cfg.PhraseRegex != "" {
+		cfg.PhraseRegexp, err = regexp.Compile(cfg.PhraseRegex)
+		if err != nil {
+			return fmt.Errorf("invalid phrase regex: %w", err)
+		}
+	}
+
+	if cfg.NameRegex != "" {
+		cfg.NameRegexp, err = regexp.Compile(cfg.NameRegex)
+		if err != nil {
+			return fmt.Errorf("invalid name regex: %w", err)
+		}
+	}
+
+	for _, s := range splitList(cfg.IP) {
+		prefix, err := parsePrefixOrAddr(s)
+		if err != nil {
+			return fmt.Errorf("invalid ip %q: %w", s, err)
+		}
+		cfg.IPPrefixes = append(cfg.IPPrefixes, prefix)
+	}
+
+	cfg.Queries = splitList(cfg.Query)
+
+	if cfg.Where != "" {
+		cfg.WhereProgram, err = CompileWhere(cfg.Where)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.PhraseRegexp == nil && cfg.NameRegexp == nil && len(cfg.IPPrefixes) == 0 && len(cfg.Queries) == 0 && cfg.WhereProgram == nil {
+		return errors.New("at least one of phrase regex, name regex, ip, query or where is required")
+	}
+
+	allowed := []string{FormatJSON, FormatText}
+	lOutput := strings.ToLower(cfg.Output)
+	if !isOneOf(lOutput, allowed...) {
+		return fmt.Errorf("invalid output format %q: must be one of %v", cfg.Output, allowed)
+	}
+	cfg.Output = lOutput
+	return nil
+}
+
+// parsePrefixOrAddr parses a CIDR range or a single IP as range of one address.
+func parsePrefixOrAddr(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return prefix, err
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
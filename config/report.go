@@ -0,0 +1,76 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ReportLanguages are the languages that reports can be rendered in.
+var ReportLanguages = []string{"en", "de", "fr"}
+
+func NewReportConfig() ReportConfig {
+	return ReportConfig{
+		Input:    "-",
+		Output:   FormatMarkdown,
+		Examples: 3,
+	}
+}
+
+// ReportConfig is the configuration of the report subcommand.
+type ReportConfig struct {
+	Input    string `koanf:"input" short:"i" description:"extended json results (-e -o json), - for stdin"`
+	Name     string `koanf:"name" short:"n" description:"nickname of the player the report is about"`
+	Output   string `koanf:"output" short:"o" description:"output format, one of 'markdown' or 'html'"`
+	Examples int    `koanf:"examples" description:"number of representative chat messages per category"`
+	Lang     string `koanf:"lang" description:"language of the report, one of 'en', 'de' or 'fr', defaults to the language of the LC_ALL, LC_MESSAGES or LANG environment variables and english"`
+}
+
+func (cfg *ReportConfig) Validate() error {
+	if cfg.Input == "" {
+		return errors.New("input is required")
+	}
+
+	if cfg.Name == "" {
+		return errors.New("name is required")
+	}
+
+	allowed := []string{FormatMarkdown, FormatHTML}
+	lOutput := strings.ToLower(cfg.Output)
+	if !isOneOf(lOutput, allowed...) {
+		return fmt.Errorf("invalid output format %q: must be one of %v", cfg.Output, allowed)
+	}
+	cfg.Output = lOutput
+
+	if cfg.Examples < 0 {
+		return errors.New("examples must not be negative")
+	}
+
+	if cfg.Lang == "" {
+		cfg.Lang = environmentLanguage()
+	}
+	cfg.Lang = strings.ToLower(cfg.Lang)
+	if !isOneOf(cfg.Lang, ReportLanguages...) {
+		return fmt.Errorf("invalid language %q: must be one of %v", cfg.Lang, ReportLanguages)
+	}
+	return nil
+}
+
+// environmentLanguage returns the supported language of the locale environment variables, e.g. de for de_DE.UTF-8, or english.
+func environmentLanguage() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		lang, _, _ := strings.Cut(strings.ToLower(value), "_")
+		lang, _, _ = strings.Cut(lang, ".")
+		if isOneOf(lang, ReportLanguages...) {
+			return lang
+		}
+		// the first set variable takes precedence, e.g. LC_ALL=C
+		break
+	}
+	return "en"
+}
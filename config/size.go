@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1e6,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1e9,
+	"gb":  1e9,
+	"gib": 1 << 30,
+	"t":   1e12,
+	"tb":  1e12,
+	"tib": 1 << 40,
+}
+
+// ParseByteSize parses human readable sizes like 512, 100MB, 1.5GiB.
+func ParseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	idx := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if idx < 0 {
+		idx = len(s)
+	}
+
+	number, unit := s[:idx], strings.ToLower(strings.TrimSpace(s[idx:]))
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+
+	factor, ok := byteUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, unit)
+	}
+	return int64(value * factor), nil
+}
+
+// ParseBandwidth parses human readable bandwidths like 100MB/s, the /s suffix is optional.
+func ParseBandwidth(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "/s"), "/S")
+	if s == "" {
+		return 0, nil
+	}
+	n, err := ParseByteSize(s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("invalid bandwidth %q: must not be negative", s)
+	}
+	return n, nil
+}
+
+// ParseIONice parses the io priority 'idle' or a best effort level between 0 and 7.
+// An empty priority keeps the priority of the process.
+func ParseIONice(s string) (idle bool, level int, err error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "":
+		return false, 0, nil
+	case "idle":
+		return true, 0, nil
+	}
+	level, err = strconv.Atoi(s)
+	if err != nil || level < 0 || level > 7 {
+		return false, 0, fmt.Errorf("invalid io nice %q: must be 'idle' or a level between 0 and 7", s)
+	}
+	return false, level, nil
+}
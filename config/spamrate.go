@@ -0,0 +1,53 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+func NewSpamRateConfig() SpamRateConfig {
+	return SpamRateConfig{
+		SearchDir:   ".",
+		FileRegex:   `.*\.log$`,
+		Threshold:   10,
+		MinMessages: 10,
+		Output:      FormatText,
+	}
+}
+
+// SpamRateConfig is the configuration of the spamrate subcommand.
+type SpamRateConfig struct {
+	SearchDir   string         `koanf:"search.dir" short:"d" description:"directory to search for files recursively"`
+	FileRegex   string         `koanf:"file.regex" short:"f" description:"regex to match files in the search dir"`
+	FileRegexp  *regexp.Regexp `koanf:"-"`
+	Name        string         `koanf:"name" short:"n" description:"only report the sessions of this nickname"`
+	Threshold   float64        `koanf:"threshold" description:"chat messages per minute above which a nickname and IP is flagged as outlier"`
+	MinMessages int            `koanf:"min.messages" description:"number of chat messages that a nickname and IP requires to be flagged, as short sessions have unreliable rates"`
+	All         bool           `koanf:"all" description:"report all nicknames and IPs with their rates instead of only the outliers"`
+	Output      string         `koanf:"output" short:"o" description:"output format, one of 'json' or 'text'"`
+}
+
+func (cfg *SpamRateConfig) Validate() error {
+	var err error
+	cfg.FileRegexp, err = compilePathRegex(cfg.FileRegex)
+	if err != nil {
+		return fmt.Errorf("invalid file regex: %w", err)
+	}
+
+	if cfg.Threshold <= 0 {
+		return errors.New("threshold must be greater than 0")
+	}
+	if cfg.MinMessages < 1 {
+		return errors.New("min messages must be at least 1")
+	}
+
+	allowed := []string{FormatJSON, FormatText}
+	lOutput := strings.ToLower(cfg.Output)
+	if !isOneOf(lOutput, allowed...) {
+		return fmt.Errorf("invalid output format %q: must be one of %v", cfg.Output, allowed)
+	}
+	cfg.Output = lOutput
+	return nil
+}
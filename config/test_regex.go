@@ -0,0 +1,54 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+func NewTestRegexConfig() TestRegexConfig {
+	defaults := NewConfig()
+	return TestRegexConfig{
+		FileRegex:    defaults.FileRegex,
+		ArchiveRegex: defaults.ArchiveRegex,
+	}
+}
+
+// TestRegexConfig is the configuration of the test-regex subcommand.
+type TestRegexConfig struct {
+	PhraseRegex   string         `koanf:"phrase.regex" short:"p" description:"regex to search for that a player said"`
+	PhraseRegexp  *regexp.Regexp `koanf:"-"`
+	FileRegex     string         `koanf:"file.regex" short:"f" description:"regex to match files in the search dir"`
+	FileRegexp    *regexp.Regexp `koanf:"-"`
+	ArchiveRegex  string         `koanf:"archive.regex" short:"a" description:"regex to match archive files in the search dir"`
+	ArchiveRegexp *regexp.Regexp `koanf:"-"`
+	Sample        string         `koanf:"sample" short:"s" description:"log line that the phrase regex is tested against"`
+	Path          string         `koanf:"path" description:"file path that the file and archive regexes are tested against"`
+}
+
+func (cfg *TestRegexConfig) Validate() error {
+	if cfg.Sample == "" && cfg.Path == "" {
+		return errors.New("sample or path is required")
+	}
+
+	if cfg.Sample != "" && cfg.PhraseRegex == "" {
+		return errors.New("regex is required")
+	}
+
+	var err error
+	cfg.PhraseRegexp, err = regexp.Compile(cfg.PhraseRegex)
+	if err != nil {
+		return fmt.Errorf("invalid regex: %w", err)
+	}
+
+	cfg.FileRegexp, err = compilePathRegex(cfg.FileRegex)
+	if err != nil {
+		return fmt.Errorf("invalid file regex: %w", err)
+	}
+
+	cfg.ArchiveRegexp, err = compilePathRegex(cfg.ArchiveRegex)
+	if err != nil {
+		return fmt.Errorf("invalid archive regex: %w", err)
+	}
+	return nil
+}
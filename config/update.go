@@ -0,0 +1,42 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+func NewUpdateConfig() UpdateConfig {
+	return UpdateConfig{
+		Repository: "jxsl13/twlog-who-said",
+		APIURL:     "https://api.github.com",
+	}
+}
+
+// UpdateConfig is the configuration of the update subcommand.
+type UpdateConfig struct {
+	Repository string `koanf:"repository" description:"github repository of the releases, owner/name"`
+	APIURL     string `koanf:"api.url" description:"URL of the github api, e.g. of a mirror"`
+	Version    string `koanf:"version" description:"release tag to install, e.g. v1.2.0, instead of the newest release"`
+	Check      bool   `koanf:"check" description:"only print whether a newer release is available, exits with code 1 in case there is one"`
+	Force      bool   `koanf:"force" description:"replace the binary even if it is a development build or already up to date, e.g. in order to downgrade"`
+}
+
+func (cfg *UpdateConfig) Validate() error {
+	owner, name, found := strings.Cut(cfg.Repository, "/")
+	if !found || owner == "" || name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid repository %q: must be of the form owner/name", cfg.Repository)
+	}
+
+	if cfg.APIURL == "" {
+		return errors.New("api url is required")
+	}
+	cfg.APIURL = strings.TrimSuffix(cfg.APIURL, "/")
+
+	if cfg.Version != "" && !semver.IsValid(cfg.Version) {
+		return fmt.Errorf("invalid version %q: must be a release tag like v1.2.0", cfg.Version)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+func NewVotesConfig() VotesConfig {
+	return VotesConfig{
+		SearchDir: ".",
+		FileRegex: `.*\.log$`,
+		Output:    FormatText,
+	}
+}
+
+// VotesConfig is the configuration of the votes subcommand.
+type VotesConfig struct {
+	SearchDir  string         `koanf:"search.dir" short:"d" description:"directory to search for files recursively"`
+	FileRegex  string         `koanf:"file.regex" short:"f" description:"regex to match files in the search dir"`
+	FileRegexp *regexp.Regexp `koanf:"-"`
+	Output     string         `koanf:"output" short:"o" description:"output format, one of 'json' or 'text'"`
+}
+
+func (cfg *VotesConfig) Validate() error {
+	var err error
+	cfg.FileRegexp, err = compilePathRegex(cfg.FileRegex)
+	if err != nil {
+		return fmt.Errorf("invalid file regex: %w", err)
+	}
+
+	allowed := []string{FormatJSON, FormatText}
+	lOutput := strings.ToLower(cfg.Output)
+	if !isOneOf(lOutput, allowed...) {
+		return fmt.Errorf("invalid output format %q: must be one of %v", cfg.Output, allowed)
+	}
+	cfg.Output = lOutput
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// WhereEnv contains the variables of a where expression, named like the json fields of a match.
+type WhereEnv struct {
+	Query         string             `expr:"query"`
+	Server        string             `expr:"server"`
+	File          string             `expr:"file"`
+	Event         string             `expr:"event"`
+	Map           string             `expr:"map"`
+	Nickname      string             `expr:"nickname"`
+	Dummy         bool               `expr:"dummy"`
+	MainNickname  string             `expr:"main_nickname"`
+	ClientVersion string             `expr:"client_version"`
+	FinishTime    float64            `expr:"finish_time"`
+	ID            int                `expr:"id"`
+	IP            string             `expr:"ip"`
+	Hostname      string             `expr:"hostname"`
+	VPN           bool               `expr:"vpn"`
+	Banned        bool               `expr:"banned"`
+	ASN           int                `expr:"asn"`
+	Organization  string             `expr:"organization"`
+	Text          string             `expr:"text"`
+	Language      string             `expr:"language"`
+	Labels        map[string]float64 `expr:"labels"`
+	Toxicity      float64            `expr:"toxicity"`
+	Line          int                `expr:"line"`
+	// Time is the zero time when the log format has no timestamps.
+	Time     time.Time         `expr:"time"`
+	Fields   map[string]string `expr:"fields"`
+	Category string            `expr:"category"`
+	Severity int               `expr:"severity"`
+}
+
+// CompileWhere compiles a boolean expression that is evaluated for every match.
+func CompileWhere(expression string) (*vm.Program, error) {
+	program, err := expr.Compile(expression, expr.Env(WhereEnv{}), expr.AsBool())
+	if err != nil {
+		return nil, fmt.Errorf("invalid where expression: %w", err)
+	}
+	return program, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "inspect the configuration",
+	}
+	cmd.AddCommand(NewConfigShowCmd())
+	return cmd
+}
+
+// NewConfigShowCmd prints the configuration that results from merging
+// flags > environment variables > .env file > yaml config file > defaults.
+func NewConfigShowCmd() *cobra.Command {
+	cfg := config.NewConfig()
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "print the effective configuration in the yaml config file format",
+		Args:  cobra.NoArgs,
+	}
+	parser := registerConfigFlags(&cfg, cmd, false)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		// an invalid config file is an error, unlike an invalid configuration
+		err := cfg.LoadFile(changedFlagArgs(cmd))
+		if err != nil {
+			return err
+		}
+
+		err = parser()
+		if err != nil {
+			// an incomplete configuration is still worth printing
+			log.Printf("configuration is not valid: %v", err)
+		}
+
+		data, err := cfg.YAML()
+		if err != nil {
+			return fmt.Errorf("failed to marshal configuration: %w", err)
+		}
+		_, err = cmd.OutOrStdout().Write(data)
+		return err
+	}
+	return cmd
+}
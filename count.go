@@ -0,0 +1,68 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FileCount is the number of matches of a file or of a file inside of an archive.
+type FileCount struct {
+	File    string `json:"file"`
+	Archive string `json:"archive,omitempty"`
+	Member  string `json:"member,omitempty"`
+	Matches int    `json:"matches"`
+}
+
+func (c FileCount) String() string {
+	return fmt.Sprintf("%s:%d", c.File, c.Matches)
+}
+
+type FileCountList []FileCount
+
+func (l FileCountList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(l) * 64)
+	for _, c := range l {
+		sb.WriteString(c.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func (l FileCountList) CSV() [][]string {
+	records := make([][]string, 0, len(l)+1)
+	records = append(records, []string{"file", "archive", "member", "matches"})
+	for _, c := range l {
+		records = append(records, []string{c.File, c.Archive, c.Member, strconv.Itoa(c.Matches)})
+	}
+	return records
+}
+
+// ToFileCountList counts the matches per file, sorted by file.
+func (p PlayerExtendedList) ToFileCountList() FileCountList {
+	byFile := make(map[string]*FileCount, 16)
+	for _, player := range p {
+		c, found := byFile[player.File]
+		if !found {
+			c = &FileCount{
+				File:    player.File,
+				Archive: player.Archive,
+				Member:  player.Member,
+			}
+			byFile[player.File] = c
+		}
+		c.Matches++
+	}
+
+	counts := make(FileCountList, 0, len(byFile))
+	for _, c := range byFile {
+		counts = append(counts, *c)
+	}
+	slices.SortFunc(counts, func(a, b FileCount) int {
+		return cmp.Compare(a.File, b.File)
+	})
+	return counts
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// NewDiffCmd prints the matches of a scan that were not found by a previous scan.
+func NewDiffCmd() *cobra.Command {
+	cfg := config.NewDiffConfig()
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "print only the matches of the new json results that are missing in the old json results",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		oldPlayers, err := readPlayersFile(cfg.Old, nil)
+		if err != nil {
+			return err
+		}
+		newPlayers, err := readPlayersFile(cfg.New, cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+
+		added := diffPlayers(oldPlayers, newPlayers)
+		if cfg.Output == config.FormatText {
+			_, err = fmt.Fprint(cmd.OutOrStdout(), added)
+			return err
+		}
+
+		data, err := json.MarshalIndent(added, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal json result: %w", err)
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s\n", data)
+		return err
+	}
+	return cmd
+}
+
+// readPlayersFile reads json results from a file, - reads from stdin.
+func readPlayersFile(path string, stdin io.Reader) (PlayerExtendedList, error) {
+	r := stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open results: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	players, err := decodePlayerExtendedList(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse json results %s: %w", path, err)
+	}
+	return players, nil
+}
+
+// matchIdentity identifies a chat message independently of enrichments, positions and archives,
+// as logs may be rotated into archives between two scans.
+type matchIdentity struct {
+	query, nickname, ip, text string
+	time                      time.Time
+	// file is only used in case the logs contain no timestamps
+	file string
+}
+
+func (p PlayerExtended) identity() matchIdentity {
+	id := matchIdentity{
+		query:    p.Query,
+		nickname: p.Nickname,
+		ip:       p.IP,
+		text:     p.Text,
+	}
+	if p.Time != nil {
+		id.time = p.Time.UTC()
+	} else {
+		id.file = p.File
+	}
+	return id
+}
+
+// diffPlayers returns the players of newPlayers that are missing in oldPlayers.
+func diffPlayers(oldPlayers, newPlayers PlayerExtendedList) PlayerExtendedList {
+	known := make(map[matchIdentity]struct{}, len(oldPlayers))
+	for _, p := range oldPlayers {
+		known[p.identity()] = struct{}{}
+	}
+
+	added := make(PlayerExtendedList, 0, len(newPlayers))
+	for _, p := range newPlayers {
+		if _, found := known[p.identity()]; !found {
+			added = append(added, p)
+		}
+	}
+	return added
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// discordMaxContentLength is the maximum length of a discord message.
+const discordMaxContentLength = 2000
+
+// discordWebhook posts matches to a discord channel.
+type discordWebhook struct {
+	url    string
+	client *http.Client
+}
+
+func newDiscordWebhook(url string) *discordWebhook {
+	return &discordWebhook{
+		url:    url,
+		client: &http.Client{Timeout: sinkTimeout},
+	}
+}
+
+// Post sends the matches as one or more messages, every line contains a single match.
+func (d *discordWebhook) Post(ctx context.Context, players PlayerExtendedList) error {
+	var sb strings.Builder
+	for _, p := range players {
+		line := formatDiscordLine(p)
+		if sb.Len() > 0 && sb.Len()+1+len(line) > discordMaxContentLength {
+			err := d.post(ctx, sb.String())
+			if err != nil {
+				return err
+			}
+			sb.Reset()
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(line)
+	}
+	return d.post(ctx, sb.String())
+}
+
+func formatDiscordLine(p PlayerExtended) string {
+	var sb strings.Builder
+	if p.Server != "" {
+		fmt.Fprintf(&sb, "[%s] ", escapeDiscord(p.Server))
+	}
+	if p.Query != "" {
+		fmt.Fprintf(&sb, "(%s) ", escapeDiscord(p.Query))
+	}
+	fmt.Fprintf(&sb, "**%s** `%s`: %s", escapeDiscord(p.Nickname), p.IP, escapeDiscord(p.Text))
+
+	line := sb.String()
+	if len(line) > discordMaxContentLength {
+		line = line[:discordMaxContentLength]
+	}
+	return line
+}
+
+var discordEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"~", `\~`,
+	"`", "\\`",
+	"|", `\|`,
+	">", `\>`,
+)
+
+// escapeDiscord prevents players from injecting markdown into the message.
+func escapeDiscord(s string) string {
+	return discordEscaper.Replace(s)
+}
+
+func (d *discordWebhook) post(ctx context.Context, content string) error {
+	body, err := json.Marshal(map[string]any{
+		"content": content,
+		// chat messages must not ping anyone
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := d.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to post discord message: %w", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			// discord tells us how long to wait
+			retryAfter, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+			if err != nil || retryAfter <= 0 {
+				retryAfter = 1
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(retryAfter * float64(time.Second))):
+			}
+		case resp.StatusCode >= 300:
+			return fmt.Errorf("failed to post discord message: unexpected status code: %s", resp.Status)
+		default:
+			return nil
+		}
+	}
+}
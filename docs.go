@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewDocsCmd generates man pages and a flag reference from the flag definitions of all commands.
+func NewDocsCmd() *cobra.Command {
+	cfg := config.NewDocsConfig()
+
+	cmd := &cobra.Command{
+		Use:   "docs",
+		Short: "generate man pages and a markdown reference of all commands and flags, e.g. for distribution packages",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		err := os.MkdirAll(cfg.Dir, 0o755)
+		if err != nil {
+			return fmt.Errorf("failed to create docs dir: %w", err)
+		}
+
+		// the name of the binary differs, e.g. when it is run via go run
+		root := cmd.Root()
+		root.Use = cfg.Name
+		commands := documentedCommands(root)
+		date := docsDate()
+
+		for _, format := range cfg.FormatList {
+			switch format {
+			case config.DocsFormatMan:
+				for _, c := range commands {
+					path := filepath.Join(cfg.Dir, manPageName(c)+".1")
+					err = writeFile(path, func(w io.Writer) error {
+						return writeManPage(w, c, date)
+					})
+					if err != nil {
+						return err
+					}
+				}
+			case config.DocsFormatMarkdown:
+				path := filepath.Join(cfg.Dir, "reference.md")
+				err = writeFile(path, func(w io.Writer) error {
+					return writeMarkdownReference(w, commands)
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "documented %d commands in %s\n", len(commands), cfg.Dir)
+		return err
+	}
+	return cmd
+}
+
+// documentedCommands returns the command and all of its subcommands, the help command is left out.
+func documentedCommands(cmd *cobra.Command) []*cobra.Command {
+	commands := []*cobra.Command{cmd}
+	for _, c := range cmd.Commands() {
+		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		commands = append(commands, documentedCommands(c)...)
+	}
+	return commands
+}
+
+// docsDate is the date in the man pages. SOURCE_DATE_EPOCH makes the pages reproducible,
+// otherwise the commit time of the binary is used.
+func docsDate() time.Time {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		if sec, err := strconv.ParseInt(epoch, 10, 64); err == nil {
+			return time.Unix(sec, 0).UTC()
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, readVersionInfo().BuildDate); err == nil {
+		return t
+	}
+	return time.Now().UTC()
+}
+
+func manPageName(cmd *cobra.Command) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+}
+
+// documentedFlags returns the flags of the command without help and version.
+// All other flags can be set via environment variables as well.
+func documentedFlags(cmd *cobra.Command) []*pflag.Flag {
+	flags := make([]*pflag.Flag, 0, 16)
+	cmd.NonInheritedFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Hidden || f.Name == "help" || f.Name == "version" {
+			return
+		}
+		flags = append(flags, f)
+	})
+	slices.SortFunc(flags, func(a, b *pflag.Flag) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return flags
+}
+
+func flagEnv(f *pflag.Flag) string {
+	return config.EnvPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+}
+
+// flagDefault returns the default value of the flag, empty for zero values.
+// Paths in the home directory of the user that generates the docs start with ~ instead.
+func flagDefault(f *pflag.Flag) string {
+	switch f.DefValue {
+	case "", "false", "0", "0s", "[]":
+		return ""
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		if rest, ok := strings.CutPrefix(f.DefValue, home); ok && (rest == "" || os.IsPathSeparator(rest[0])) {
+			return "~" + rest
+		}
+	}
+	return f.DefValue
+}
+
+func writeFile(path string, write func(w io.Writer) error) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+
+	bw := bufio.NewWriter(f)
+	err = write(bw)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return bw.Flush()
+}
+
+// roffEscape escapes text, so that roff does not interpret it as request or escape sequence.
+func roffEscape(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "-", `\-`)
+	lines := strings.Split(text, "\n")
+	for idx, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[idx] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func writeManPage(w io.Writer, cmd *cobra.Command, date time.Time) error {
+	name := manPageName(cmd)
+	root := cmd.Root().Name()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, ".TH %q 1 %q %q \"User Commands\"\n",
+		strings.ToUpper(name), date.Format("2006-01-02"), root+" "+readVersionInfo().Version)
+
+	sb.WriteString(".SH NAME\n")
+	fmt.Fprintf(&sb, "%s \\- %s\n", roffEscape(name), roffEscape(cmd.Short))
+
+	sb.WriteString(".SH SYNOPSIS\n")
+	fmt.Fprintf(&sb, "\\fB%s\\fP [flags]\n", roffEscape(cmd.CommandPath()))
+	if cmd.HasAvailableSubCommands() {
+		fmt.Fprintf(&sb, ".br\n\\fB%s\\fP \\fIcommand\\fP [flags]\n", roffEscape(cmd.CommandPath()))
+	}
+
+	if cmd.Short != "" {
+		sb.WriteString(".SH DESCRIPTION\n")
+		sb.WriteString(roffEscape(cmd.Short) + "\n")
+	}
+
+	flags := documentedFlags(cmd)
+	if len(flags) > 0 {
+		sb.WriteString(".SH OPTIONS\n")
+		for _, f := range flags {
+			sb.WriteString(".TP\n")
+			if f.Shorthand != "" {
+				fmt.Fprintf(&sb, "\\fB\\-%s\\fP, ", f.Shorthand)
+			}
+			fmt.Fprintf(&sb, "\\fB\\-\\-%s\\fP", roffEscape(f.Name))
+			if f.Value.Type() != "bool" {
+				fmt.Fprintf(&sb, " \\fI%s\\fP", f.Value.Type())
+			}
+			sb.WriteString("\n" + roffEscape(f.Usage))
+			if def := flagDefault(f); def != "" {
+				fmt.Fprintf(&sb, " (default: \"%s\")", roffEscape(def))
+			}
+			sb.WriteString("\n")
+		}
+
+		sb.WriteString(".SH ENVIRONMENT\n")
+		sb.WriteString("Every flag can be set via an environment variable as well.\n")
+		for _, f := range flags {
+			fmt.Fprintf(&sb, ".TP\n\\fB%s\\fP\n\\fB\\-\\-%s\\fP\n", roffEscape(flagEnv(f)), roffEscape(f.Name))
+		}
+	}
+
+	related := make([]string, 0, 8)
+	if cmd.HasParent() {
+		related = append(related, manPageName(cmd.Parent()))
+	}
+	for _, c := range cmd.Commands() {
+		if c.IsAvailableCommand() && !c.IsAdditionalHelpTopicCommand() {
+			related = append(related, manPageName(c))
+		}
+	}
+	if len(related) > 0 {
+		sb.WriteString(".SH SEE ALSO\n")
+		for idx, page := range related {
+			if idx > 0 {
+				sb.WriteString(",\n")
+			}
+			fmt.Fprintf(&sb, "\\fB%s\\fP(1)", roffEscape(page))
+		}
+		sb.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// markdownEscape escapes the characters that break markdown table cells.
+func markdownEscape(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.ReplaceAll(text, "\n", " ")
+}
+
+func writeMarkdownReference(w io.Writer, commands []*cobra.Command) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s command reference\n\n", commands[0].Root().Name())
+	sb.WriteString("Generated from the flag definitions, every flag can be set via its environment variable as well.\n")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(&sb, "\n## %s\n\n", cmd.CommandPath())
+		if cmd.Short != "" {
+			sb.WriteString(cmd.Short + "\n\n")
+		}
+		fmt.Fprintf(&sb, "```\n%s [flags]\n```\n", cmd.CommandPath())
+
+		flags := documentedFlags(cmd)
+		if len(flags) == 0 {
+			continue
+		}
+		sb.WriteString("\n| flag | environment variable | default | description |\n")
+		sb.WriteString("| --- | --- | --- | --- |\n")
+		for _, f := range flags {
+			flag := "`--" + f.Name + "`"
+			if f.Shorthand != "" {
+				flag = "`-" + f.Shorthand + "`, " + flag
+			}
+			def := flagDefault(f)
+			if def != "" {
+				def = "`" + markdownEscape(def) + "`"
+			}
+			fmt.Fprintf(&sb, "| %s | `%s` | %s | %s |\n", flag, flagEnv(f), def, markdownEscape(f.Usage))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog-who-said/archive"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// number of files that are sampled in order to estimate the scan duration
+	dryRunSampleFiles = 4
+	// number of bytes that are read from every sampled file
+	dryRunSampleBytes = 4 * 1024 * 1024
+)
+
+type DryRunFile struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	Archive bool   `json:"archive"`
+}
+
+type DryRunResult struct {
+	Files             []DryRunFile `json:"files"`
+	NumFiles          int          `json:"num_files"`
+	NumArchives       int          `json:"num_archives"`
+	TotalBytes        int64        `json:"total_bytes"`
+	SampledBytes      int64        `json:"sampled_bytes"`
+	BytesPerSecond    float64      `json:"bytes_per_second"`
+	EstimatedDuration string       `json:"estimated_duration"`
+}
+
+func (r DryRunResult) String() string {
+	var sb strings.Builder
+	for _, f := range r.Files {
+		kind := "file"
+		if f.Archive {
+			kind = "archive"
+		}
+		fmt.Fprintf(&sb, "%-7s %12d %s\n", kind, f.Size, f.Path)
+	}
+	fmt.Fprintf(&sb, "\n%d files and %d archives with a total of %d bytes\n", r.NumFiles, r.NumArchives, r.TotalBytes)
+	if r.SampledBytes > 0 {
+		fmt.Fprintf(&sb, "sampled %d bytes at %.1f MiB/s per worker\n", r.SampledBytes, r.BytesPerSecond/1024/1024)
+	}
+	fmt.Fprintf(&sb, "estimated duration: %s", r.EstimatedDuration)
+	if r.NumArchives > 0 {
+		sb.WriteString(" (archives are estimated based on their compressed size)")
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+func (r *DryRunResult) add(paths []string, isArchive bool) error {
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		r.Files = append(r.Files, DryRunFile{
+			Path:    path,
+			Size:    fi.Size(),
+			Archive: isArchive,
+		})
+		r.TotalBytes += fi.Size()
+	}
+	return nil
+}
+
+// dryRun lists the files and archives that would be scanned and estimates the scan duration
+// by scanning the beginning of a few files.
+func (cli *CLI) dryRun(cmd *cobra.Command) error {
+	files, archives, err := cli.collect(cli.ctx, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	result := DryRunResult{
+		Files:       make([]DryRunFile, 0, len(files)+len(archives)),
+		NumFiles:    len(files),
+		NumArchives: len(archives),
+	}
+
+	err = result.add(files, false)
+	if err != nil {
+		return err
+	}
+	err = result.add(archives, true)
+	if err != nil {
+		return err
+	}
+
+	sampled, elapsed, err := cli.sampleScan(files)
+	if err != nil {
+		return err
+	}
+	result.SampledBytes = sampled
+
+	result.EstimatedDuration = "unknown"
+	if sampled > 0 && elapsed > 0 {
+		result.BytesPerSecond = float64(sampled) / elapsed.Seconds()
+		workers := min(cli.cfg.Concurrency, max(1, len(result.Files)))
+		estimate := time.Duration(float64(result.TotalBytes) / result.BytesPerSecond / float64(workers) * float64(time.Second))
+		result.EstimatedDuration = estimate.Round(time.Second).String()
+	}
+
+	return cli.print(cmd.OutOrStdout(), cli.dryRunFormat(), result)
+}
+
+func (cli *CLI) dryRunFormat() string {
+	if cli.cfg.Output == config.FormatJSON {
+		return config.FormatJSON
+	}
+	return config.FormatText
+}
+
+// sampleScan searches the beginning of a few files that are spread over the file list
+// and returns the number of scanned bytes as well as the time it took.
+func (cli *CLI) sampleScan(files []string) (sampled int64, elapsed time.Duration, err error) {
+	if len(files) == 0 {
+		return 0, 0, nil
+	}
+
+	step := max(1, len(files)/dryRunSampleFiles)
+	for i := 0; i < len(files) && i/step < dryRunSampleFiles; i += step {
+		path := files[i]
+		n, d, err := cli.sampleFile(path)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to sample file %s: %w", path, err)
+		}
+		sampled += n
+		elapsed += d
+	}
+	return sampled, elapsed, nil
+}
+
+func (cli *CLI) sampleFile(path string) (int64, time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start := time.Now()
+	size := min(fi.Size(), dryRunSampleBytes)
+	memFile, err := archive.NewFile(io.LimitReader(f, size), size)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, err = searchPhrase(cli.ctx, path, memFile, cli.queriesFor(path), cli.cfg.InvalidUTF8)
+	if err != nil {
+		return 0, 0, err
+	}
+	return size, time.Since(start), nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// dummyMarkerRegexp matches join lines of servers that log dummy connections explicitly.
+var dummyMarkerRegexp = regexp.MustCompile(`(?i)\bdummy\b`)
+
+// connections tracks the connected clients of a server in order to detect dummies,
+// which are additional clients of a player that connect from the same IP,
+// and to remember the client versions.
+type connections struct {
+	seq     int
+	clients map[int]*connection
+	// versions are announced before or after the join line and are kept until the client leaves
+	versions map[int]string
+	// lastSanction is the last sanction issued via rcon, whose net_ban or kick line follows
+	lastSanction *sanction
+}
+
+type connection struct {
+	ip       string
+	port     int
+	nickname string
+	// seq is the order in which the clients joined, the first client of an IP is the main player
+	seq    int
+	marked bool
+}
+
+func newConnections() *connections {
+	return &connections{
+		clients:  make(map[int]*connection, 64),
+		versions: make(map[int]string, 64),
+	}
+}
+
+// Update applies join and leave lines and remembers the nicknames and versions of the clients.
+func (c *connections) Update(line string, event logEvent) {
+	if id, version, ok := parseClientVersion(event.Text); ok {
+		c.versions[id] = version
+	}
+
+	if isLeaveLine(event.Text) {
+		delete(c.clients, event.ID)
+		delete(c.versions, event.ID)
+		return
+	}
+
+	if event.Type == config.EventConnect && event.IP != "" {
+		c.seq++
+		c.clients[event.ID] = &connection{
+			ip:       event.IP,
+			port:     event.Port,
+			nickname: event.Nickname,
+			seq:      c.seq,
+			marked:   dummyMarkerRegexp.MatchString(line),
+		}
+		return
+	}
+
+	if client, ok := c.clients[event.ID]; ok && event.Nickname != "" {
+		client.nickname = event.Nickname
+	}
+}
+
+// idOf returns the id of the connected client with the nickname, -1 when it is unknown.
+func (c *connections) idOf(nickname string) int {
+	for id, client := range c.clients {
+		if client.nickname == nickname {
+			return id
+		}
+	}
+	return -1
+}
+
+// Port returns the source port of the connected client, zero when it is unknown.
+func (c *connections) Port(id int) int {
+	if client, ok := c.clients[id]; ok {
+		return client.port
+	}
+	return 0
+}
+
+// Version returns the client version that the client announced, empty when it is unknown.
+func (c *connections) Version(id int) string {
+	return c.versions[id]
+}
+
+// MainPlayer returns whether the client is a dummy and the nickname of the main player,
+// which is empty when it is unknown.
+func (c *connections) MainPlayer(id int) (dummy bool, mainNickname string) {
+	client, ok := c.clients[id]
+	if !ok {
+		return false, ""
+	}
+
+	var main *connection
+	for otherID, other := range c.clients {
+		if otherID == id || other.ip != client.ip || other.seq > client.seq {
+			continue
+		}
+		if main == nil || other.seq < main.seq {
+			main = other
+		}
+	}
+	if main == nil {
+		return client.marked, ""
+	}
+	return true, main.nickname
+}
+
+// CollapseDummies attributes the matches of dummies to their main player in aggregations.
+func (p PlayerExtendedList) CollapseDummies() PlayerExtendedList {
+	players := make(PlayerExtendedList, 0, len(p))
+	for _, player := range p {
+		if player.Dummy && player.MainNickname != "" {
+			player.Nickname = player.MainNickname
+		}
+		players = append(players, player)
+	}
+	return players
+}
+
+func isLeaveLine(message string) bool {
+	lMessage := strings.ToLower(message)
+	return strings.HasPrefix(message, "leave ") ||
+		strings.Contains(lMessage, "client dropped") ||
+		strings.Contains(lMessage, "player has left the game")
+}
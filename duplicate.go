@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// fingerprintBlockSize is the size of the first and last block of a file that is hashed.
+const fingerprintBlockSize = 64 * 1024
+
+// fileFingerprint identifies files with the same content, e.g. the same log file
+// inside and outside of a backup archive. Only the first and last block are hashed,
+// as log files only differ in their end once they were appended to.
+type fileFingerprint struct {
+	size       int64
+	head, tail [sha256.Size]byte
+}
+
+func fingerprint(r io.ReaderAt, size int64) (fileFingerprint, error) {
+	fp := fileFingerprint{size: size}
+
+	buf := make([]byte, min(size, fingerprintBlockSize))
+	_, err := r.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fp, err
+	}
+	fp.head = sha256.Sum256(buf)
+
+	_, err = r.ReadAt(buf, size-int64(len(buf)))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fp, err
+	}
+	fp.tail = sha256.Sum256(buf)
+	return fp, nil
+}
+
+func fingerprintFile(path string) (fileFingerprint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return fileFingerprint{}, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fileFingerprint{}, err
+	}
+	return fingerprint(f, fi.Size())
+}
+
+// duplicateFiles remembers the first file of every fingerprint.
+type duplicateFiles struct {
+	mu   sync.Mutex
+	seen map[fileFingerprint]string
+}
+
+func newDuplicateFiles() *duplicateFiles {
+	return &duplicateFiles{
+		seen: make(map[fileFingerprint]string, 64),
+	}
+}
+
+// claim returns the path of the first file with the same fingerprint and true
+// in case the file at path is a duplicate of it.
+func (d *duplicateFiles) claim(fp fileFingerprint, path string) (original string, duplicate bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if original, ok := d.seen[fp]; ok {
+		return original, true
+	}
+	d.seen[fp] = path
+	return "", false
+}
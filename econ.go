@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	econDialTimeout    = 10 * time.Second
+	econReconnectDelay = 5 * time.Second
+)
+
+var (
+	ErrEconAuthentication = errors.New("econ authentication failed")
+)
+
+// econConn is a connection to the external console of a DDNet or Teeworlds server.
+type econConn struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+// dialEcon connects to the econ port and authenticates with the password.
+func dialEcon(ctx context.Context, address, password string) (*econConn, error) {
+	dialer := &net.Dialer{Timeout: econDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to econ %s: %w", address, err)
+	}
+
+	c := &econConn{
+		conn:    conn,
+		scanner: bufio.NewScanner(conn),
+	}
+
+	// the server asks for the password and acknowledges it before any log line is sent
+	err = c.conn.SetDeadline(time.Now().Add(econDialTimeout))
+	if err != nil {
+		return nil, errors.Join(err, c.Close())
+	}
+
+	err = c.Send(password)
+	if err != nil {
+		return nil, errors.Join(err, c.Close())
+	}
+
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to authenticate at econ %s: %w", address, err), c.Close())
+		}
+		if strings.HasPrefix(line, "Authentication successful") {
+			break
+		}
+		if strings.HasPrefix(line, "Wrong password") {
+			return nil, errors.Join(fmt.Errorf("%w: %s", ErrEconAuthentication, line), c.Close())
+		}
+	}
+
+	err = c.conn.SetDeadline(time.Time{})
+	if err != nil {
+		return nil, errors.Join(err, c.Close())
+	}
+	return c, nil
+}
+
+// ReadLine blocks until the next line of the server output is received.
+func (c *econConn) ReadLine() (string, error) {
+	if !c.scanner.Scan() {
+		err := c.scanner.Err()
+		if err == nil {
+			err = net.ErrClosed
+		}
+		return "", err
+	}
+	return strings.TrimRight(c.scanner.Text(), "\r\x00"), nil
+}
+
+// Send executes a console command.
+func (c *econConn) Send(command string) error {
+	_, err := c.conn.Write([]byte(command + "\n"))
+	return err
+}
+
+func (c *econConn) Close() error {
+	return c.conn.Close()
+}
+
+// runEcon applies the queries to the live output of a server until the context is canceled.
+// Lost connections are reestablished, a wrong password aborts.
+func (cli *CLI) runEcon(cmd *cobra.Command) error {
+	address := cli.cfg.EconAddress
+	for {
+		err := cli.watchEcon(cmd, address)
+		if errors.Is(err, ErrEconAuthentication) {
+			return err
+		}
+		if checkShutDown(cli.ctx) != nil {
+			slog.Info("stopping econ watcher", "address", address)
+			return nil
+		}
+		errorsTotal.Inc()
+		slog.Warn("econ connection lost, reconnecting", "address", address, "error", err, "delay", econReconnectDelay)
+
+		select {
+		case <-cli.ctx.Done():
+			slog.Info("stopping econ watcher", "address", address)
+			return nil
+		case <-time.After(econReconnectDelay):
+		}
+	}
+}
+
+func (cli *CLI) watchEcon(cmd *cobra.Command, address string) error {
+	c, err := dialEcon(cli.ctx, address, cli.cfg.EconPassword)
+	if err != nil {
+		return err
+	}
+	slog.Info("connected to econ", "address", address)
+
+	// unblock the reader on shutdown
+	stop := context.AfterFunc(cli.ctx, func() {
+		_ = c.Close()
+	})
+	defer func() {
+		if stop() {
+			_ = c.Close()
+		}
+	}()
+
+	var (
+		w       = cmd.OutOrStdout()
+		server  = cli.serverID(address)
+		queries = cli.cfg.Queries()
+		// live output cannot be searched backwards, the IPs are remembered when players join
+		ips = make(map[int]string, 64)
+		// currentMap is unknown until the first map change after connecting
+		currentMap string
+		// clients are tracked in order to detect dummies
+		clients = newConnections()
+	)
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return err
+		}
+		linesScanned.Inc()
+		line, raw, ok := validLine(line, cli.cfg.InvalidUTF8)
+		if !ok {
+			continue
+		}
+
+		if name, ok := parseMapLine(line); ok {
+			currentMap = name
+			continue
+		}
+
+		event, ok := parseEvent(line)
+		if !ok {
+			continue
+		}
+		// sanctions are resolved before their targets leave
+		searched := clients.Resolve(&event)
+		clients.Update(line, event)
+		if !searched {
+			continue
+		}
+		if event.Type == config.EventConnect && event.IP != "" {
+			ips[event.ID] = event.IP
+		}
+
+		matchedQueries := matchQueries(queries, event.Type, event.Text)
+		if len(matchedQueries) == 0 {
+			continue
+		}
+
+		ip := event.IP
+		if ip == "" {
+			ip = ips[event.ID]
+		}
+		if ip == "" {
+			slog.Warn("could not find join line", "econ", address, "name", event.Nickname, "id", event.ID)
+			continue
+		}
+
+		var timestamp *time.Time
+		if t, ok := parseLogTime(line); ok {
+			timestamp = &t
+		}
+
+		players := make(PlayerExtendedList, 0, len(matchedQueries))
+		dummy, mainNickname := clients.MainPlayer(event.ID)
+		for _, m := range matchedQueries {
+			fields := m.fields
+			if event.Sanction != nil {
+				fields = event.Sanction.Fields(fields)
+			}
+			players = append(players, PlayerExtended{
+				Query:         m.name,
+				Server:        server,
+				File:          address,
+				Event:         eventName(event.Type),
+				Map:           currentMap,
+				Nickname:      event.Nickname,
+				Dummy:         dummy,
+				MainNickname:  mainNickname,
+				ClientVersion: clients.Version(event.ID),
+				FinishTime:    event.FinishTime.Seconds(),
+				ID:            event.ID,
+				IP:            ip,
+				Port:          clients.Port(event.ID),
+				Text:          event.Text,
+				TextBase64:    rawText(raw),
+				Time:          timestamp,
+				Fields:        fields,
+				Category:      m.category,
+				Severity:      m.severity,
+			})
+		}
+		players = cli.enrich(cli.ctx, players)
+		// responses, e.g. bans, require the unredacted IPs
+		redacted := cli.formatIPs(cli.redact(players))
+		cli.liveMatches += len(players)
+		cli.sendToSinks(redacted)
+		recordMatches(redacted)
+
+		for idx, p := range players {
+			err = cli.printMatch(w, cli.cfg.Query(p.Query), redacted[idx])
+			if err != nil {
+				return err
+			}
+
+			err = cli.respond(c, p)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// respond executes the configured console commands for a match.
+func (cli *CLI) respond(c *econConn, p PlayerExtended) error {
+	if cli.cfg.EconResponseTemplate == nil {
+		return nil
+	}
+
+	var sb strings.Builder
+	err := cli.cfg.EconResponseTemplate.Execute(&sb, p)
+	if err != nil {
+		return fmt.Errorf("failed to render econ response: %w", err)
+	}
+
+	command := strings.TrimSpace(sb.String())
+	if command == "" {
+		return nil
+	}
+	err = config.CheckEconCommand(command)
+	if err != nil {
+		slog.Warn("skipping econ response", "command", command, "name", p.Nickname, "id", p.ID, "error", err)
+		return nil
+	}
+
+	slog.Info("executing econ response", "command", command, "name", p.Nickname, "id", p.ID)
+	return c.Send(command)
+}
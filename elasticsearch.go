@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// elasticsearchMapping maps the fields of a match to types that can be searched and aggregated in kibana.
+const elasticsearchMapping = `{
+  "mappings": {
+    "properties": {
+      "@timestamp": {"type": "date"},
+      "query":      {"type": "keyword"},
+      "server":     {"type": "keyword"},
+      "file":       {"type": "keyword"},
+      "archive":    {"type": "keyword"},
+      "member":     {"type": "keyword"},
+      "nickname":   {"type": "keyword"},
+      "id":         {"type": "integer"},
+      "ip":         {"type": "ip"},
+      "hostname":   {"type": "keyword"},
+      "vpn":        {"type": "boolean"},
+      "text":       {"type": "text"},
+      "line":       {"type": "long"},
+      "offset":     {"type": "long"}
+    }
+  }
+}`
+
+// elasticsearchDocument is an indexed match.
+type elasticsearchDocument struct {
+	Timestamp *time.Time `json:"@timestamp,omitempty"`
+	Query     string     `json:"query,omitempty"`
+	Server    string     `json:"server,omitempty"`
+	File      string     `json:"file"`
+	Archive   string     `json:"archive,omitempty"`
+	Member    string     `json:"member,omitempty"`
+	Nickname  string     `json:"nickname"`
+	ID        int        `json:"id"`
+	IP        string     `json:"ip"`
+	Hostname  string     `json:"hostname,omitempty"`
+	VPN       bool       `json:"vpn"`
+	Text      string     `json:"text"`
+	Line      int        `json:"line,omitempty"`
+	Offset    int64      `json:"offset,omitempty"`
+	Fields    Fields     `json:"fields,omitempty"`
+}
+
+// elasticsearchIndexer bulk indexes matches into elasticsearch or opensearch.
+type elasticsearchIndexer struct {
+	url      string
+	index    string
+	username string
+	password string
+	client   *http.Client
+
+	mu           sync.Mutex
+	indexCreated bool
+}
+
+func newElasticsearchIndexer(url, index, username, password string) *elasticsearchIndexer {
+	return &elasticsearchIndexer{
+		url:      strings.TrimRight(url, "/"),
+		index:    index,
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: sinkTimeout},
+	}
+}
+
+// Post indexes the matches with a single bulk request.
+func (e *elasticsearchIndexer) Post(ctx context.Context, players PlayerExtendedList) error {
+	err := e.createIndex(ctx)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	action, err := json.Marshal(map[string]any{"index": map[string]string{"_index": e.index}})
+	if err != nil {
+		return err
+	}
+	for _, p := range players {
+		doc, err := json.Marshal(elasticsearchDocument{
+			Timestamp: p.Time,
+			Query:     p.Query,
+			Server:    p.Server,
+			File:      p.File,
+			Archive:   p.Archive,
+			Member:    p.Member,
+			Nickname:  p.Nickname,
+			ID:        p.ID,
+			IP:        p.IP,
+			Hostname:  p.Hostname,
+			VPN:       p.VPN,
+			Text:      p.Text,
+			Line:      p.Line,
+			Offset:    p.Offset,
+			Fields:    p.Fields,
+		})
+		if err != nil {
+			return err
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(doc)
+		body.WriteByte('\n')
+	}
+
+	resp, err := e.do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", &body)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Error json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return fmt.Errorf("failed to parse elasticsearch bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+
+	failed := 0
+	var firstErr json.RawMessage
+	for _, item := range result.Items {
+		for _, status := range item {
+			if len(status.Error) > 0 {
+				failed++
+				if firstErr == nil {
+					firstErr = status.Error
+				}
+			}
+		}
+	}
+	return fmt.Errorf("failed to index %d of %d matches: %s", failed, len(players), firstErr)
+}
+
+// createIndex creates the index with the mapping unless it already exists.
+func (e *elasticsearchIndexer) createIndex(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.indexCreated {
+		return nil
+	}
+
+	_, err := e.do(ctx, http.MethodPut, "/"+e.index, "application/json", strings.NewReader(elasticsearchMapping))
+	if err != nil && !strings.Contains(err.Error(), "resource_already_exists_exception") {
+		return fmt.Errorf("failed to create elasticsearch index %s: %w", e.index, err)
+	}
+	e.indexCreated = true
+	return nil
+}
+
+func (e *elasticsearchIndexer) do(ctx context.Context, method, path, contentType string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, e.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if e.username != "" {
+		req.SetBasicAuth(e.username, e.password)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status code: %s: %s", resp.Status, data)
+	}
+	return data, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// enrich adds information about the IPs and messages to the players and applies the filters.
+func (cli *CLI) enrich(ctx context.Context, players PlayerExtendedList) PlayerExtendedList {
+	if cli.cfg.DetectLanguage {
+		for idx := range players {
+			players[idx].Language = detectLanguage(players[idx].Text)
+		}
+	}
+
+	if len(cli.cfg.LanguageList) > 0 {
+		spoken := make(PlayerExtendedList, 0, len(players))
+		for _, player := range players {
+			if slices.Contains(cli.cfg.LanguageList, player.Language) {
+				spoken = append(spoken, player)
+			}
+		}
+		players = spoken
+	}
+
+	if cli.cfg.MinMessageLength > 0 || cli.cfg.MaxMessageLength > 0 || cli.cfg.WordCount != "" {
+		sized := make(PlayerExtendedList, 0, len(players))
+		for _, player := range players {
+			if cli.messageSizeMatches(player.Text) {
+				sized = append(sized, player)
+			}
+		}
+		players = sized
+	}
+
+	if len(cli.cfg.ClanTagRegexps) > 0 {
+		for idx := range players {
+			name := stripClanTags(players[idx].Nickname, cli.cfg.ClanTagRegexps)
+			if name != players[idx].Nickname {
+				players[idx].RawNickname = players[idx].Nickname
+				players[idx].Nickname = name
+			}
+		}
+	}
+
+	if cli.cfg.NameRegexp != nil {
+		// the nickname in the output stays the raw nickname
+		named := make(PlayerExtendedList, 0, len(players))
+		for _, player := range players {
+			if cli.cfg.NameRegexp.MatchString(normalizeName(player.Nickname)) ||
+				(player.RawNickname != "" && cli.cfg.NameRegexp.MatchString(normalizeName(player.RawNickname))) {
+				named = append(named, player)
+			}
+		}
+		players = named
+	}
+
+	if cli.cfg.MapRegexp != nil {
+		played := make(PlayerExtendedList, 0, len(players))
+		for _, player := range players {
+			if cli.cfg.MapRegexp.MatchString(player.Map) {
+				played = append(played, player)
+			}
+		}
+		players = played
+	}
+
+	if cli.cfg.ClientVersionRegexp != nil {
+		versioned := make(PlayerExtendedList, 0, len(players))
+		for _, player := range players {
+			if cli.cfg.ClientVersionRegexp.MatchString(player.ClientVersion) {
+				versioned = append(versioned, player)
+			}
+		}
+		players = versioned
+	}
+
+	if cli.cfg.MaxFinishTime > 0 {
+		fast := make(PlayerExtendedList, 0, len(players))
+		for _, player := range players {
+			if player.Event != config.EventFinish || player.FinishTime < cli.cfg.MaxFinishTime.Seconds() {
+				fast = append(fast, player)
+			}
+		}
+		players = fast
+	}
+
+	// after the cheap filters, as http classifiers are called for every match
+	if cli.cfg.Classify != "" {
+		cli.classify(ctx, players)
+	}
+
+	if cli.cfg.MinToxicity > 0 {
+		toxic := make(PlayerExtendedList, 0, len(players))
+		for _, player := range players {
+			if player.Toxicity >= cli.cfg.MinToxicity {
+				toxic = append(toxic, player)
+			}
+		}
+		players = toxic
+	}
+
+	if cli.cfg.RDNS {
+		cli.resolveHostnames(ctx, players)
+	}
+
+	if cli.vpnRanges != nil {
+		for idx := range players {
+			players[idx].VPN = cli.vpnRanges.Contains(players[idx].IP)
+		}
+	}
+
+	if cli.knownBans != nil {
+		for idx := range players {
+			players[idx].Banned = cli.knownBans.Contains(players[idx].IP)
+		}
+	}
+
+	if cli.cfg.UnbannedOnly {
+		unbanned := make(PlayerExtendedList, 0, len(players))
+		for _, player := range players {
+			if !player.Banned {
+				unbanned = append(unbanned, player)
+			}
+		}
+		players = unbanned
+	}
+
+	if cli.asnDB != nil {
+		for idx := range players {
+			r, found := cli.asnDB.Lookup(players[idx].IP)
+			if found {
+				players[idx].ASN = r.asn
+				players[idx].Organization = r.organization
+			}
+		}
+	}
+
+	if len(cli.cfg.ASNExcludeList) > 0 {
+		included := make(PlayerExtendedList, 0, len(players))
+		for _, player := range players {
+			if !slices.Contains(cli.cfg.ASNExcludeList, player.ASN) {
+				included = append(included, player)
+			}
+		}
+		players = included
+	}
+
+	if cli.cfg.FlagVPNOnly {
+		flagged := make(PlayerExtendedList, 0, len(players))
+		for _, player := range players {
+			if player.VPN {
+				flagged = append(flagged, player)
+			}
+		}
+		players = flagged
+	}
+
+	if cli.cfg.WhereProgram != nil {
+		players = cli.where(players)
+	}
+
+	if cli.script != nil {
+		players = cli.script.Apply(players)
+	}
+
+	if cli.cfg.Online && len(players) > 0 {
+		cli.lookupOnline(ctx, players)
+	}
+	return players
+}
+
+// messageSizeMatches reports whether the message length in characters and the number of
+// whitespace separated words are within the configured limits.
+func (cli *CLI) messageSizeMatches(text string) bool {
+	length := utf8.RuneCountInString(strings.TrimSpace(text))
+	if length < cli.cfg.MinMessageLength {
+		return false
+	}
+	if cli.cfg.MaxMessageLength > 0 && length > cli.cfg.MaxMessageLength {
+		return false
+	}
+	if cli.cfg.WordCount == "" {
+		return true
+	}
+
+	words := len(strings.Fields(text))
+	if words < cli.cfg.MinWords {
+		return false
+	}
+	return cli.cfg.MaxWords == 0 || words <= cli.cfg.MaxWords
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+var (
+	// category and message of ddnet (2024-01-01 12:00:00 I chat: ...) and vanilla ([65a2b3c4][chat]: ...) log lines
+	logLineRegexp = regexp.MustCompile(`(?:^|[\] ])\[?([a-z_]+)\]?: (.*)$`)
+	// id, nick, chat message
+	chatMessageRegexp = regexp.MustCompile(`^(\d+):-?\d+:(.+): (.+)$`)
+	// id and nick of the first player of a game event, e.g. kill killer='0:nameless tee' or killer='0:-1:nameless tee'
+	eventPlayerRegexp = regexp.MustCompile(`'(\d+):(?:-?\d+:)?([^']*)'`)
+	// id of server events, e.g. ClientID=0 or cid=0
+	eventClientIDRegexp = regexp.MustCompile(`(?i)\b(?:ClientID|cid|id)=(\d+)`)
+	// map of vanilla ([datafile]: loading. filename='maps/ctf5.map'), older (datafile: loading. filename='maps/ctf5.map')
+	// and ddnet (maps/Kobra 4.map crc is 1a2b3c4d) map changes
+	mapLineRegexp = regexp.MustCompile(`(?:\[?datafile\]?: loading\. filename='([^']+)'|: (maps/.+\.map) crc is )`)
+	// nick and time of ddnet race finishes, e.g. *** 'nameless tee' finished in: 1 minute(s) 23.45 second(s) or finished in: 01:23.45
+	finishRegexp = regexp.MustCompile(`^\*\*\* '?(.+?)'? finished in: (?:(\d+) minute\(s\) ([\d.]+) second\(s\)|(?:(\d+):)?(\d+):([\d.]+))`)
+	// id and announced client version, e.g. ddnet: cid=0 version=18020 or ClientID=0 version='DDNet 18.2'
+	clientVersionRegexp = regexp.MustCompile(`(?i)\b(?:cid|ClientID)=(\d+)\b.*?\bversion[=: ]\s*(?:'([^']*)'|"([^"]*)"|(\S+))`)
+)
+
+// logEvent is a log line of a player.
+type logEvent struct {
+	Type     string
+	ID       int
+	Nickname string
+	// IP and Port are only known for connect events of join lines.
+	IP   string
+	Port int
+	// Text is the chat message or the message of the log line without its category.
+	Text string
+	// Sanction is set for ban events.
+	Sanction *sanction
+	// FinishTime is set for finish events.
+	FinishTime time.Duration
+}
+
+// parseEvent classifies the log line and returns the player it is about.
+// Lines that cannot be attributed to a player are not events.
+func parseEvent(line string) (logEvent, bool) {
+	m := logLineRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return logEvent{}, false
+	}
+	category, message := m[1], m[2]
+
+	if category == "chat" || category == "teamchat" {
+		chat := chatMessageRegexp.FindStringSubmatch(message)
+		if chat == nil {
+			// finishes are announced by the server, the id is resolved by nickname
+			if nickname, finishTime, ok := parseFinish(message); ok {
+				return logEvent{
+					Type:       config.EventFinish,
+					ID:         -1,
+					Nickname:   nickname,
+					Text:       message,
+					FinishTime: finishTime,
+				}, true
+			}
+			return logEvent{}, false
+		}
+		id, err := strconv.Atoi(chat[1])
+		if err != nil {
+			return logEvent{}, false
+		}
+		return logEvent{
+			Type:     config.EventChat,
+			ID:       id,
+			Nickname: chat[2],
+			Text:     chat[3],
+		}, true
+	}
+
+	if s, ok := parseSanction(message); ok {
+		return logEvent{
+			Type:     config.EventBan,
+			ID:       s.TargetID,
+			IP:       s.TargetIP,
+			Text:     message,
+			Sanction: s,
+		}, true
+	}
+
+	event := logEvent{
+		Type: eventType(category, message),
+		Text: message,
+	}
+	if id, ip, port, ok := parseJoinLine(line); ok {
+		event.Type = config.EventConnect
+		event.ID = id
+		event.IP = ip
+		event.Port = port
+		if p := playerzCatchJoinRegex.FindStringSubmatch(line); p != nil {
+			event.Nickname = p[5]
+		}
+		return event, true
+	}
+
+	if p := eventPlayerRegexp.FindStringSubmatch(message); p != nil {
+		id, err := strconv.Atoi(p[1])
+		if err != nil {
+			return logEvent{}, false
+		}
+		event.ID = id
+		event.Nickname = p[2]
+		return event, true
+	}
+	if p := eventClientIDRegexp.FindStringSubmatch(message); p != nil {
+		id, err := strconv.Atoi(p[1])
+		if err != nil {
+			return logEvent{}, false
+		}
+		event.ID = id
+		return event, true
+	}
+	return logEvent{}, false
+}
+
+// eventType classifies a log line that is not a chat message.
+func eventType(category, message string) string {
+	lMessage := strings.ToLower(message)
+	switch {
+	case strings.HasPrefix(message, "kill "):
+		return config.EventKill
+	case strings.HasPrefix(message, "pickup "):
+		return config.EventPickup
+	case strings.HasPrefix(message, "join "),
+		strings.HasPrefix(message, "leave "),
+		strings.Contains(lMessage, "client dropped"),
+		strings.Contains(lMessage, "player has left the game"):
+		return config.EventConnect
+	case category == "vote", strings.Contains(lMessage, "vote"):
+		return config.EventVote
+	case category == "rcon", strings.Contains(lMessage, "rcon"), strings.Contains(lMessage, "authed"):
+		return config.EventRcon
+	default:
+		return config.EventOther
+	}
+}
+
+// eventName is the event of a match, chat messages are the default and have none.
+func eventName(eventType string) string {
+	if eventType == config.EventChat {
+		return ""
+	}
+	return eventType
+}
+
+// parseMapLine returns the name of the map that the server changed to.
+func parseMapLine(line string) (string, bool) {
+	m := mapLineRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return "", false
+	}
+	file := m[1]
+	if file == "" {
+		file = m[2]
+	}
+	return strings.TrimSuffix(path.Base(file), ".map"), true
+}
+
+// parseClientVersion returns the client id and the version that the client announced.
+func parseClientVersion(message string) (int, string, bool) {
+	m := clientVersionRegexp.FindStringSubmatch(message)
+	if m == nil {
+		return 0, "", false
+	}
+	id, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, "", false
+	}
+	for _, version := range m[2:] {
+		if version != "" {
+			return id, version, true
+		}
+	}
+	return 0, "", false
+}
+
+// parseFinish returns the nickname and the time of a race finish.
+func parseFinish(message string) (string, time.Duration, bool) {
+	m := finishRegexp.FindStringSubmatch(message)
+	if m == nil {
+		return "", 0, false
+	}
+
+	var hours, minutes, seconds string
+	if m[3] != "" {
+		minutes, seconds = m[2], m[3]
+	} else {
+		hours, minutes, seconds = m[4], m[5], m[6]
+	}
+
+	var d time.Duration
+	for _, part := range []struct {
+		value string
+		unit  time.Duration
+	}{
+		{hours, time.Hour},
+		{minutes, time.Minute},
+		{seconds, time.Second},
+	} {
+		if part.value == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(part.value, 64)
+		if err != nil {
+			return "", 0, false
+		}
+		d += time.Duration(f * float64(part.unit))
+	}
+	return m[1], d, true
+}
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestParseMapLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   string
+		wantOK bool
+	}{
+		{
+			line:   "2024-01-01 12:00:00 I datafile: loading. filename='maps/ctf5.map'",
+			want:   "ctf5",
+			wantOK: true,
+		},
+		{
+			line:   "[2024-01-01 12:00:00][datafile]: loading. filename='maps/dm1.map'",
+			want:   "dm1",
+			wantOK: true,
+		},
+		{
+			line:   "2024-01-01 12:00:00 I server: maps/Kobra 4.map crc is 1a2b3c4d",
+			want:   "Kobra 4",
+			wantOK: true,
+		},
+		{
+			line: "2024-01-01 12:00:00 I chat: 0:-2:nameless tee: datafile: loading",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, ok := parseMapLine(tt.line)
+			if got != tt.want || ok != tt.wantOK {
+				t.Fatalf("parseMapLine() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
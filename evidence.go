@@ -0,0 +1,417 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/archive"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// NewExportEvidenceCmd bundles matches together with their context and the checksums
+// of their source files into a zip file that can be verified against the original logs.
+func NewExportEvidenceCmd(ctx context.Context) *cobra.Command {
+	cfg := config.NewEvidenceConfig()
+
+	cmd := &cobra.Command{
+		Use:   "export-evidence",
+		Short: "bundle matched lines with context, byte ranges and checksums of the source files into a zip file",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
+		cmd.SilenceUsage = true
+
+		r := cmd.InOrStdin()
+		if cfg.Input != "-" {
+			f, err := os.Open(cfg.Input)
+			if err != nil {
+				return fmt.Errorf("failed to open input: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		players, err := decodePlayerExtendedList(r)
+		if err != nil {
+			return fmt.Errorf("failed to parse json input, expected extended json results: %w", err)
+		}
+
+		manifest, err := collectEvidence(ctx, players, cfg.Context)
+		if err != nil {
+			return err
+		}
+		return writeEvidence(cfg.Output, manifest)
+	}
+	return cmd
+}
+
+// evidenceManifest describes the content of an evidence package.
+type evidenceManifest struct {
+	Created      time.Time         `json:"created"`
+	ContextLines int               `json:"context_lines"`
+	Sources      []*evidenceSource `json:"sources"`
+	Matches      []*evidenceMatch  `json:"matches"`
+}
+
+// evidenceSource is a log file that contains matches.
+type evidenceSource struct {
+	File    string `json:"file"`
+	Archive string `json:"archive,omitempty"`
+	Member  string `json:"member,omitempty"`
+	// Size and SHA256 are the size and checksum of the log file, the extracted member in case of archives.
+	Size          int64  `json:"size"`
+	SHA256        string `json:"sha256"`
+	ArchiveSHA256 string `json:"archive_sha256,omitempty"`
+}
+
+// evidenceMatch is a single match together with the location of its context in the source file.
+type evidenceMatch struct {
+	PlayerExtended
+	// ByteRange is the half open byte range of the matched line in the source file.
+	ByteRange [2]int64 `json:"byte_range"`
+	// ContextLineRange and ContextByteRange cover the matched line and its context.
+	ContextLineRange [2]int   `json:"context_line_range"`
+	ContextByteRange [2]int64 `json:"context_byte_range"`
+	// Context are the lines before and after the matched line, so that they can be rendered without parsing the evidence file.
+	Context evidenceContext `json:"context"`
+	// Evidence is the file in the package that contains the context, EvidenceSHA256 its checksum.
+	Evidence       string `json:"evidence"`
+	EvidenceSHA256 string `json:"evidence_sha256"`
+
+	context []evidenceLine
+}
+
+type evidenceLine struct {
+	number int
+	offset int64
+	// raw is the line including its line ending
+	raw string
+}
+
+// evidenceContext are the context lines of a match in the order of the source file.
+type evidenceContext struct {
+	Before []evidenceContextLine `json:"before"`
+	After  []evidenceContextLine `json:"after"`
+}
+
+// evidenceContextLine is a single context line without its line ending.
+type evidenceContextLine struct {
+	Line   int   `json:"line"`
+	Offset int64 `json:"offset"`
+	// Time is the timestamp of the line, nil when the log format has none.
+	Time *time.Time `json:"time,omitempty"`
+	Text string     `json:"text"`
+}
+
+func newEvidenceContextLine(l evidenceLine) evidenceContextLine {
+	cl := evidenceContextLine{
+		Line:   l.number,
+		Offset: l.offset,
+		Text:   strings.TrimRight(l.raw, "\r\n"),
+	}
+	if t, ok := parseLogTime(cl.Text); ok {
+		cl.Time = &t
+	}
+	return cl
+}
+
+// collectEvidence reads the context of all matches from their source files and checksums the source files.
+func collectEvidence(ctx context.Context, players PlayerExtendedList, contextLines int) (*evidenceManifest, error) {
+	manifest := &evidenceManifest{
+		Created:      time.Now().UTC(),
+		ContextLines: contextLines,
+		Sources:      make([]*evidenceSource, 0, 8),
+		Matches:      make([]*evidenceMatch, 0, len(players)),
+	}
+
+	// matches grouped by source file and by archive
+	matchesBySource := make(map[string][]*evidenceMatch, 8)
+	sourcesByArchive := make(map[string][]*evidenceSource, 1)
+	for idx, player := range players {
+		if player.File == "" || player.Line <= 0 {
+			return nil, fmt.Errorf("match %d has no position, scan with -e -o json --with-position", idx)
+		}
+
+		match := &evidenceMatch{
+			PlayerExtended: player,
+			Evidence:       fmt.Sprintf("matches/%06d.txt", idx+1),
+		}
+		manifest.Matches = append(manifest.Matches, match)
+
+		if _, found := matchesBySource[player.File]; !found {
+			source := &evidenceSource{
+				File:    player.File,
+				Archive: player.Archive,
+				Member:  player.Member,
+			}
+			manifest.Sources = append(manifest.Sources, source)
+			if source.Archive != "" {
+				sourcesByArchive[source.Archive] = append(sourcesByArchive[source.Archive], source)
+			}
+		}
+		matchesBySource[player.File] = append(matchesBySource[player.File], match)
+	}
+
+	for _, source := range manifest.Sources {
+		if source.Archive != "" {
+			continue
+		}
+
+		err := func() error {
+			f, err := os.Open(source.File)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return readEvidence(f, source, matchesBySource[source.File], contextLines)
+		}()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read evidence of %s: %w", source.File, err)
+		}
+	}
+
+	for archivePath, sources := range sourcesByArchive {
+		archiveSum, err := sha256File(archivePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to checksum archive %s: %w", archivePath, err)
+		}
+
+		err = archive.Walk(ctx, archivePath, func(path string, info fs.FileInfo, r io.Reader, err error) error {
+			if err != nil {
+				return err
+			}
+
+			idx := slices.IndexFunc(sources, func(s *evidenceSource) bool {
+				return s.Member == path
+			})
+			if idx < 0 || !info.Mode().IsRegular() {
+				return nil
+			}
+
+			source := sources[idx]
+			source.ArchiveSHA256 = archiveSum
+			err = readEvidence(r, source, matchesBySource[source.File], contextLines)
+			if err != nil {
+				return fmt.Errorf("failed to read evidence of %s: %w", source.File, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk archive %s: %w", archivePath, err)
+		}
+
+		for _, source := range sources {
+			if source.SHA256 == "" {
+				return nil, fmt.Errorf("archive %s does not contain %s", archivePath, source.Member)
+			}
+		}
+	}
+	return manifest, nil
+}
+
+// readEvidence checksums the source file and collects the context lines of its matches in a single pass.
+func readEvidence(r io.Reader, source *evidenceSource, matches []*evidenceMatch, contextLines int) error {
+	slices.SortStableFunc(matches, func(a, b *evidenceMatch) int {
+		return a.Line - b.Line
+	})
+
+	h := sha256.New()
+	br := bufio.NewReader(io.TeeReader(r, h))
+
+	var (
+		offset int64
+		number int
+		// index of the first match whose context did not end yet
+		next int
+	)
+	for {
+		raw, err := br.ReadString('\n')
+		if len(raw) > 0 {
+			number++
+			for next < len(matches) && matches[next].Line+contextLines < number {
+				next++
+			}
+			for _, match := range matches[next:] {
+				if match.Line-contextLines > number {
+					break
+				}
+				match.context = append(match.context, evidenceLine{number: number, offset: offset, raw: raw})
+			}
+			offset += int64(len(raw))
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	source.Size = offset
+	source.SHA256 = hex.EncodeToString(h.Sum(nil))
+
+	for _, match := range matches {
+		err := match.locate()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// locate verifies that the matched line is still located at the recorded position
+// and computes the byte ranges of the match and its context.
+func (m *evidenceMatch) locate() error {
+	idx := slices.IndexFunc(m.context, func(l evidenceLine) bool {
+		return l.number == m.Line
+	})
+	if idx < 0 {
+		return fmt.Errorf("line %d does not exist, the file changed since the scan", m.Line)
+	}
+
+	line := m.context[idx]
+	if line.offset != m.Offset || !m.matchesLine(line.raw) {
+		return fmt.Errorf("line %d does not contain the match at offset %d, the file changed since the scan", m.Line, m.Offset)
+	}
+
+	first, last := m.context[0], m.context[len(m.context)-1]
+	m.ByteRange = [2]int64{line.offset, line.offset + int64(len(line.raw))}
+	m.ContextLineRange = [2]int{first.number, last.number}
+	m.ContextByteRange = [2]int64{first.offset, last.offset + int64(len(last.raw))}
+
+	m.Context = evidenceContext{
+		Before: make([]evidenceContextLine, 0, idx),
+		After:  make([]evidenceContextLine, 0, len(m.context)-idx-1),
+	}
+	for _, l := range m.context[:idx] {
+		m.Context.Before = append(m.Context.Before, newEvidenceContextLine(l))
+	}
+	for _, l := range m.context[idx+1:] {
+		m.Context.After = append(m.Context.After, newEvidenceContextLine(l))
+	}
+	return nil
+}
+
+// matchesLine returns true in case the raw line is the matched line.
+// The text of the match may differ from the line, e.g. after invalid UTF-8 was replaced or a script
+// rewrote it, so the checksum of the line is compared in case the match has one.
+func (m *evidenceMatch) matchesLine(raw string) bool {
+	// the checksum of the line does not contain the line ending, like bufio.ScanLines
+	content := strings.TrimSuffix(strings.TrimSuffix(raw, "\n"), "\r")
+	if m.LineSHA256 != "" {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:]) == m.LineSHA256
+	}
+	return strings.Contains(strings.ToValidUTF8(content, "\uFFFD"), m.Text)
+}
+
+// render returns the human readable evidence file of the match, the matched line is marked with >.
+func (m *evidenceMatch) render() []byte {
+	var sb strings.Builder
+	if m.Query != "" {
+		fmt.Fprintf(&sb, "query: %s\n", m.Query)
+	}
+	fmt.Fprintf(&sb, "nickname: %s\n", m.Nickname)
+	fmt.Fprintf(&sb, "ip: %s\n", m.IP)
+	fmt.Fprintf(&sb, "file: %s\n", m.File)
+	fmt.Fprintf(&sb, "line: %d\n", m.Line)
+	fmt.Fprintf(&sb, "bytes: %d-%d\n", m.ByteRange[0], m.ByteRange[1])
+	sb.WriteByte('\n')
+
+	width := len(fmt.Sprint(m.ContextLineRange[1]))
+	for _, line := range m.context {
+		marker := ' '
+		if line.number == m.Line {
+			marker = '>'
+		}
+		fmt.Fprintf(&sb, "%c %*d | %s\n", marker, width, line.number, strings.TrimRight(line.raw, "\r\n"))
+	}
+	return []byte(sb.String())
+}
+
+// writeEvidence writes the evidence files of all matches and the manifest into a new zip file.
+func writeEvidence(path string, manifest *evidenceManifest) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create evidence package: %w", err)
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+		if err != nil {
+			_ = os.Remove(path)
+		}
+	}()
+
+	zw := zip.NewWriter(f)
+	for _, match := range manifest.Matches {
+		data := match.render()
+		sum := sha256.Sum256(data)
+		match.EvidenceSHA256 = hex.EncodeToString(sum[:])
+
+		err = writeZipFile(zw, match.Evidence, manifest.Created, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	err = writeZipFile(zw, "manifest.json", manifest.Created, data)
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeZipFile(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to evidence package: %w", name, err)
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to write %s to evidence package: %w", name, err)
+	}
+	return nil
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
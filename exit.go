@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	exitCodeNoMatches = 1
+	exitCodeError     = 2
+)
+
+// ErrNoMatches is returned in grep compatible exit code mode when the scan did not find anything.
+var ErrNoMatches = errors.New("no matches found")
+
+// ExitCodeError defines the exit code of the process.
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
+// grepExitCode maps the outcome of a scan to grep compatible exit codes
+// in case they are enabled: 0 when matches were found, 1 when no matches were found and 2 on errors.
+func (cli *CLI) grepExitCode(cmd *cobra.Command, matches int, err error) error {
+	if !cli.cfg.GrepExitCodes {
+		return err
+	}
+
+	if err != nil {
+		return &ExitCodeError{Code: exitCodeError, Err: err}
+	}
+
+	if matches == 0 {
+		// not finding anything is not worth an error message
+		cmd.SilenceErrors = true
+		return &ExitCodeError{Code: exitCodeNoMatches, Err: ErrNoMatches}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/jxsl13/twlog-who-said/archive"
+)
+
+const (
+	FileErrorUnreadable  = "unreadable"
+	FileErrorUndecodable = "undecodable"
+	FileErrorTooLarge    = "too_large"
+	FileErrorTimeout     = "timeout"
+)
+
+// FileError is the machine-readable error of a single file or archive member,
+// which allows to retry exactly the failed files.
+type FileError struct {
+	File string `json:"file"`
+	// Archive and Member are set when the file is located inside of an archive.
+	Archive string `json:"archive,omitempty"`
+	Member  string `json:"member,omitempty"`
+	// Kind is one of unreadable, undecodable, too_large or timeout.
+	Kind  string `json:"kind"`
+	Error string `json:"error"`
+}
+
+// newFileError classifies the error of a file, errors that cannot be classified are of the fallback kind.
+func newFileError(file string, err error, fallback string) FileError {
+	return FileError{
+		File:  file,
+		Kind:  fileErrorKind(err, fallback),
+		Error: err.Error(),
+	}
+}
+
+// newMemberError classifies the error of a file inside of an archive.
+func newMemberError(archiveFile, member string, err error, fallback string) FileError {
+	fe := newFileError(archivePath(archiveFile, member), err, fallback)
+	fe.Archive = archiveFile
+	fe.Member = member
+	return fe
+}
+
+func fileErrorKind(err error, fallback string) string {
+	var (
+		corrupt flate.CorruptInputError
+		pathErr *fs.PathError
+	)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return FileErrorTimeout
+	case errors.Is(err, bufio.ErrTooLong), errors.Is(err, archive.ErrTempSpaceExceeded):
+		return FileErrorTooLarge
+	case errors.Is(err, archive.ErrUnsupportedArchive),
+		errors.Is(err, gzip.ErrHeader), errors.Is(err, gzip.ErrChecksum),
+		errors.Is(err, zip.ErrFormat), errors.Is(err, zip.ErrChecksum), errors.Is(err, zip.ErrAlgorithm),
+		errors.Is(err, tar.ErrHeader), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.As(err, &corrupt):
+		return FileErrorUndecodable
+	case errors.As(err, &pathErr):
+		return FileErrorUnreadable
+	}
+	return fallback
+}
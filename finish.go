@@ -0,0 +1,94 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// PlayerFinish is the fastest race finish of a player on a map.
+type PlayerFinish struct {
+	Map      string `json:"map"`
+	Nickname string `json:"nickname"`
+	IP       string `json:"ip"`
+	// Time is the fastest race time in seconds.
+	Time     float64 `json:"time"`
+	Finishes int     `json:"finishes"`
+	// File and Date locate the fastest finish in order to investigate the chat around it.
+	File string     `json:"file"`
+	Line int        `json:"line,omitempty"`
+	Date *time.Time `json:"date,omitempty"`
+}
+
+func (p PlayerFinish) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "map=%q time=%.2fs finishes=%d name=%s ip=%s file=%s", p.Map, p.Time, p.Finishes, p.Nickname, p.IP, p.File)
+	if p.Line > 0 {
+		fmt.Fprintf(&sb, ":%d", p.Line)
+	}
+	if p.Date != nil {
+		sb.WriteString(" date=" + p.Date.Format(time.RFC3339))
+	}
+	return sb.String()
+}
+
+type PlayerFinishList []PlayerFinish
+
+func (p PlayerFinishList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(p) * 128)
+	for _, finish := range p {
+		sb.WriteString(finish.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// ToFinishList aggregates the finishes per map and nickname, sorted by map and fastest time.
+func (p PlayerExtendedList) ToFinishList() PlayerFinishList {
+	type finishKey struct {
+		gameMap, nickname string
+	}
+	byKey := make(map[finishKey]*PlayerFinish, len(p))
+	for _, player := range p {
+		if player.Event != config.EventFinish {
+			continue
+		}
+
+		key := finishKey{player.Map, player.Nickname}
+		finish, found := byKey[key]
+		if !found {
+			finish = &PlayerFinish{
+				Map:      player.Map,
+				Nickname: player.Nickname,
+			}
+			byKey[key] = finish
+		}
+
+		finish.Finishes++
+		if finish.Finishes == 1 || player.FinishTime < finish.Time {
+			finish.Time = player.FinishTime
+			finish.IP = player.IP
+			finish.File = player.File
+			finish.Line = player.Line
+			finish.Date = player.Time
+		}
+	}
+
+	finishes := make(PlayerFinishList, 0, len(byKey))
+	for _, finish := range byKey {
+		finishes = append(finishes, *finish)
+	}
+	slices.SortFunc(finishes, func(a, b PlayerFinish) int {
+		return cmp.Or(
+			strings.Compare(a.Map, b.Map),
+			cmp.Compare(a.Time, b.Time),
+			strings.Compare(a.Nickname, b.Nickname),
+		)
+	})
+	return finishes
+}
@@ -0,0 +1,54 @@
+//go:build !windows
+
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"syscall"
+)
+
+// deviceID returns the id of the device that contains the file.
+func deviceID(fi fs.FileInfo) (uint64, bool) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	// Dev is not an uint64 on all platforms
+	return uint64(stat.Dev), true
+}
+
+// extendedPath is only required on windows.
+func extendedPath(path string) string {
+	return path
+}
+
+// trimExtendedPath is only required on windows.
+func trimExtendedPath(path string) string {
+	return path
+}
+
+// openPath is only required on windows, as all file names can be opened.
+func openPath(path string) string {
+	return path
+}
+
+// realPath resolves all symlinks of path.
+func realPath(path string) (string, error) {
+	return filepath.EvalSymlinks(path)
+}
+
+// transientErrnos are the errors of network file systems after which a file is read again.
+var transientErrnos = []syscall.Errno{
+	syscall.EIO,
+	syscall.EAGAIN,
+	syscall.EINTR,
+	syscall.ETIMEDOUT,
+	syscall.ESTALE,
+	syscall.ECONNRESET,
+	syscall.ECONNABORTED,
+	syscall.ENOTCONN,
+	syscall.EHOSTDOWN,
+	syscall.EHOSTUNREACH,
+	syscall.ENETUNREACH,
+}
@@ -0,0 +1,118 @@
+//go:build windows
+
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	extendedPathPrefix    = `\\?\`
+	extendedUNCPathPrefix = `\\?\UNC\`
+)
+
+// reservedNames are device names that cannot be used as file names, regardless of the extension.
+var reservedNames = []string{
+	"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9", "COM¹", "COM²", "COM³",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9", "LPT¹", "LPT²", "LPT³",
+}
+
+// deviceID is not supported on windows, which disables the file system boundary check.
+func deviceID(fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// extendedPath returns the absolute path with the \\?\ prefix, which lifts the MAX_PATH limit
+// of 260 characters and disables the special handling of reserved device names.
+func extendedPath(path string) string {
+	if strings.HasPrefix(path, extendedPathPrefix) {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		// network share, e.g. \\server\share\logs
+		return extendedUNCPathPrefix + abs[2:]
+	}
+	return extendedPathPrefix + abs
+}
+
+// trimExtendedPath removes the \\?\ prefix of extended paths.
+func trimExtendedPath(path string) string {
+	if rest, ok := strings.CutPrefix(path, extendedUNCPathPrefix); ok {
+		return `\\` + rest
+	}
+	return strings.TrimPrefix(path, extendedPathPrefix)
+}
+
+// isReservedName returns true for file names that cannot be opened without the \\?\ prefix,
+// e.g. nul.log copied from a linux server, or names that end with a dot or space.
+func isReservedName(name string) bool {
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return true
+	}
+	base, _, _ := strings.Cut(name, ".")
+	base = strings.TrimRight(base, " ")
+	for _, reserved := range reservedNames {
+		if strings.EqualFold(base, reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// openPath returns the path that files are opened with, which is the extended path
+// in case any element of the path is a reserved name.
+func openPath(path string) string {
+	for _, element := range strings.FieldsFunc(trimExtendedPath(path), func(r rune) bool { return r == '\\' || r == '/' }) {
+		if isReservedName(element) {
+			return extendedPath(path)
+		}
+	}
+	return path
+}
+
+// realPath resolves all symlinks and junctions of path. Unlike filepath.EvalSymlinks it supports long paths.
+func realPath(path string) (string, error) {
+	name, err := windows.UTF16PtrFromString(extendedPath(path))
+	if err != nil {
+		return "", &fs.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	// directories can only be opened with backup semantics
+	h, err := windows.CreateFile(name, 0,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return "", &fs.PathError{Op: "open", Path: path, Err: err}
+	}
+	defer windows.CloseHandle(h)
+
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	n, err := windows.GetFinalPathNameByHandle(h, &buf[0], uint32(len(buf)), 0)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: path, Err: err}
+	}
+	return trimExtendedPath(windows.UTF16ToString(buf[:n])), nil
+}
+
+// transientErrnos are the errors of network shares after which a file is read again.
+var transientErrnos = []syscall.Errno{
+	windows.ERROR_BAD_NETPATH,
+	windows.ERROR_NETWORK_BUSY,
+	windows.ERROR_DEV_NOT_EXIST,
+	windows.ERROR_UNEXP_NET_ERR,
+	windows.ERROR_NETNAME_DELETED,
+	windows.ERROR_SEM_TIMEOUT,
+	windows.ERROR_VC_DISCONNECTED,
+	windows.ERROR_NETWORK_UNREACHABLE,
+	windows.ERROR_CONNECTION_ABORTED,
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/archive"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// NewGDPRCmd finds all occurrences of a player in the log files
+// and optionally writes redacted copies of the affected files.
+func NewGDPRCmd(ctx context.Context) *cobra.Command {
+	cfg := config.NewGDPRConfig()
+
+	cmd := &cobra.Command{
+		Use:   "gdpr",
+		Short: "find all occurrences of a nickname or IP and optionally write redacted copies of the affected log files",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		searchDir, err := filepath.Abs(cfg.SearchDir)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path of search dir: %w", err)
+		}
+
+		files, err := listFiles(ctx, searchDir, cfg.FileRegexp)
+		if err != nil {
+			return err
+		}
+		var archives []string
+		if cfg.ArchiveRegexp != nil {
+			archives, err = listFiles(ctx, searchDir, cfg.ArchiveRegexp)
+			if err != nil {
+				return err
+			}
+			// like in the scan, archives are never searched as log files
+			files = slices.DeleteFunc(files, func(file string) bool {
+				_, found := slices.BinarySearch(archives, file)
+				return found
+			})
+		}
+
+		finder := newOccurrenceFinder(cfg.Name, cfg.IP)
+		affected, lines := 0, 0
+		for _, file := range files {
+			err = checkShutDown(ctx)
+			if err != nil {
+				return err
+			}
+
+			n, err := finder.reportFile(cmd.OutOrStdout(), file)
+			if err != nil {
+				return fmt.Errorf("failed to search %s: %w", file, err)
+			}
+			if n == 0 {
+				continue
+			}
+			affected++
+			lines += n
+
+			if cfg.Output == "" {
+				continue
+			}
+
+			rel, err := filepath.Rel(searchDir, trimExtendedPath(file))
+			if err != nil {
+				return err
+			}
+			err = finder.redactCopy(file, filepath.Join(cfg.Output, rel), cfg.Replacement)
+			if err != nil {
+				return fmt.Errorf("failed to write redacted copy of %s: %w", file, err)
+			}
+		}
+
+		for _, file := range archives {
+			err = checkShutDown(ctx)
+			if err != nil {
+				return err
+			}
+
+			members, n, err := finder.reportArchive(ctx, cmd.OutOrStdout(), file, cfg.FileRegexp)
+			if errors.Is(err, archive.ErrUnsupportedArchive) {
+				slog.Warn("skipping unsupported archive, it was not searched", "archive", file)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to search %s: %w", file, err)
+			}
+			if n == 0 {
+				continue
+			}
+			affected += members
+			lines += n
+
+			if cfg.Output != "" {
+				// archives cannot be redacted in place, their affected files must be replaced manually
+				slog.Warn("no redacted copy written for archive", "archive", file, "affected_files", members)
+			}
+		}
+		slog.Info("found occurrences", "files", len(files), "archives", len(archives), "affected_files", affected, "lines", lines)
+		return nil
+	}
+	return cmd
+}
+
+// occurrenceFinder finds a nickname and an IP in log lines.
+type occurrenceFinder struct {
+	name string
+	ip   string
+	ipv6 bool
+}
+
+func newOccurrenceFinder(name, ip string) *occurrenceFinder {
+	f := &occurrenceFinder{
+		name: name,
+		ip:   ip,
+	}
+	if ip != "" {
+		f.ipv6 = netip.MustParseAddr(ip).Is6()
+	}
+	return f
+}
+
+// find returns the sorted and merged byte ranges of all occurrences in the line.
+func (f *occurrenceFinder) find(line string) [][2]int {
+	spans := make([][2]int, 0, 2)
+	if f.name != "" {
+		spans = appendIndices(spans, line, f.name, nil)
+	}
+	if f.ip != "" {
+		spans = appendIndices(spans, line, f.ip, func(start, end int) bool {
+			// 1.2.3.4 must not match 11.2.3.4 or 1.2.3.45
+			return isIPBoundary(line, start-1, -1, f.ipv6) && isIPBoundary(line, end, 1, f.ipv6)
+		})
+	}
+	if len(spans) < 2 {
+		return spans
+	}
+
+	slices.SortFunc(spans, func(a, b [2]int) int {
+		return a[0] - b[0]
+	})
+	merged := spans[:1]
+	for _, span := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if span[0] <= last[1] {
+			last[1] = max(last[1], span[1])
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// appendIndices appends the ranges of all non overlapping occurrences of substr in s that are accepted.
+func appendIndices(spans [][2]int, s, substr string, accept func(start, end int) bool) [][2]int {
+	offset := 0
+	for {
+		idx := strings.Index(s[offset:], substr)
+		if idx < 0 {
+			return spans
+		}
+		start, end := offset+idx, offset+idx+len(substr)
+		if accept == nil || accept(start, end) {
+			spans = append(spans, [2]int{start, end})
+			offset = end
+			continue
+		}
+		offset = start + 1
+	}
+}
+
+// isIPBoundary returns true in case the character at idx cannot be part of the IP.
+// dir is -1 for the character before and 1 for the character after the IP.
+func isIPBoundary(s string, idx, dir int, ipv6 bool) bool {
+	if idx < 0 || idx >= len(s) {
+		return true
+	}
+
+	c := s[idx]
+	switch {
+	case isDigit(c):
+		return false
+	case ipv6:
+		return c != ':' && !('a' <= c && c <= 'f') && !('A' <= c && c <= 'F')
+	case c == '.':
+		// the end of a sentence is a boundary, another octet is not
+		next := idx + dir
+		return next < 0 || next >= len(s) || !isDigit(s[next])
+	default:
+		return true
+	}
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// reportFile writes every line of the file that contains an occurrence and returns the number of lines.
+func (f *occurrenceFinder) reportFile(w io.Writer, file string) (int, error) {
+	fd, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer fd.Close()
+	return f.report(w, file, fd)
+}
+
+// reportArchive writes every line of the files in the archive that match the file regex and contain an occurrence.
+// It returns the number of affected files and lines.
+func (f *occurrenceFinder) reportArchive(ctx context.Context, w io.Writer, file string, fileRegexp *regexp.Regexp) (members, lines int, err error) {
+	err = archive.Walk(ctx, file, func(path string, info fs.FileInfo, r io.Reader, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || !fileRegexp.MatchString(path) {
+			return nil
+		}
+
+		n, err := f.report(w, archivePath(file, path), r)
+		if err != nil {
+			return fmt.Errorf("failed to search archive file %s: %w", path, err)
+		}
+		if n > 0 {
+			members++
+			lines += n
+		}
+		return nil
+	})
+	return members, lines, err
+}
+
+// report writes every line of r that contains an occurrence prefixed with the name and returns the number of lines.
+func (f *occurrenceFinder) report(w io.Writer, name string, r io.Reader) (int, error) {
+	n := 0
+	lineNumber := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if len(f.find(line)) == 0 {
+			continue
+		}
+		n++
+		_, err := fmt.Fprintf(w, "%s:%d: %s\n", name, lineNumber, line)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, scanner.Err()
+}
+
+// redactCopy writes a copy of the file with all occurrences replaced, existing files are never overwritten.
+func (f *occurrenceFinder) redactCopy(file, target, replacement string) (err error) {
+	src, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// the copies keep the names of the source files, which may be reserved names on windows
+	target = extendedPath(target)
+	err = os.MkdirAll(filepath.Dir(target), 0o755)
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, dst.Close())
+		if err != nil {
+			_ = os.Remove(target)
+		}
+	}()
+
+	br := bufio.NewReader(src)
+	bw := bufio.NewWriter(dst)
+	for {
+		line, readErr := br.ReadString('\n')
+		if len(line) > 0 {
+			offset := 0
+			for _, span := range f.find(line) {
+				_, err = bw.WriteString(line[offset:span[0]] + replacement)
+				if err != nil {
+					return err
+				}
+				offset = span[1]
+			}
+			_, err = bw.WriteString(line[offset:])
+			if err != nil {
+				return err
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+const (
+	graphNodeName = "name"
+	graphNodeIP   = "ip"
+)
+
+// nameIPGraph connects the nicknames with the IPs they were seen with.
+// Nodes of the same cluster are connected directly or via other nodes or are lookalike nicknames,
+// e.g. a player that evades bans with several nicknames and IPs.
+type nameIPGraph struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+type graphNode struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Label   string `json:"label"`
+	Matches int    `json:"matches"`
+	Cluster int    `json:"cluster"`
+}
+
+type graphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// Weight is the number of matches of the nickname with the IP.
+	Weight int `json:"weight"`
+}
+
+func graphNodeID(nodeType, label string) string {
+	return nodeType + ":" + label
+}
+
+// newNameIPGraph builds the graph of the matches, nodes and edges are sorted.
+func newNameIPGraph(players PlayerExtendedList) nameIPGraph {
+	var (
+		nodes = make(map[string]*graphNode, len(players))
+		edges = make(map[[2]string]*graphEdge, len(players))
+		// parents of the union find that assigns the clusters
+		parents = make(map[string]string, len(players))
+	)
+	addNode := func(nodeType, label string) string {
+		id := graphNodeID(nodeType, label)
+		node, found := nodes[id]
+		if !found {
+			node = &graphNode{ID: id, Type: nodeType, Label: label}
+			nodes[id] = node
+			parents[id] = id
+		}
+		node.Matches++
+		return id
+	}
+	var root func(id string) string
+	root = func(id string) string {
+		if parents[id] != id {
+			parents[id] = root(parents[id])
+		}
+		return parents[id]
+	}
+
+	for _, p := range players {
+		if p.Nickname == "" || p.IP == "" {
+			continue
+		}
+		name := addNode(graphNodeName, p.Nickname)
+		ip := addNode(graphNodeIP, p.IP)
+
+		key := [2]string{name, ip}
+		edge, found := edges[key]
+		if !found {
+			edge = &graphEdge{Source: name, Target: ip}
+			edges[key] = edge
+		}
+		edge.Weight++
+
+		parents[root(name)] = root(ip)
+	}
+
+	// lookalike nicknames are aliases of the same player
+	aliases := make(map[string]string, len(nodes))
+	for id, node := range nodes {
+		if node.Type != graphNodeName {
+			continue
+		}
+		normalized := normalizeName(node.Label)
+		if alias, found := aliases[normalized]; found {
+			parents[root(id)] = root(alias)
+		} else {
+			aliases[normalized] = id
+		}
+	}
+
+	g := nameIPGraph{
+		Nodes: make([]graphNode, 0, len(nodes)),
+		Edges: make([]graphEdge, 0, len(edges)),
+	}
+	for _, node := range nodes {
+		g.Nodes = append(g.Nodes, *node)
+	}
+	slices.SortFunc(g.Nodes, func(a, b graphNode) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	// clusters are numbered in the order of their first node
+	clusters := make(map[string]int, len(nodes))
+	for idx := range g.Nodes {
+		r := root(g.Nodes[idx].ID)
+		cluster, found := clusters[r]
+		if !found {
+			cluster = len(clusters) + 1
+			clusters[r] = cluster
+		}
+		g.Nodes[idx].Cluster = cluster
+	}
+
+	for _, edge := range edges {
+		g.Edges = append(g.Edges, *edge)
+	}
+	slices.SortFunc(g.Edges, func(a, b graphEdge) int {
+		return cmp.Or(
+			cmp.Compare(a.Source, b.Source),
+			cmp.Compare(a.Target, b.Target),
+		)
+	})
+	return g
+}
+
+// printGraph writes the graph of the matches in the graph format.
+func (cli *CLI) printGraph(w io.Writer, format string, players PlayerExtendedList) error {
+	g := newNameIPGraph(players)
+	switch format {
+	case config.FormatDOT:
+		return g.writeDOT(w)
+	case config.FormatGraphML:
+		return g.writeGraphML(w)
+	case config.FormatJSON:
+		return cli.printJSON(w, g)
+	default:
+		// should never happen
+		return fmt.Errorf("unsupported graph format: %s", format)
+	}
+}
+
+// writeDOT writes the graph in the Graphviz DOT language, names are ellipses and IPs boxes.
+func (g nameIPGraph) writeDOT(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("graph twlog {\n")
+	for _, node := range g.Nodes {
+		shape := "ellipse"
+		if node.Type == graphNodeIP {
+			shape = "box"
+		}
+		fmt.Fprintf(&sb, "  %s [label=%s, shape=%s, cluster=%d];\n",
+			strconv.Quote(node.ID), strconv.Quote(node.Label), shape, node.Cluster)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&sb, "  %s -- %s [weight=%d, label=%d];\n",
+			strconv.Quote(edge.Source), strconv.Quote(edge.Target), edge.Weight, edge.Weight)
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// writeGraphML writes the graph in the GraphML format of e.g. Gephi.
+func (g nameIPGraph) writeGraphML(w io.Writer) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "matches", For: "node", AttrName: "matches", AttrType: "int"},
+			{ID: "cluster", For: "node", AttrName: "cluster", AttrType: "int"},
+			{ID: "weight", For: "edge", AttrName: "weight", AttrType: "int"},
+		},
+		Graph: graphMLGraph{
+			EdgeDefault: "undirected",
+			Nodes:       make([]graphMLNode, 0, len(g.Nodes)),
+			Edges:       make([]graphMLEdge, 0, len(g.Edges)),
+		},
+	}
+	for _, node := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: node.ID,
+			Data: []graphMLData{
+				{Key: "type", Value: node.Type},
+				{Key: "label", Value: node.Label},
+				{Key: "matches", Value: strconv.Itoa(node.Matches)},
+				{Key: "cluster", Value: strconv.Itoa(node.Cluster)},
+			},
+		})
+	}
+	for _, edge := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: edge.Source,
+			Target: edge.Target,
+			Data: []graphMLData{
+				{Key: "weight", Value: strconv.Itoa(edge.Weight)},
+			},
+		})
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode graphml: %w", err)
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// SortBy sorts the players by the given key, players with the same key keep the scan order.
+func (p PlayerExtendedList) SortBy(key string) PlayerExtendedList {
+	players := slices.Clone(p)
+	switch key {
+	case config.SortTime:
+		slices.SortStableFunc(players, func(a, b PlayerExtended) int {
+			return compareTime(a, b)
+		})
+	case config.SortName:
+		slices.SortStableFunc(players, func(a, b PlayerExtended) int {
+			return cmp.Or(
+				strings.Compare(a.Nickname, b.Nickname),
+				compareTime(a, b),
+			)
+		})
+	case config.SortIP:
+		slices.SortStableFunc(players, func(a, b PlayerExtended) int {
+			return cmp.Or(
+				compareIP(a.IP, b.IP),
+				compareTime(a, b),
+			)
+		})
+	case config.SortFile:
+		slices.SortStableFunc(players, func(a, b PlayerExtended) int {
+			return cmp.Or(
+				strings.Compare(a.File, b.File),
+				cmp.Compare(a.Line, b.Line),
+			)
+		})
+	}
+	return players
+}
+
+// compareTime sorts players without timestamps after the ones with timestamps.
+func compareTime(a, b PlayerExtended) int {
+	switch {
+	case a.Time == nil && b.Time == nil:
+		return 0
+	case a.Time == nil:
+		return 1
+	case b.Time == nil:
+		return -1
+	}
+	return a.Time.Compare(*b.Time)
+}
+
+// compareIP sorts IPs with and without port numerically, redacted IPs are compared as strings after all valid IPs.
+func compareIP(a, b string) int {
+	addrA, errA := parseIPOrAddrPort(a)
+	addrB, errB := parseIPOrAddrPort(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return 1
+	case errB != nil:
+		return -1
+	}
+	return addrA.Compare(addrB)
+}
+
+// groupKey returns the value of the player that it is grouped by.
+func (p PlayerExtended) groupKey(key string) string {
+	switch key {
+	case config.SortIP:
+		return p.IP
+	case config.SortFile:
+		return p.File
+	default:
+		return p.Nickname
+	}
+}
+
+// GroupBy splits the players into groups sorted by their key, the players of a group keep their order.
+func (p PlayerExtendedList) GroupBy(key string) []PlayerExtendedList {
+	byKey := make(map[string]PlayerExtendedList, 16)
+	for _, player := range p {
+		k := player.groupKey(key)
+		byKey[k] = append(byKey[k], player)
+	}
+
+	keys := make([]string, 0, len(byKey))
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+	if key == config.SortIP {
+		slices.SortFunc(keys, compareIP)
+	} else {
+		slices.Sort(keys)
+	}
+
+	groups := make([]PlayerExtendedList, 0, len(keys))
+	for _, k := range keys {
+		groups = append(groups, byKey[k])
+	}
+	return groups
+}
+
+// MatchGroup are the matches of a group, e.g. of a single nickname.
+type MatchGroup struct {
+	By  string `json:"by"`
+	Key string `json:"key"`
+	// Count is the number of matches after deduplication.
+	Count   int `json:"count"`
+	Matches any `json:"matches"`
+}
+
+func (g MatchGroup) String() string {
+	return fmt.Sprintf("# %s=%s matches=%d\n%s", g.By, g.Key, g.Count, g.Matches)
+}
+
+type MatchGroupList []MatchGroup
+
+func (l MatchGroupList) String() string {
+	var sb strings.Builder
+	for idx, g := range l {
+		if idx > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(g.String())
+	}
+	return sb.String()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// ignoreFileName is the name of the files in the search dir tree that exclude paths from scanning.
+// They use the gitignore syntax and apply to the paths relative to the directory they are located in.
+const ignoreFileName = ".twlogignore"
+
+// ignoreRules contains the ignore files that were found while walking the search dir.
+type ignoreRules struct {
+	root     string
+	matchers map[string]*ignore.GitIgnore
+}
+
+func newIgnoreRules(root string) *ignoreRules {
+	return &ignoreRules{
+		root:     root,
+		matchers: make(map[string]*ignore.GitIgnore),
+	}
+}
+
+// load reads the ignore file of dir, in case there is one.
+func (r *ignoreRules) load(dir string) error {
+	path := filepath.Join(extendedPath(dir), ignoreFileName)
+	matcher, err := ignore.CompileIgnoreFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	r.matchers[dir] = matcher
+	return nil
+}
+
+// Ignored returns true in case any ignore file of the parent directories of path excludes it.
+func (r *ignoreRules) Ignored(path string, isDir bool) bool {
+	if len(r.matchers) == 0 {
+		return false
+	}
+
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if matcher, ok := r.matchers[dir]; ok {
+			rel, err := filepath.Rel(dir, path)
+			if err == nil {
+				rel = filepath.ToSlash(rel)
+				if isDir {
+					rel += "/"
+				}
+				if matcher.MatchesPath(rel) {
+					return true
+				}
+			}
+		}
+
+		if dir == r.root || dir == filepath.Dir(dir) {
+			return false
+		}
+	}
+}
+
+// isIgnoreFile returns true for the ignore files themselves, which are never scanned.
+func isIgnoreFile(info os.DirEntry) bool {
+	return info.Name() == ignoreFileName
+}
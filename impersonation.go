@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// NewImpersonationCmd flags sessions of nicknames that appear from IPs that the nickname was never seen with before.
+func NewImpersonationCmd(ctx context.Context) *cobra.Command {
+	cfg := config.NewImpersonationConfig()
+
+	cmd := &cobra.Command{
+		Use:   "impersonation",
+		Short: "flag sessions of nicknames from networks that differ from all earlier sessions of the nickname, ranked by confidence",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		searchDir, err := filepath.Abs(cfg.SearchDir)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path of search dir: %w", err)
+		}
+
+		files, err := listFiles(ctx, searchDir, cfg.FileRegexp)
+		if err != nil {
+			return err
+		}
+
+		sessions := make([]playerSession, 0, 1024)
+		for _, file := range files {
+			err = checkShutDown(ctx)
+			if err != nil {
+				return err
+			}
+
+			sessions, err = appendSessions(sessions, file)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", file, err)
+			}
+		}
+
+		suspects := findImpersonations(sessions, cfg.Name, cfg.MinSessions)
+		if len(suspects) == 0 {
+			return fmt.Errorf("%w: no suspicious sessions found", ErrNoMatches)
+		}
+		if cfg.Output == config.FormatText {
+			_, err := fmt.Fprint(cmd.OutOrStdout(), suspects)
+			return err
+		}
+
+		data, err := json.MarshalIndent(suspects, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal json result: %w", err)
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s\n", data)
+		return err
+	}
+	return cmd
+}
+
+// playerSession is a connection of a client between its join and leave lines.
+type playerSession struct {
+	Nickname string
+	IP       string
+	File     string
+	Line     int
+	// Time is the time of the join line, nil when the log format has no timestamps.
+	Time *time.Time
+}
+
+// appendSessions appends the sessions of a log file whose nickname is known.
+func appendSessions(sessions []playerSession, file string) ([]playerSession, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return sessions, err
+	}
+	defer f.Close()
+
+	var (
+		open       = make(map[int]*playerSession, 64)
+		lineNumber = 0
+	)
+	closeSession := func(id int) {
+		s, ok := open[id]
+		if !ok {
+			return
+		}
+		delete(open, id)
+		if s.Nickname != "" {
+			sessions = append(sessions, *s)
+		}
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		event, ok := parseEvent(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case event.Type == config.EventConnect && event.IP != "":
+			closeSession(event.ID)
+			s := &playerSession{
+				IP:   event.IP,
+				File: file,
+				Line: lineNumber,
+			}
+			if t, ok := parseLogTime(line); ok {
+				s.Time = &t
+			}
+			open[event.ID] = s
+		case isLeaveLine(event.Text):
+			closeSession(event.ID)
+		case event.Nickname != "":
+			if s, ok := open[event.ID]; ok && s.Nickname == "" {
+				// the first nickname of the session, name changes are not followed
+				s.Nickname = event.Nickname
+			}
+		}
+	}
+
+	ids := make([]int, 0, len(open))
+	for id := range open {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		closeSession(id)
+	}
+	return sessions, scanner.Err()
+}
+
+// Impersonation is a session of a nickname from a network that none of the earlier sessions of the nickname used.
+type Impersonation struct {
+	Nickname string     `json:"nickname"`
+	IP       string     `json:"ip"`
+	File     string     `json:"file"`
+	Line     int        `json:"line"`
+	Time     *time.Time `json:"time,omitempty"`
+	// KnownIPs are the IPs of the earlier sessions of the nickname.
+	KnownIPs []string `json:"known_ips"`
+	// Sessions is the number of earlier sessions of the nickname.
+	Sessions int `json:"sessions"`
+	// Confidence grows with the number of earlier sessions and is halved
+	// when the nickname keeps using the new network afterwards, e.g. after changing the ISP.
+	Confidence float64 `json:"confidence"`
+}
+
+func (i Impersonation) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "confidence=%.2f name=%s ip=%s sessions=%d known_ips=%s file=%s:%d",
+		i.Confidence, i.Nickname, i.IP, i.Sessions, strings.Join(i.KnownIPs, ","), i.File, i.Line)
+	if i.Time != nil {
+		sb.WriteString(" time=" + i.Time.Format(time.RFC3339))
+	}
+	return sb.String()
+}
+
+type ImpersonationList []Impersonation
+
+func (l ImpersonationList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(l) * 128)
+	for _, i := range l {
+		sb.WriteString(i.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// findImpersonations compares every session of a nickname with its earlier sessions.
+// Sessions from the same network as an earlier session are not flagged, as dynamic IPs change within it.
+func findImpersonations(sessions []playerSession, name string, minSessions int) ImpersonationList {
+	byName := make(map[string][]playerSession, len(sessions))
+	for _, s := range sessions {
+		if name != "" && s.Nickname != name {
+			continue
+		}
+		byName[s.Nickname] = append(byName[s.Nickname], s)
+	}
+
+	suspects := make(ImpersonationList, 0, 16)
+	for nickname, nameSessions := range byName {
+		// files are read in sorted order, sessions without timestamps keep it
+		slices.SortStableFunc(nameSessions, func(a, b playerSession) int {
+			if a.Time == nil || b.Time == nil {
+				return 0
+			}
+			return a.Time.Compare(*b.Time)
+		})
+
+		for idx, s := range nameSessions {
+			if idx < minSessions {
+				continue
+			}
+
+			earlier := nameSessions[:idx]
+			network := networkOf(s.IP)
+			known := false
+			for _, e := range earlier {
+				if e.IP == s.IP || networkOf(e.IP) == network {
+					known = true
+					break
+				}
+			}
+			if known {
+				continue
+			}
+
+			confidence := float64(len(earlier)) / float64(len(earlier)+1)
+			later := nameSessions[idx+1:]
+			if len(later) > 0 && !slices.ContainsFunc(later, func(l playerSession) bool {
+				return networkOf(l.IP) != network
+			}) {
+				confidence /= 2
+			}
+
+			knownIPs := make([]string, 0, len(earlier))
+			for _, e := range earlier {
+				knownIPs = append(knownIPs, e.IP)
+			}
+			slices.Sort(knownIPs)
+
+			suspects = append(suspects, Impersonation{
+				Nickname:   nickname,
+				IP:         s.IP,
+				File:       s.File,
+				Line:       s.Line,
+				Time:       s.Time,
+				KnownIPs:   slices.Compact(knownIPs),
+				Sessions:   len(earlier),
+				Confidence: confidence,
+			})
+		}
+	}
+
+	slices.SortFunc(suspects, func(a, b Impersonation) int {
+		return cmp.Or(
+			cmp.Compare(b.Confidence, a.Confidence),
+			strings.Compare(a.Nickname, b.Nickname),
+			strings.Compare(a.File, b.File),
+			cmp.Compare(a.Line, b.Line),
+		)
+	})
+	return suspects
+}
+
+// networkOf returns the /24 network of IPv4 and the /48 network of IPv6 addresses.
+func networkOf(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix.String()
+}
@@ -0,0 +1,44 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioprioClassBestEffort = 2
+	ioprioClassIdle       = 3
+	ioprioClassShift      = 13
+	ioprioWhoProcess      = 1
+)
+
+// setIOPriority sets the io scheduling class and level of all threads of the process,
+// threads that are started later inherit the priority.
+func setIOPriority(idle bool, level int) error {
+	prio := ioprioClassBestEffort<<ioprioClassShift | level
+	if idle {
+		prio = ioprioClassIdle << ioprioClassShift
+	}
+
+	// ioprio_set only applies to a single thread
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return fmt.Errorf("failed to list threads: %w", err)
+	}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		_, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(prio))
+		if errno != 0 && errno != unix.ESRCH {
+			return fmt.Errorf("failed to set io priority: %w", errno)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !windows
+
+package main
+
+import "log/slog"
+
+// setIOPriority is not supported on this platform, the reads can still be throttled with --max-read-bandwidth.
+func setIOPriority(bool, int) error {
+	slog.Warn("io nice is not supported on this platform, ignoring it")
+	return nil
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// setIOPriority enables the background mode of the process, which lowers its io and memory priority.
+// Windows has no io priority levels, the level is ignored.
+func setIOPriority(bool, int) error {
+	err := windows.SetPriorityClass(windows.CurrentProcess(), windows.PROCESS_MODE_BACKGROUND_BEGIN)
+	if err != nil {
+		return fmt.Errorf("failed to set io priority: %w", err)
+	}
+	return nil
+}
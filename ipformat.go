@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/netip"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// formatIP renders the IP in the ip format, the IP is normalized, e.g. IPv4-mapped IPv6 addresses become IPv4 addresses.
+// IPs that cannot be parsed, e.g. redacted ones, are returned unchanged.
+func formatIP(ip string, port int, format string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+
+	switch format {
+	case config.IPFormatKeepPort:
+		if port > 0 {
+			return netip.AddrPortFrom(addr, uint16(port)).String()
+		}
+	case config.IPFormatAnonymize:
+		bits := 24
+		if addr.Is6() {
+			// the interface identifier of IPv6 addresses identifies the device
+			bits = 64
+		}
+		prefix, _ := addr.Prefix(bits)
+		return prefix.Addr().String()
+	}
+	return addr.String()
+}
+
+// formatIPs returns copies of the players with IPs in the configured ip format.
+// The port is part of the IP with the keep-port format and removed otherwise.
+func (cli *CLI) formatIPs(players PlayerExtendedList) PlayerExtendedList {
+	formatted := make(PlayerExtendedList, 0, len(players))
+	for _, player := range players {
+		player.IP = formatIP(player.IP, player.Port, cli.cfg.IPFormat)
+		player.Port = 0
+		formatted = append(formatted, player)
+	}
+	return formatted
+}
+
+// parseIPOrAddrPort parses IPs with and without port.
+func parseIPOrAddrPort(s string) (netip.AddrPort, error) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return netip.AddrPortFrom(addr.Unmap(), 0), nil
+	}
+	addrPort, err := netip.ParseAddrPort(s)
+	if err != nil {
+		return addrPort, err
+	}
+	return netip.AddrPortFrom(addrPort.Addr().Unmap(), addrPort.Port()), nil
+}
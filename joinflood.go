@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// NewJoinFloodCmd detects bursts of joins from nearby IPs, which are typical for spam bots.
+func NewJoinFloodCmd(ctx context.Context) *cobra.Command {
+	cfg := config.NewJoinFloodConfig()
+
+	cmd := &cobra.Command{
+		Use:   "joinflood",
+		Short: "detect rapid joins with generated looking nicknames from nearby IPs and print the flooding ranges or ban_range commands",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		searchDir, err := filepath.Abs(cfg.SearchDir)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path of search dir: %w", err)
+		}
+
+		files, err := listFiles(ctx, searchDir, cfg.FileRegexp)
+		if err != nil {
+			return err
+		}
+
+		floods := make(JoinFloodList, 0, 16)
+		for _, file := range files {
+			err = checkShutDown(ctx)
+			if err != nil {
+				return err
+			}
+
+			joins, err := readJoins(file)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", file, err)
+			}
+			floods = append(floods, findJoinFloods(file, joins, cfg.Window, cfg.MinJoins, cfg.MinGenerated)...)
+		}
+
+		if len(floods) == 0 {
+			return fmt.Errorf("%w: no join floods found", ErrNoMatches)
+		}
+
+		if cfg.BanRanges {
+			bans := make([]ban, 0, len(floods))
+			for _, flood := range floods {
+				prefix := netip.MustParsePrefix(flood.Range)
+				bans = append(bans, ban{
+					First: prefix.Addr(),
+					Last:  lastAddr(prefix),
+				})
+			}
+			return writeBanFile(cmd.OutOrStdout(), bans, cfg.BanDuration, joinFloodReason)
+		}
+
+		if cfg.Output == config.FormatText {
+			_, err := fmt.Fprint(cmd.OutOrStdout(), floods)
+			return err
+		}
+
+		data, err := json.MarshalIndent(floods, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal json result: %w", err)
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s\n", data)
+		return err
+	}
+	return cmd
+}
+
+var joinFloodReason = template.Must(template.New("reason").Parse("join flood"))
+
+// join is a join line together with the first nickname of the client.
+type join struct {
+	IP       netip.Addr
+	Time     time.Time
+	Nickname string
+}
+
+// readJoins returns the joins of a log file with timestamps.
+func readJoins(file string) ([]*join, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		joins = make([]*join, 0, 64)
+		// unnamed are the joins whose nickname is not known yet
+		unnamed = make(map[int]*join, 64)
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		event, ok := parseEvent(line)
+		if !ok {
+			continue
+		}
+
+		if event.Type == config.EventConnect && event.IP != "" {
+			t, ok := parseLogTime(line)
+			if !ok {
+				continue
+			}
+			addr, err := netip.ParseAddr(event.IP)
+			if err != nil {
+				continue
+			}
+			j := &join{
+				IP:   addr.Unmap(),
+				Time: t,
+			}
+			joins = append(joins, j)
+			unnamed[event.ID] = j
+			continue
+		}
+
+		if j, ok := unnamed[event.ID]; ok && event.Nickname != "" {
+			j.Nickname = event.Nickname
+			delete(unnamed, event.ID)
+		}
+	}
+	return joins, scanner.Err()
+}
+
+// JoinFlood is a burst of joins from the same network.
+type JoinFlood struct {
+	File  string    `json:"file"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Joins int       `json:"joins"`
+	// Range is the smallest CIDR range that contains the IPs of the flood.
+	Range string `json:"range"`
+	// Generated is the ratio of generated looking nicknames among the joins with known nicknames.
+	Generated float64  `json:"generated"`
+	Nicknames []string `json:"nicknames,omitempty"`
+}
+
+func (f JoinFlood) String() string {
+	return fmt.Sprintf("start=%s end=%s joins=%d range=%s generated=%.2f file=%s names=%q",
+		f.Start.Format(time.RFC3339), f.End.Format(time.RFC3339), f.Joins, f.Range, f.Generated, f.File, strings.Join(f.Nicknames, ","))
+}
+
+type JoinFloodList []JoinFlood
+
+func (l JoinFloodList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(l) * 128)
+	for _, f := range l {
+		sb.WriteString(f.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// findJoinFloods groups the joins by network and finds the windows with at least minJoins joins.
+// Joins without known nicknames do not affect the generated ratio, bots often never send one.
+func findJoinFloods(file string, joins []*join, window time.Duration, minJoins int, minGenerated float64) JoinFloodList {
+	byNetwork := make(map[string][]*join, len(joins))
+	for _, j := range joins {
+		network := networkOf(j.IP.String())
+		byNetwork[network] = append(byNetwork[network], j)
+	}
+
+	floods := make(JoinFloodList, 0, 4)
+	for _, networkJoins := range byNetwork {
+		slices.SortStableFunc(networkJoins, func(a, b *join) int {
+			return a.Time.Compare(b.Time)
+		})
+
+		for start := 0; start < len(networkJoins); {
+			end := start + 1
+			for end < len(networkJoins) && networkJoins[end].Time.Sub(networkJoins[start].Time) <= window {
+				end++
+			}
+			// extend the flood as long as joins keep coming within the window
+			for end < len(networkJoins) && end-start >= minJoins && networkJoins[end].Time.Sub(networkJoins[end-1].Time) <= window {
+				end++
+			}
+			if end-start < minJoins {
+				start++
+				continue
+			}
+
+			flood := newJoinFlood(file, networkJoins[start:end])
+			if flood.Generated >= minGenerated {
+				floods = append(floods, flood)
+			}
+			start = end
+		}
+	}
+
+	slices.SortFunc(floods, func(a, b JoinFlood) int {
+		return cmp.Or(
+			a.Start.Compare(b.Start),
+			strings.Compare(a.Range, b.Range),
+		)
+	})
+	return floods
+}
+
+func newJoinFlood(file string, joins []*join) JoinFlood {
+	flood := JoinFlood{
+		File:      file,
+		Start:     joins[0].Time,
+		End:       joins[len(joins)-1].Time,
+		Joins:     len(joins),
+		Generated: 1,
+	}
+
+	first, last := joins[0].IP, joins[0].IP
+	named, generated := 0, 0
+	for _, j := range joins {
+		if j.IP.Less(first) {
+			first = j.IP
+		}
+		if last.Less(j.IP) {
+			last = j.IP
+		}
+		if j.Nickname == "" {
+			continue
+		}
+		named++
+		if generatedName(j.Nickname) {
+			generated++
+		}
+		flood.Nicknames = append(flood.Nicknames, j.Nickname)
+	}
+	if named > 0 {
+		flood.Generated = float64(generated) / float64(named)
+	}
+	slices.Sort(flood.Nicknames)
+	flood.Nicknames = slices.Compact(flood.Nicknames)
+	flood.Range = commonPrefix(first, last).String()
+	return flood
+}
+
+// commonPrefix returns the smallest prefix that contains both addresses of the same family.
+func commonPrefix(a, b netip.Addr) netip.Prefix {
+	as, bs := a.AsSlice(), b.AsSlice()
+	bits := 0
+	for idx := range as {
+		diff := as[idx] ^ bs[idx]
+		if diff == 0 {
+			bits += 8
+			continue
+		}
+		for diff&0x80 == 0 {
+			bits++
+			diff <<= 1
+		}
+		break
+	}
+	prefix, _ := a.Prefix(bits)
+	return prefix
+}
+
+// generatedName returns true for nicknames that look randomly generated, e.g. with many digits
+// or long runs of consonants.
+func generatedName(name string) bool {
+	var digits, letters, vowels, run, maxRun int
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+			run = 0
+		case strings.ContainsRune("aeiouy", r):
+			letters++
+			vowels++
+			run = 0
+		case unicode.IsLetter(r):
+			letters++
+			run++
+			maxRun = max(maxRun, run)
+		default:
+			run = 0
+		}
+	}
+	return digits >= 3 || maxRun >= 5 || (letters >= 6 && vowels == 0)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// knownBans are the IPs and ranges that are already banned in the bans.cfg files of the servers.
+type knownBans []ban
+
+// loadKnownBans reads the ban and ban_range commands of bans.cfg files.
+// Directories are searched recursively for .cfg files.
+func loadKnownBans(paths []string) (knownBans, error) {
+	bans := make(knownBans, 0, 256)
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open known bans: %w", err)
+		}
+
+		if !fi.IsDir() {
+			bans, err = bans.load(path)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.EqualFold(filepath.Ext(file), ".cfg") {
+				return nil
+			}
+			bans, err = bans.load(file)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read known bans of %s: %w", path, err)
+		}
+	}
+	return bans, nil
+}
+
+// load appends the bans of a single file.
+// unban and unban_range commands remove previous bans of the same IP or range.
+func (k knownBans) load(file string) (knownBans, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open known bans: %w", err)
+	}
+	defer f.Close()
+
+	before := len(k)
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		args := splitConsoleArgs(scanner.Text())
+		if len(args) == 0 || strings.HasPrefix(args[0], "#") {
+			continue
+		}
+
+		var (
+			first, last netip.Addr
+			remove      bool
+		)
+		switch {
+		case (args[0] == "ban" || args[0] == "unban") && len(args) >= 2:
+			first, err = netip.ParseAddr(args[1])
+			if err != nil {
+				// client ids are not persistent
+				slog.Debug("skipping ban of client id", "file", file, "line", lineNumber)
+				continue
+			}
+			last = first
+			remove = args[0] == "unban"
+		case (args[0] == "ban_range" || args[0] == "unban_range") && len(args) >= 3:
+			first, last, err = parseIPRange(args[1] + "-" + args[2])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", file, lineNumber, err)
+			}
+			remove = args[0] == "unban_range"
+		default:
+			continue
+		}
+
+		if remove {
+			k = k.remove(first.Unmap(), last.Unmap())
+			continue
+		}
+		k = append(k, ban{First: first.Unmap(), Last: last.Unmap()})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read known bans %s: %w", file, err)
+	}
+
+	slog.Info("loaded known bans", "file", file, "bans", len(k)-before)
+	return k, nil
+}
+
+func (k knownBans) remove(first, last netip.Addr) knownBans {
+	bans := k[:0]
+	for _, b := range k {
+		if b.First != first || b.Last != last {
+			bans = append(bans, b)
+		}
+	}
+	return bans
+}
+
+// Contains returns true in case the IP is banned by any of the bans.
+func (k knownBans) Contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, b := range k {
+		if b.First.Compare(addr) <= 0 && addr.Compare(b.Last) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// splitConsoleArgs splits a console command into its arguments.
+// Arguments are separated by spaces, quoted arguments may contain spaces and escaped quotes.
+func splitConsoleArgs(line string) []string {
+	var (
+		args    = make([]string, 0, 4)
+		sb      strings.Builder
+		inArg   bool
+		quoted  bool
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			sb.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inArg {
+				args = append(args, sb.String())
+				sb.Reset()
+				inArg = false
+			}
+		default:
+			sb.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, sb.String())
+	}
+	return args
+}
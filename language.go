@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// languageWords are frequent words of chat messages per ISO 639-1 language code.
+// Words that several languages share count for all of them.
+var languageWords = map[string][]string{
+	"en": {"the", "you", "and", "is", "are", "what", "this", "that", "with", "have", "not", "your", "my", "me", "it", "why", "how", "can", "pls", "please", "thanks", "he", "she", "they", "was", "just", "dont", "im", "its", "u"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "du", "bist", "ein", "eine", "mit", "auf", "was", "wie", "warum", "hab", "habe", "mal", "noch", "schon", "bitte", "danke", "aber", "auch", "kann", "mich", "dich", "wir", "ihr"},
+	"fr": {"le", "les", "et", "est", "je", "tu", "pas", "une", "des", "avec", "pour", "quoi", "mais", "oui", "merci", "suis", "moi", "toi", "c'est", "il", "elle", "nous", "vous", "ça", "qui", "du", "au", "ce", "mdr", "bien"},
+	"es": {"el", "los", "las", "y", "es", "yo", "tu", "que", "una", "por", "para", "pero", "gracias", "muy", "eres", "soy", "como", "porque", "mi", "hola", "bueno", "jaja", "del", "está", "esta", "también", "nada", "vamos", "ya", "aqui"},
+	"pt": {"o", "os", "e", "é", "eu", "você", "voce", "não", "nao", "uma", "com", "para", "mas", "obrigado", "muito", "sou", "como", "porque", "meu", "oi", "bom", "kkk", "do", "da", "está", "tá", "ta", "também", "nada", "vamos"},
+	"it": {"il", "lo", "gli", "e", "è", "io", "sei", "non", "una", "con", "per", "ma", "grazie", "molto", "sono", "come", "perché", "mio", "ciao", "bene", "anche", "niente", "andiamo", "che", "della", "questo", "cosa", "dove", "ho", "hai"},
+	"nl": {"de", "het", "en", "is", "ik", "jij", "je", "niet", "een", "met", "voor", "maar", "dank", "heel", "ben", "hoe", "waarom", "mijn", "hoi", "goed", "ook", "niks", "wat", "dat", "deze", "wij", "jullie", "hebben", "heb", "kan"},
+	"pl": {"i", "jest", "nie", "ja", "ty", "się", "sie", "na", "to", "co", "jak", "dlaczego", "dzięki", "dzieki", "bardzo", "jestem", "mój", "moj", "cześć", "czesc", "dobrze", "też", "tez", "nic", "chodź", "tak", "ale", "mnie", "ciebie", "kurwa"},
+	"tr": {"ve", "bir", "bu", "ne", "ben", "sen", "değil", "degil", "için", "icin", "ama", "teşekkürler", "tesekkurler", "çok", "cok", "nasıl", "nasil", "neden", "benim", "merhaba", "iyi", "da", "de", "hiç", "hic", "gel", "evet", "hayır", "hayir", "mi"},
+	"sv": {"och", "är", "jag", "du", "inte", "en", "ett", "med", "för", "men", "tack", "mycket", "hur", "varför", "min", "hej", "bra", "också", "inget", "vad", "det", "den", "vi", "ni", "har", "kan", "nej", "ja", "så", "om"},
+}
+
+// languageLetters are letters that are specific to a single of the languages above.
+var languageLetters = map[rune]string{
+	'ß': "de", 'ä': "de", 'ü': "de",
+	'ñ': "es", '¿': "es", '¡': "es",
+	'ã': "pt", 'õ': "pt",
+	'ł': "pl", 'ą': "pl", 'ę': "pl", 'ś': "pl", 'ż': "pl", 'ź': "pl", 'ć': "pl", 'ń': "pl",
+	'ş': "tr", 'ğ': "tr", 'ı': "tr",
+	'å': "sv",
+	'œ': "fr", 'ù': "fr", 'û': "fr",
+}
+
+// languageWordSets are the words of languageWords as sets.
+var languageWordSets = func() map[string]map[string]struct{} {
+	sets := make(map[string]map[string]struct{}, len(languageWords))
+	for lang, words := range languageWords {
+		set := make(map[string]struct{}, len(words))
+		for _, w := range words {
+			set[w] = struct{}{}
+		}
+		sets[lang] = set
+	}
+	return sets
+}()
+
+// detectLanguage returns the ISO 639-1 code of the language of a chat message, empty when it cannot be told.
+// Messages in non-Latin scripts are detected by their script, Latin messages by frequent words and specific letters.
+// Chat messages are short, so the detection is a best guess for routing matches rather than an exact classification.
+func detectLanguage(text string) string {
+	if lang := detectScript(text); lang != "" {
+		return lang
+	}
+
+	scores := make(map[string]int, len(languageWords))
+	for _, r := range strings.ToLower(text) {
+		if lang, ok := languageLetters[r]; ok {
+			scores[lang] += 2
+		}
+	}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		for lang, set := range languageWordSets {
+			if _, ok := set[word]; ok {
+				scores[lang]++
+			}
+		}
+	}
+
+	best, bestScore, tie := "", 0, false
+	for lang, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = lang, score, false
+		case score == bestScore:
+			tie = true
+		}
+	}
+	if tie {
+		return ""
+	}
+	return best
+}
+
+// detectScript returns the language of messages whose letters are mostly of a non-Latin script.
+func detectScript(text string) string {
+	var latin, cyrillic, ukrainian, greek, han, kana, hangul, arabic, hebrew, thai int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				ukrainian++
+			}
+		case unicode.Is(unicode.Greek, r):
+			greek++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Hebrew, r):
+			hebrew++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		}
+	}
+
+	switch {
+	case cyrillic > latin && ukrainian > 0:
+		return "uk"
+	case cyrillic > latin:
+		return "ru"
+	case greek > latin:
+		return "el"
+	case kana > 0 && kana+han > latin:
+		return "ja"
+	case han > latin:
+		return "zh"
+	case hangul > latin:
+		return "ko"
+	case arabic > latin:
+		return "ar"
+	case hebrew > latin:
+		return "he"
+	case thai > latin:
+		return "th"
+	}
+	return ""
+}
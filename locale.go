@@ -0,0 +1,56 @@
+package main
+
+import "fmt"
+
+// reportTranslations are the texts of reports, which are forwarded to players that often do not speak english.
+// Missing translations fall back to english.
+var reportTranslations = map[string]map[string]string{
+	"en": {
+		"title":      "Offense report",
+		"generated":  "Generated",
+		"matches":    "matches",
+		"period":     "period",
+		"ips":        "IPs",
+		"servers":    "servers",
+		"summary":    "Summary",
+		"category":   "category",
+		"unknown":    "unknown",
+		"no_matches": "no matches found for %s",
+	},
+	"de": {
+		"title":      "Verstoßbericht",
+		"generated":  "Erstellt",
+		"matches":    "Treffer",
+		"period":     "Zeitraum",
+		"ips":        "IPs",
+		"servers":    "Server",
+		"summary":    "Übersicht",
+		"category":   "Kategorie",
+		"unknown":    "unbekannt",
+		"no_matches": "keine Treffer für %s gefunden",
+	},
+	"fr": {
+		"title":      "Rapport d'infraction",
+		"generated":  "Généré le",
+		"matches":    "occurrences",
+		"period":     "période",
+		"ips":        "IPs",
+		"servers":    "serveurs",
+		"summary":    "Résumé",
+		"category":   "catégorie",
+		"unknown":    "inconnue",
+		"no_matches": "aucune occurrence trouvée pour %s",
+	},
+}
+
+// translate returns the text of the key in the language, formatted with the arguments in case there are any.
+func translate(lang, key string, args ...any) string {
+	text, ok := reportTranslations[lang][key]
+	if !ok {
+		text = reportTranslations["en"][key]
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// setupLogging writes the diagnostics of the tool to w, separated from the results on stdout.
+func (cli *CLI) setupLogging(w io.Writer) {
+	level := slog.LevelWarn
+	switch {
+	case cli.cfg.Quiet:
+		level = slog.LevelError
+	case cli.cfg.Verbosity >= config.VerbosityDebug:
+		level = slog.LevelDebug
+	case cli.cfg.Verbosity >= config.VerbosityInfo:
+		level = slog.LevelInfo
+	}
+
+	slog.SetDefault(slog.New(cli.logHandler(w, level)))
+	// status lines are requested explicitly and ignore the verbosity
+	cli.statusLogger = slog.New(cli.logHandler(w, slog.LevelInfo))
+}
+
+func (cli *CLI) logHandler(w io.Writer, level slog.Level) slog.Handler {
+	opts := &slog.HandlerOptions{
+		Level: level,
+	}
+
+	if cli.cfg.LogFormat == config.FormatJSON {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
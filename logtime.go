@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	// 1: date time, e.g. ddnet: 2024-01-02 10:00:05 I chat: ... or [2024-01-02 10:00:05][chat]: ...
+	dateTimeRegex = regexp.MustCompile(`^\[?(\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}:\d{2})\]?`)
+
+	// 1: hex unix timestamp, e.g. teeworlds: [5f3c1a2b][chat]: ...
+	hexTimeRegex = regexp.MustCompile(`^\[([0-9a-fA-F]{8})\]`)
+)
+
+// parseLogTime returns the timestamp at the beginning of a log line.
+// Timestamps without time zone are interpreted in the local time zone.
+func parseLogTime(line string) (time.Time, bool) {
+	if matches := dateTimeRegex.FindStringSubmatch(line); len(matches) != 0 {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", matches[1][:10]+" "+matches[1][11:], time.Local)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t, true
+	}
+
+	if matches := hexTimeRegex.FindStringSubmatch(line); len(matches) != 0 {
+		sec, err := strconv.ParseInt(matches[1], 16, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(sec, 0), true
+	}
+	return time.Time{}, false
+}
+
+// relativeTime formats t relative to now, e.g. 3 days ago.
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	suffix := "ago"
+	if d < 0 {
+		d = -d
+		suffix = "from now"
+	}
+
+	var (
+		n    int64
+		unit string
+	)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		n, unit = int64(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int64(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		n, unit = int64(d/(24*time.Hour)), "day"
+	case d < 365*24*time.Hour:
+		n, unit = int64(d/(30*24*time.Hour)), "month"
+	default:
+		n, unit = int64(d/(365*24*time.Hour)), "year"
+	}
+
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s %s", n, unit, suffix)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lokiJob is the job label of all streams.
+const lokiJob = "twlog-who-said"
+
+// lokiPusher pushes matches to grafana loki.
+type lokiPusher struct {
+	url    string
+	tenant string
+	client *http.Client
+}
+
+func newLokiPusher(url, tenant string) *lokiPusher {
+	return &lokiPusher{
+		url:    strings.TrimRight(url, "/") + "/loki/api/v1/push",
+		tenant: tenant,
+		client: &http.Client{Timeout: sinkTimeout},
+	}
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// Post pushes the matches as json lines, one stream per server, query and player.
+// Matches without timestamp are pushed with the current time.
+func (l *lokiPusher) Post(ctx context.Context, players PlayerExtendedList) error {
+	now := time.Now()
+	streams := make(map[string]*lokiStream, 1)
+	keys := make([]string, 0, 1)
+	for _, p := range players {
+		labels := map[string]string{"job": lokiJob}
+		if p.Server != "" {
+			labels["server"] = p.Server
+		}
+		if p.Query != "" {
+			labels["query"] = p.Query
+		}
+		labels["player"] = p.Nickname
+
+		key := p.Server + "\x00" + p.Query + "\x00" + p.Nickname
+		stream, found := streams[key]
+		if !found {
+			stream = &lokiStream{Stream: labels}
+			streams[key] = stream
+			keys = append(keys, key)
+		}
+
+		t := now
+		if p.Time != nil {
+			t = *p.Time
+		}
+		line, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(t.UnixNano(), 10), string(line)})
+	}
+
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{
+		Streams: make([]*lokiStream, 0, len(streams)),
+	}
+	for _, key := range keys {
+		stream := streams[key]
+		// loki expects the entries of a stream in chronological order
+		slices.SortStableFunc(stream.Values, func(a, b [2]string) int {
+			ta, _ := strconv.ParseInt(a[0], 10, 64)
+			tb, _ := strconv.ParseInt(b[0], 10, 64)
+			return cmp.Compare(ta, tb)
+		})
+		payload.Streams = append(payload.Streams, stream)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.tenant != "" {
+		req.Header.Set("X-Scope-OrgID", l.tenant)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push to loki: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to push to loki: unexpected status code: %s: %s", resp.Status, data)
+	}
+	return nil
+}
@@ -1,37 +1,45 @@
 package main
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"io/fs"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"path/filepath"
-	"regexp"
 	"slices"
-	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
-	"github.com/icza/backscanner"
 	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
-	"github.com/jxsl13/twlog-who-said/archive"
 	"github.com/jxsl13/twlog-who-said/config"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
+	go func() {
+		<-ctx.Done()
+		// restore the default signal behavior, a second signal kills the process
+		cancel()
+	}()
+
 	cmd := NewRootCmd(ctx)
 	if err := cmd.Execute(); err != nil {
+		var exitErr *ExitCodeError
+		if errors.As(err, &exitErr) {
+			if !errors.Is(err, ErrNoMatches) {
+				log.Print(err)
+			}
+			os.Exit(exitErr.Code)
+		}
 		log.Fatal(err)
 	}
 }
@@ -46,10 +54,40 @@ func NewRootCmd(ctx context.Context) *cobra.Command {
 
 	cmd := cobra.Command{
 		Use: filepath.Base(os.Args[0]),
+		// --version prints the build information and capabilities instead of only the version
+		Version: readVersionInfo().Version,
 	}
+	cobra.AddTemplateFunc("versionInfo", versionTemplate)
+	cmd.SetVersionTemplate(`{{versionInfo .}}`)
 	cmd.PreRunE = cli.PreRunE(&cmd)
+	// otherwise the flag is unknown while looking up subcommands, e.g. in --version -o json,
+	// after the other flags, as -v is the verbosity
+	cmd.InitDefaultVersionFlag()
+	cmd.Flags().Lookup("version").Usage = "print the version, commit, build date, output schema version and optional features, as json with -o json"
 	cmd.RunE = cli.RunE
 	cmd.PostRunE = cli.PostRunE
+	cli.registerCompletions(&cmd)
+
+	cmd.AddCommand(
+		NewConfigCmd(),
+		NewTestRegexCmd(),
+		NewBanFileCmd(),
+		NewExportEvidenceCmd(cctx),
+		NewGDPRCmd(cctx),
+		NewReportCmd(),
+		NewAuditCmd(),
+		NewDiffCmd(),
+		NewMergeCmd(),
+		NewRefineCmd(),
+		NewPopulationCmd(cctx),
+		NewVotesCmd(cctx),
+		NewImpersonationCmd(cctx),
+		NewJoinFloodCmd(cctx),
+		NewSpamRateCmd(cctx),
+		NewBenchCmd(cctx),
+		NewUpdateCmd(cctx),
+		NewDocsCmd(),
+	)
 	return &cmd
 }
 
@@ -57,479 +95,388 @@ type CLI struct {
 	ctx         context.Context
 	CancelCause context.CancelCauseFunc
 	cfg         config.Config
+	resolver    *hostnameResolver
+	vpnRanges   ipRanges
+	asnDB       asnDatabase
+	script      *matchScript
+	knownBans   knownBans
+	redactor    *ipRedactor
+	// liveMatches counts the matches of the econ mode for the audit log
+	liveMatches int
+	online      *onlineLookup
+	sinks       []Sink
+	// statusLogger logs the periodic status lines of scans
+	statusLogger *slog.Logger
+	// checkpoint is the progress of the current scan, nil when no checkpoint is configured
+	checkpoint *scanCheckpoint
+	// readLimiter throttles the reads of log files and archives, nil does not limit them
+	readLimiter *bandwidthLimiter
+	// emit receives the matches of every scanned file or archive during the scan instead of
+	// the result of the scan, nil collects them
+	emit func(PlayerExtendedList)
+	// reported are the offsets of the files up to which scheduled runs reported matches, nil for single scans
+	reported *reportedOffsets
 }
 
-func (cli *CLI) PreRunE(cmd *cobra.Command) func(*cobra.Command, []string) error {
-	parser := cliconfig.RegisterFlags(&cli.cfg, false, cmd)
-	return func(cmd *cobra.Command, args []string) error {
-		log.SetOutput(cmd.ErrOrStderr()) // redirect log output to stderr
-		return parser()                  // parse registered commands
-	}
+// ScanStats summarizes the progress of a scan.
+type ScanStats struct {
+	TotalFiles    int
+	TotalArchives int
+	Files         atomic.Int64
+	Archives      atomic.Int64
+	// Members is the number of scanned files inside of archives.
+	Members atomic.Int64
+	// Bytes is the number of bytes read from files and archive members.
+	Bytes atomic.Int64
+	// Duplicates is the number of skipped duplicate files and archive members.
+	Duplicates atomic.Int64
+	// Retries is the number of times that files were read again after transient read errors.
+	Retries atomic.Int64
+	// Found is the number of matches found so far, before any filters are applied.
+	Found        atomic.Int64
+	ScanDuration time.Duration
+	Matches      int
+	// Paths are the collected files and archives of the scan.
+	Paths []string
+
+	mu     sync.Mutex
+	errors []FileError
 }
 
-func (cli *CLI) PostRunE(*cobra.Command, []string) error {
-	cli.CancelCause(context.Canceled) // cleanup only
-	return nil
+// addError records the error of a single file, archive or archive member.
+func (s *ScanStats) addError(fe FileError) {
+	slog.Debug("failed to scan file", "file", fe.File, "kind", fe.Kind, "error", fe.Error)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors = append(s.errors, fe)
 }
 
-func (cli *CLI) checkShutDown() error {
-	select {
-	case <-cli.ctx.Done():
-		return cli.ctx.Err()
-	default:
-		return nil
-	}
+// Errors returns the errors of single files, archives and archive members.
+func (s *ScanStats) Errors() []FileError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.errors)
 }
 
-func (cli *CLI) abort(err error) {
-	cli.CancelCause(err)
+func (s *ScanStats) String() string {
+	return fmt.Sprintf("scanned %d/%d files and %d/%d archives, found %d matches",
+		s.Files.Load(), s.TotalFiles,
+		s.Archives.Load(), s.TotalArchives,
+		s.Matches,
+	)
 }
 
-func (cli *CLI) RunE(cmd *cobra.Command, args []string) error {
-	files := make([]string, 0, 16)
-	archives := make([]string, 0, 1)
-
-	entryDir := cli.cfg.SearchDir
-	entryDir, err := filepath.Abs(entryDir)
-	if err != nil {
-		return fmt.Errorf("failed to get absolute path of search dir: %w", err)
-	}
+func (cli *CLI) PreRunE(cmd *cobra.Command) func(*cobra.Command, []string) error {
+	parser := registerConfigFlags(&cli.cfg, cmd, true)
+	// cannot be registered via struct tags, as -vv requires a count flag
+	cmd.Flags().CountP("verbose", "v", "log verbosity, -v logs skipped files, -vv logs every opened file")
+	return func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr()) // redirect log output to stderr
 
-	// collect log file and archive paths
-	err = filepath.WalkDir(entryDir, func(path string, info os.DirEntry, err error) error {
-		if err != nil {
-			return err
+		err := parser() // parse registered commands
+		if errors.Is(err, config.ErrRegexRequired) && isTerminal(cmd.InOrStdin()) {
+			// moderators that are not familiar with the flags are asked for the query
+			err = cli.promptQuery(cmd)
+			if err == nil {
+				err = parser()
+			}
 		}
-
-		err = cli.checkShutDown()
 		if err != nil {
-			return err
+			return cli.grepExitCode(cmd, 0, err)
 		}
-
-		// skip non-files
-		if !info.Type().IsRegular() {
-			return nil
-		}
-
-		if cli.cfg.IncludeArchives && cli.cfg.ArchiveRegexp.MatchString(path) {
-			archives = append(archives, path)
-			return nil
-		}
-
-		if !cli.cfg.FileRegexp.MatchString(path) {
-			return nil
-		}
-
-		files = append(files, path)
+		cli.setupLogging(cmd.ErrOrStderr())
 		return nil
-	})
-	if err != nil {
-		return err
 	}
-	slices.Sort(files)
-	slices.Sort(archives)
-
-	wg := &sync.WaitGroup{}
-	mu := &sync.Mutex{}
-	extendedPlayerList := make(PlayerExtendedList, 0, 16)
-
-	concurrency := make(chan struct{}, cli.cfg.Concurrency)
-
-	wg.Add(len(files))
-	for _, file := range files {
-		exec := func() {
-			concurrency <- struct{}{}
-			defer func() {
-				<-concurrency
-				wg.Done()
-			}()
+}
 
-			filePlayers, err := searchPhraseInFile(file, cli.cfg.PhraseRegexp)
-			if err != nil {
-				cli.abort(fmt.Errorf("failed to search phrase in file %s: %w", file, err))
-				return
-			}
-			mu.Lock()
-			defer mu.Unlock()
-			extendedPlayerList = append(extendedPlayerList, filePlayers...)
+// registerConfigFlags registers the flags of the configuration and returns their parser.
+// The parser loads the yaml config file and, with replay, the configuration of the replayed manifest
+// of the flags that cobra parsed, their values become the defaults that environment variables and flags override.
+func registerConfigFlags(cfg *config.Config, cmd *cobra.Command, replay bool) func() error {
+	_ = cliconfig.RegisterFlags(cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	return func() error {
+		args := changedFlagArgs(cmd)
+		*cfg = config.NewConfig()
+		err := cfg.LoadFile(args)
+		if err != nil {
+			return err
 		}
-
-		if cli.cfg.Concurrency > 1 {
-			// only run in parallel if concurrency is greater than 1
-			go exec()
-		} else {
-			exec()
-			err = cli.checkShutDown()
+		if replay {
+			// the replayed configuration overrides the config file
+			err = cfg.LoadReplay(args)
 			if err != nil {
 				return err
 			}
 		}
-	}
-
-	wg.Add(len(archives))
-	for _, file := range archives {
-		exec := func() {
-			concurrency <- struct{}{}
-			defer func() {
-				<-concurrency
-				wg.Done()
-			}()
-
-			err = archive.Walk(file, func(path string, info fs.FileInfo, r io.Reader, err error) error {
-				if err != nil {
-					return err
-				}
-
-				err = cli.checkShutDown()
-				if err != nil {
-					return err
-				}
 
-				if !info.Mode().IsRegular() {
-					// skip dirs & symlinks
-					return nil
-				}
-
-				if !cli.cfg.FileRegexp.MatchString(path) {
-					return nil
-				}
-
-				// matching file in archive
-				// read file into memory only if the file path matches the regex
-				memFile, err := archive.NewFile(r, info.Size())
-				if err != nil {
-					return fmt.Errorf("failed to read file %s from archive: %w", path, err)
-				}
-
-				filePath := fmt.Sprintf("%s@%s", file, path)
-				filePlayers, err := searchPhrase(filePath, memFile, cli.cfg.PhraseRegexp)
-				if err != nil {
-					return fmt.Errorf("failed to search phrase in archive file %s: %w", filePath, err)
-				}
-
-				mu.Lock()
-				defer mu.Unlock()
-				extendedPlayerList = append(extendedPlayerList, filePlayers...)
-
-				return nil
-			})
-			if err != nil {
-				if errors.Is(err, archive.ErrUnsupportedArchive) {
-					log.Printf("skipping unsupported archive: %s", file)
+		// the defaults of the flags are read when they are registered,
+		// so the flags are registered again with the loaded values as defaults
+		loaded := &cobra.Command{}
+		parser := cliconfig.RegisterFlags(cfg, false, loaded, cliconfig.WithEnvPrefix(config.EnvPrefix))
+		cmd.Flags().VisitAll(func(f *pflag.Flag) {
+			lf := loaded.Flags().Lookup(f.Name)
+			if lf == nil {
+				if !f.Changed {
 					return
 				}
-				cli.abort(fmt.Errorf("failed to walk archive %s: %w", file, err))
+				// e.g. the verbosity, which cannot be registered via struct tags
+				lf = &pflag.Flag{Name: f.Name, Shorthand: f.Shorthand, NoOptDefVal: f.NoOptDefVal}
+				loaded.Flags().AddFlag(lf)
 			}
-		}
-
-		if cli.cfg.Concurrency > 1 {
-			// only run in parallel if concurrency is greater than 1
-			go exec()
-		} else {
-			exec()
-			err = cli.checkShutDown()
-			if err != nil {
-				return err
+			if f.Changed {
+				lf.Value, lf.Changed = f.Value, true
 			}
-		}
-	}
-	wg.Wait()
-
-	err = cli.checkShutDown()
-	if err != nil {
-		return err
-	}
-
-	if cli.cfg.IPsOnly {
-		ipList := extendedPlayerList.ToIPList()
-		if cli.cfg.Deduplicate {
-			ipList = deduplicate(ipList)
-		}
-		return cli.print(cmd, ipList)
-	} else if cli.cfg.Extended {
-		if cli.cfg.Deduplicate {
-			extendedPlayerList = deduplicate(extendedPlayerList)
-		}
-		return cli.print(cmd, extendedPlayerList)
-	}
-
-	// not extended list of players
-	playerList := extendedPlayerList.ToPlayerList()
-	if cli.cfg.Deduplicate {
-		playerList = deduplicate(playerList)
+			lf.Value = parsedValue{lf.Value}
+		})
+		return parser()
 	}
-
-	return cli.print(cmd, playerList)
 }
 
-func (cli *CLI) print(cmd *cobra.Command, a any) error {
-	switch cli.cfg.Output {
-	case config.FormatText:
-		return cli.printText(cmd, a)
-	case config.FormatJSON:
-		return cli.printJSON(cmd, a)
-	default:
-		// should never happen
-		return fmt.Errorf("unsupported output format: %s", cli.cfg.Output)
-	}
+// parsedValue is the value of a flag that was already parsed by cobra.
+// The parser parses the arguments a second time, which must neither change nor double the value, e.g. of -vv.
+type parsedValue struct {
+	pflag.Value
 }
 
-func (cli *CLI) printText(cmd *cobra.Command, a any) error {
-	s := a.(fmt.Stringer) // will panic if used incorrectly
-	_, err := fmt.Fprintln(cmd.OutOrStdout(), s.String())
-	return err
+func (parsedValue) Set(string) error {
+	return nil
 }
 
-func (cli *CLI) printJSON(cmd *cobra.Command, a any) error {
-	data, err := json.MarshalIndent(a, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal json result: %w", err)
-	}
+// changedFlagArgs returns the flags that were set on the command line as --name=value arguments.
+func changedFlagArgs(cmd *cobra.Command) []string {
+	args := make([]string, 0, 8)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		args = append(args, "--"+f.Name+"="+f.Value.String())
+	})
+	return args
+}
 
-	_, err = cmd.OutOrStdout().Write(data)
-	if err != nil {
-		return fmt.Errorf("failed to print json result: %w", err)
-	}
-	fmt.Fprint(cmd.OutOrStdout(), "\n")
+func (cli *CLI) PostRunE(*cobra.Command, []string) error {
+	cli.CancelCause(context.Canceled) // cleanup only
 	return nil
 }
 
-func deduplicate[C comparable](items []C) []C {
-	seen := make(map[C]struct{}, max(16, len(items)/16))
-	unique := make([]C, 0, len(items))
+// ErrScanTimeout is the cause of scans that were stopped by the --timeout deadline.
+var ErrScanTimeout = errors.New("scan timeout exceeded")
 
-	for _, item := range items {
-		if _, ok := seen[item]; ok {
-			continue
-		}
-		seen[item] = struct{}{}
-		unique = append(unique, item)
+// checkShutDown returns the reason for the shutdown in case the scan was interrupted
+// by a signal or aborted due to an error.
+func checkShutDown(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	default:
+		return nil
 	}
-	return unique
 }
 
-var (
-	// id, nick, chat line
-	chatLineRegexp = regexp.MustCompile(`chat: (\d+):-?\d+:(.+): (.+)`)
-)
+func (cli *CLI) RunE(cmd *cobra.Command, args []string) error {
+	// flags are valid at this point, runtime errors should not print the usage
+	cmd.SilenceUsage = true
 
-func searchPhraseInFile(filePath string, phraseRegexp *regexp.Regexp) (PlayerExtendedList, error) {
-	f, err := os.Open(filePath)
+	err := addRecentSearchDir(cli.cfg.SearchDir)
 	if err != nil {
-		return nil, err
+		// only used for shell completions
+		slog.Debug("failed to store recent search dir", "error", err)
 	}
-	defer f.Close()
-
-	return searchPhrase(filePath, f, phraseRegexp)
-}
-
-func searchPhrase(filePath string, f archive.File, phraseRegexp *regexp.Regexp) (PlayerExtendedList, error) {
 
-	players := make(PlayerExtendedList, 0, 16)
-
-	beginSearchOffset := 0
-	scanner := bufio.NewScanner(f)
-	scanner.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
-		advance, token, err = bufio.ScanLines(data, atEOF)
-		beginSearchOffset = -len(data)
-		return advance, token, err
-	})
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		matches := chatLineRegexp.FindStringSubmatch(line)
-		if len(matches) == 0 {
-			continue
+	if cli.cfg.IONice != "" {
+		err = setIOPriority(cli.cfg.IONiceIdle, cli.cfg.IONiceLevel)
+		if err != nil {
+			return cli.grepExitCode(cmd, 0, err)
 		}
+	}
+	cli.readLimiter = newBandwidthLimiter(cli.cfg.MaxReadBytesPerSecond)
 
-		chat := matches[3]
-		if !phraseRegexp.MatchString(chat) {
-			continue
-		}
+	if cli.cfg.DryRun {
+		// there are no matches in dry run mode, only errors are mapped to exit codes
+		return cli.grepExitCode(cmd, 1, cli.dryRun(cmd))
+	}
 
-		id, err := strconv.Atoi(matches[1])
+	if len(cli.cfg.VPNListSources) > 0 {
+		cli.vpnRanges, err = loadIPRanges(cli.ctx, cli.cfg.VPNListSources)
 		if err != nil {
-			// must match, otherwise hte regex is wrong
-			panic(err)
+			return cli.grepExitCode(cmd, 0, err)
 		}
+	}
 
-		nick := matches[2]
-
-		offset, err := f.Seek(0, io.SeekCurrent)
+	if cli.cfg.RedactIPs != "" {
+		cli.redactor, err = newIPRedactor(cli.cfg.RedactIPs, cli.cfg.RedactKey)
 		if err != nil {
-			return players, fmt.Errorf("failed to get current offset: %w", err)
+			return cli.grepExitCode(cmd, 0, err)
 		}
+	}
 
-		ip, ok, err := seekJoinLineBackwards(f, offset, beginSearchOffset, id)
+	if len(cli.cfg.KnownBansPaths) > 0 {
+		cli.knownBans, err = loadKnownBans(cli.cfg.KnownBansPaths)
 		if err != nil {
-			return players, err
+			return cli.grepExitCode(cmd, 0, err)
 		}
+	}
 
-		if !ok {
-			fmt.Printf("could not find join line for player %s with id: %d\n", nick, id)
-			continue
+	if cli.cfg.ASNDatabase != "" {
+		cli.asnDB, err = loadASNDatabase(cli.ctx, cli.cfg.ASNDatabase)
+		if err != nil {
+			return cli.grepExitCode(cmd, 0, err)
 		}
+	}
 
-		players = append(players, PlayerExtended{
-			File:     filePath,
-			Nickname: nick,
-			ID:       id,
-			IP:       ip,
-			Text:     chat,
-		})
+	if cli.cfg.Script != "" {
+		cli.script, err = loadMatchScript(cli.cfg.Script)
+		if err != nil {
+			return cli.grepExitCode(cmd, 0, err)
+		}
+		defer cli.script.Close()
 	}
 
-	if err := scanner.Err(); err != nil {
-		if !errors.Is(err, io.EOF) {
-			return players, err
+	if cli.cfg.MetricsAddress != "" {
+		shutdown, err := serveMetrics(cli.cfg.MetricsAddress)
+		if err != nil {
+			return cli.grepExitCode(cmd, 0, fmt.Errorf("failed to serve metrics: %w", err))
 		}
+		defer func() {
+			_ = shutdown()
+		}()
 	}
 
-	return players, nil
+	cli.sinks, err = cli.newSinks()
+	if err != nil {
+		return cli.grepExitCode(cmd, 0, err)
+	}
+	matches, err := cli.execute(cmd)
+	// deliver the remaining matches, even after an error
+	err = errors.Join(err, closeSinks(cli.sinks))
+	return cli.grepExitCode(cmd, matches, err)
 }
 
-func seekJoinLineBackwards(f archive.File, resetOffset int64, beginSearchOffset int, id int) (ip string, ok bool, err error) {
-	defer func() {
-		// return back to the position from which we started searching backwards
-		_, returnErr := f.Seek(resetOffset, io.SeekStart)
-		if returnErr != nil {
-			err = errors.Join(err, returnErr)
-		}
-	}()
+// execute runs the scan in the configured mode and returns the number of matches.
+// Long running modes report a single match, as only errors are mapped to exit codes.
+func (cli *CLI) execute(cmd *cobra.Command) (matches int, err error) {
+	if cli.cfg.EconAddress != "" {
+		start := time.Now()
+		err = cli.runEcon(cmd)
+		return 1, errors.Join(err, cli.audit("econ", start, cli.liveMatches, err))
+	}
 
-	// begin searching in reverse before the matched line
-	scanOffset := int(resetOffset + int64(beginSearchOffset))
-	backScanner := backscanner.New(f, scanOffset)
-	for {
-		line, _, err := backScanner.Line()
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				return "", false, nil
-			}
-			return "", false, err
-		}
+	if cli.cfg.WorkerListen != "" {
+		return 1, cli.runWorker(cmd)
+	}
 
-		ip, ok := matchJoinLineWithID(line, id)
-		if ok {
-			return ip, true, nil
-		}
+	if cli.cfg.ScheduleSpec != nil {
+		return 1, cli.runScheduled(cmd)
 	}
-}
 
-func matchJoinLineWithID(line string, id int) (ip string, ok bool) {
+	stats := &ScanStats{}
+	err = cli.run(cmd, stats, time.Time{})
+	return stats.Matches, err
+}
 
+// run scans all files that were modified after since and prints the results.
+func (cli *CLI) run(cmd *cobra.Command, stats *ScanStats, since time.Time) error {
+	start := time.Now()
 	var (
-		joinIDStr string
-		joinIP    string
+		extendedPlayerList PlayerExtendedList
+		err                error
 	)
-	if matches := ddnetJoinRegex.FindStringSubmatch(line); len(matches) != 0 {
-		joinIDStr = matches[1]
-		joinIP = matches[2]
-	} else if matches := playerzCatchJoinRegex.FindStringSubmatch(line); len(matches) != 0 {
-		joinIDStr = matches[1]
-		joinIP = matches[2]
-	} else if matches := playerVanillaJoinRegex.FindStringSubmatch(line); len(matches) != 0 {
-		joinIDStr = matches[1]
-		joinIP = matches[2]
+	// printed results are either streamed during the scan or printed after it
+	printResults := func() error {
+		return cli.printResults(cmd, extendedPlayerList, start)
+	}
+	var stream *jsonArrayWriter
+	if cli.streamsJSON() {
+		q := cli.cfg.Queries()[0]
+		stream = &jsonArrayWriter{w: cmd.OutOrStdout()}
+		cli.emit = func(players PlayerExtendedList) {
+			players = cli.formatIPs(cli.redact(cli.enrich(cli.ctx, players)))
+			cli.sendToSinks(players)
+			recordMatches(players)
+			for _, p := range players {
+				// write errors are returned when the array is closed
+				_ = stream.Write(matchOutput(q, p))
+			}
+		}
+		defer func() {
+			cli.emit = nil
+		}()
+		printResults = stream.Close
+	}
+	ctx := cli.ctx
+	if cli.cfg.Timeout > 0 {
+		// only the scan is limited, the matches found so far are still enriched and printed
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(cli.ctx, cli.cfg.Timeout, ErrScanTimeout)
+		defer cancel()
+	}
+	if len(cli.cfg.WorkerURLs) > 0 {
+		extendedPlayerList, err = cli.scanWorkers(ctx, since)
 	} else {
-		return "", false
+		extendedPlayerList, err = cli.scan(ctx, stats, since)
 	}
-
-	joinId, err := strconv.Atoi(joinIDStr)
-	if err != nil {
-		return "", false
+	if cli.cfg.RotationOverlap == config.OverlapDedupe {
+		before := len(extendedPlayerList)
+		extendedPlayerList = extendedPlayerList.WithoutOverlap()
+		if removed := before - len(extendedPlayerList); removed > 0 {
+			slog.Info("removed matches of overlapping rotated logs", "matches", removed)
+		}
 	}
-	if joinId != id {
-		return "", false
+	extendedPlayerList = cli.formatIPs(cli.redact(cli.enrich(cli.ctx, extendedPlayerList)))
+	// filters may have removed matches
+	stats.Matches = len(extendedPlayerList)
+	if stream != nil {
+		stats.Matches = stream.elements
 	}
-	return joinIP, true
-}
-
-var (
-	// 0: full 1: ID 2: IP
-	ddnetJoinRegex = regexp.MustCompile(`(?i)player has entered the game\. ClientID=([\d]+) addr=[^\d]{0,2}([\d]{1,3}\.[\d]{1,3}\.[\d]{1,3}\.[\d]{1,3})[^\d]{0,2}`)
-
-	// 0: full 1: ID 2: IP 3: port 4: version 5: name 6: clan 7: country
-	playerzCatchJoinRegex = regexp.MustCompile(`(?i)id=([\d]+) addr=([a-fA-F0-9\.\:\[\]]+):([\d]+) version=(\d+) name='(.{0,20})' clan='(.{0,16})' country=([-\d]+)$`)
-
-	// 0: full 1: ID 2: IP
-	playerVanillaJoinRegex = regexp.MustCompile(`(?i)player is ready\. ClientID=([\d]+) addr=[^\d]{0,2}([\d]{1,3}\.[\d]{1,3}\.[\d]{1,3}\.[\d]{1,3})[^\d]{0,2}`)
-)
-
-type PlayerExtended struct {
-	File     string `json:"file"`
-	Nickname string `json:"nickname"`
-	ID       int    `json:"id"`
-	IP       string `json:"ip"`
-	Text     string `json:"text"`
-}
-
-func (p PlayerExtended) String() string {
-	return fmt.Sprintf("%s: id=%d ip=%s name=%s text=%s", p.File, p.ID, p.IP, p.Nickname, p.Text)
-}
-
-type PlayerExtendedList []PlayerExtended
-
-func (p PlayerExtendedList) String() string {
-	var sb strings.Builder
-	sb.Grow(len(p) * 512)
-	for _, player := range p {
-		sb.WriteString(player.String())
-		sb.WriteByte('\n')
+	cli.sendToSinks(extendedPlayerList)
+	recordMatches(extendedPlayerList)
+	if errors.Is(err, ErrScanTimeout) {
+		// the deadline is a planned stop, the truncated results are printed like complete ones
+		slog.Warn("scan stopped at the timeout, results are truncated", "timeout", cli.cfg.Timeout, "stats", stats.String())
+		err = printResults()
+		if err == nil {
+			cli.reported.commit()
+		}
+		err = errors.Join(err, cli.writeStatsFile(stats, extendedPlayerList, start, ErrScanTimeout))
+		return errors.Join(err, cli.audit(cli.scanMode(), start, stats.Matches, ErrScanTimeout))
 	}
-	return sb.String()
-}
-
-func (p PlayerExtendedList) ToPlayerList() PlayerList {
-	players := make([]Player, 0, len(p))
-	for _, player := range p {
-		players = append(players, Player{
-			Nickname: player.Nickname,
-			IP:       player.IP,
-			Text:     player.Text,
-		})
+	if err != nil {
+		errorsTotal.Inc()
+		var printErr error
+		if stats.Matches > 0 {
+			// flush whatever we found so far, partial results are better than none
+			printErr = printResults()
+		}
+		if printErr == nil {
+			cli.reported.commit()
+		}
+		slog.Warn("scan did not complete", "stats", stats.String())
+		err = errors.Join(err, printErr)
+		err = errors.Join(err, cli.writeStatsFile(stats, extendedPlayerList, start, err))
+		return errors.Join(err, cli.audit(cli.scanMode(), start, stats.Matches, err))
 	}
-	return players
-}
 
-func (p PlayerExtendedList) ToIPList() StringList {
-	ips := make(StringList, 0, len(p))
-	for _, player := range p {
-		ips = append(ips, player.IP)
+	scanDuration.Observe(time.Since(start).Seconds())
+	err = printResults()
+	if err == nil {
+		cli.reported.commit()
 	}
-	return ips
-}
-
-type Player struct {
-	Nickname string `json:"nickname"`
-	IP       string `json:"ip"`
-	Text     string `json:"text"`
-}
-
-func (p Player) String() string {
-	return fmt.Sprintf("<{%s}> %s: %s", p.IP, p.Nickname, p.Text)
-}
-
-type PlayerList []Player
-
-func (p PlayerList) String() string {
-	var sb strings.Builder
-	sb.Grow(len(p) * 256)
-	for _, player := range p {
-		sb.WriteString(player.String())
-		sb.WriteByte('\n')
+	err = errors.Join(err, cli.writeStatsFile(stats, extendedPlayerList, start, err))
+	if err == nil {
+		// only complete scans can be reproduced
+		err = cli.writeManifest(stats, start)
+	}
+	if err == nil {
+		// the results of the complete scan were printed, there is nothing left to resume
+		err = cli.checkpoint.remove()
 	}
-	return sb.String()
+	if err == nil && cli.cfg.OpenWith != "" {
+		err = cli.openMatches(cmd, extendedPlayerList)
+	}
+	return errors.Join(err, cli.audit(cli.scanMode(), start, stats.Matches, err))
 }
 
-type StringList []string
-
-func (s StringList) String() string {
-	var sb strings.Builder
-	sb.Grow(len(s) * 64)
-	for _, str := range s {
-		sb.WriteString(str)
-		sb.WriteByte('\n')
+// scanMode is the mode of file scans in the audit log.
+func (cli *CLI) scanMode() string {
+	if cli.cfg.ScheduleSpec != nil {
+		return "schedule"
 	}
-	return sb.String()
+	return "scan"
 }
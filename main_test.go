@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRootCmdConfigFileOfArgs(t *testing.T) {
+	dir := t.TempDir()
+	logs := filepath.Join(dir, "logs")
+	err := os.Mkdir(logs, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(logs, "2024-01-01.log"), []byte(
+		"2024-01-01 12:00:01 I server: player is ready. ClientID=0 addr=<{1.2.3.4:8303}>\n"+
+			"2024-01-01 12:00:02 I chat: 0:-2:nameless tee: visit https://bot.xyz/now\n",
+	), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "config.yaml")
+	err = os.WriteFile(configFile, []byte("phrase:\n  regex: bot\nsearch:\n  dir: "+logs+"\noutput: json\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// neither the config file of the user nor the arguments of the test binary are used
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "scan",
+			args: []string{"--config-file", configFile},
+			want: `"text": "visit https://bot.xyz/now"`,
+		},
+		{
+			name: "flags override the config file",
+			args: []string{"--config-file", configFile, "-o", "text"},
+			want: "nameless tee: visit https://bot.xyz/now",
+		},
+		{
+			name: "config show",
+			args: []string{"config", "show", "--config-file", configFile},
+			want: "output: json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCmd(context.Background())
+			var stdout bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetArgs(tt.args)
+			err := cmd.Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(stdout.String(), tt.want) {
+				t.Errorf("output does not contain %q:\n%s", tt.want, stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// buildVersion returns the module version and vcs revision of the binary.
+func buildVersion() (version, revision string) {
+	info := readVersionInfo()
+	return info.Version, info.Commit
+}
+
+// writeManifest records the configuration, queries and scanned files of a complete scan, in case a manifest is configured.
+// The start time of the scan replaces the {time} placeholder.
+func (cli *CLI) writeManifest(stats *ScanStats, start time.Time) error {
+	if cli.cfg.SaveManifest == "" {
+		return nil
+	}
+
+	values, err := cli.cfg.ManifestConfig()
+	if err != nil {
+		return fmt.Errorf("failed to record configuration: %w", err)
+	}
+	// the replay may be started from another working directory
+	values["search.dir"], err = filepath.Abs(cli.cfg.SearchDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path of search dir: %w", err)
+	}
+
+	m := config.Manifest{
+		Created: start.UTC(),
+		Args:    config.RedactArgs(os.Args[1:]),
+		Config:  values,
+		Queries: cli.cfg.ManifestQueries(),
+		Files:   make([]config.ManifestFile, 0, len(stats.Paths)),
+		Matches: stats.Matches,
+	}
+	m.Version, m.Revision = buildVersion()
+
+	for _, path := range stats.Paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to record %s: %w", path, err)
+		}
+		sum, err := sha256File(path)
+		if err != nil {
+			return fmt.Errorf("failed to hash %s: %w", path, err)
+		}
+		m.Files = append(m.Files, config.ManifestFile{
+			Path:   path,
+			Size:   fi.Size(),
+			SHA256: sum,
+		})
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	err = os.WriteFile(outputPath(cli.cfg.SaveManifest, start), append(data, '\n'), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// replayFiles restricts the collected files and archives to the files of the replayed manifest.
+// Missing files and files whose content changed fail the replay, as the scan could not be reproduced.
+func (cli *CLI) replayFiles(files, archives []string) ([]string, []string, error) {
+	recorded := make(map[string]config.ManifestFile, len(cli.cfg.ReplayManifest.Files))
+	for _, f := range cli.cfg.ReplayManifest.Files {
+		recorded[f.Path] = f
+	}
+
+	found := make(map[string]struct{}, len(recorded))
+	notRecorded := func(path string) bool {
+		_, ok := recorded[path]
+		if ok {
+			found[path] = struct{}{}
+		}
+		return !ok
+	}
+	files = slices.DeleteFunc(files, notRecorded)
+	archives = slices.DeleteFunc(archives, notRecorded)
+
+	var (
+		missing []string
+		changed []string
+	)
+	for _, f := range cli.cfg.ReplayManifest.Files {
+		if _, ok := found[f.Path]; !ok {
+			missing = append(missing, f.Path)
+			continue
+		}
+		sum, err := sha256File(f.Path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to hash %s: %w", f.Path, err)
+		}
+		if sum != f.SHA256 {
+			changed = append(changed, f.Path)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("replay: %d files of the manifest are missing: %s", len(missing), strings.Join(missing, ", "))
+	}
+	if len(changed) > 0 {
+		return nil, nil, fmt.Errorf("replay: %d files changed since the manifest was saved: %s", len(changed), strings.Join(changed, ", "))
+	}
+	return files, archives, nil
+}
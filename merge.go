@@ -0,0 +1,80 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// NewMergeCmd combines the results of multiple scans, e.g. of different hosts, into a single result.
+func NewMergeCmd() *cobra.Command {
+	cfg := config.NewMergeConfig()
+
+	cmd := &cobra.Command{
+		Use:   "merge",
+		Short: "combine json or ndjson results of multiple scans into a single sorted result",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		merged := make(PlayerExtendedList, 0, 64)
+		for _, input := range cfg.Inputs {
+			players, err := readPlayersFile(input, cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			merged = append(merged, players...)
+		}
+
+		if cfg.Deduplicate {
+			merged = deduplicateFunc(merged, PlayerExtended.key)
+		}
+		sortPlayers(merged)
+
+		if cfg.Output == config.FormatText {
+			_, err := fmt.Fprint(cmd.OutOrStdout(), merged)
+			return err
+		}
+
+		data, err := json.MarshalIndent(merged, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal json result: %w", err)
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s\n", data)
+		return err
+	}
+	return cmd
+}
+
+// sortPlayers sorts the players chronologically, matches without timestamp come last.
+// Ties are broken by query, server, file and line.
+func sortPlayers(players PlayerExtendedList) {
+	slices.SortStableFunc(players, func(a, b PlayerExtended) int {
+		if (a.Time == nil) != (b.Time == nil) {
+			if a.Time == nil {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Or(
+			a.timestamp().Compare(b.timestamp()),
+			strings.Compare(a.Query, b.Query),
+			strings.Compare(a.Server, b.Server),
+			strings.Compare(a.File, b.File),
+			cmp.Compare(a.Line, b.Line),
+		)
+	})
+}
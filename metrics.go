@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	linesScanned = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "twlog_lines_scanned_total",
+		Help: "Number of log lines that were scanned.",
+	})
+	filesScanned = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "twlog_files_scanned_total",
+		Help: "Number of log files and archive files that were scanned.",
+	})
+	matchesFound = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "twlog_matches_total",
+		Help: "Number of matches by query.",
+	}, []string{"query"})
+	errorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "twlog_errors_total",
+		Help: "Number of scans that did not complete and lost econ connections.",
+	})
+	scanDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "twlog_scan_duration_seconds",
+		Help:    "Duration of complete scans of the search dir.",
+		Buckets: prometheus.ExponentialBuckets(0.1, 4, 8),
+	})
+)
+
+func recordMatches(players PlayerExtendedList) {
+	for _, p := range players {
+		matchesFound.WithLabelValues(p.Query).Inc()
+	}
+}
+
+// serveMetrics exposes the metrics at /metrics until the returned function is called.
+func serveMetrics(address string) (shutdown func() error, err error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		err := srv.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server stopped", "error", err)
+		}
+	}()
+	slog.Info("serving metrics", "address", listener.Addr().String())
+
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	}, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsPublisher publishes every match as json event as soon as it is found.
+type natsPublisher struct {
+	conn    *nats.Conn
+	subject string
+}
+
+func newNATSPublisher(url, subject string) (*natsPublisher, error) {
+	conn, err := nats.Connect(url,
+		nats.Name("twlog-who-said"),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			slog.Warn("nats connection lost", "error", err)
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			slog.Info("nats connection reestablished", "url", conn.ConnectedUrl())
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	return &natsPublisher{
+		conn:    conn,
+		subject: subject,
+	}, nil
+}
+
+// Send publishes the matches, messages are buffered by the client while reconnecting.
+func (n *natsPublisher) Send(players PlayerExtendedList) {
+	for _, p := range players {
+		data, err := json.Marshal(p)
+		if err != nil {
+			slog.Error("failed to marshal match", "sink", "nats", "error", err)
+			continue
+		}
+
+		err = n.conn.Publish(n.subject, data)
+		if err != nil {
+			slog.Error("failed to publish match", "sink", "nats", "error", err)
+		}
+	}
+}
+
+func (n *natsPublisher) Close() error {
+	defer n.conn.Close()
+	err := n.conn.FlushTimeout(sinkTimeout)
+	if err != nil && !errors.Is(err, nats.ErrConnectionClosed) {
+		return fmt.Errorf("failed to flush nats messages: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusables maps Cyrillic and Greek glyphs to the Latin letters they look like.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd',
+	'ԛ': 'q', 'ԝ': 'w', 'һ': 'h', 'ӏ': 'l',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O', 'Р': 'P',
+	'С': 'C', 'Т': 'T', 'У': 'Y', 'Х': 'X', 'І': 'I', 'Ј': 'J', 'Ѕ': 'S', 'Ү': 'Y',
+	'Ԁ': 'D', 'Ԛ': 'Q', 'Ԝ': 'W', 'Һ': 'H', 'Ӏ': 'I',
+	// Greek
+	'α': 'a', 'ο': 'o', 'ν': 'v', 'ι': 'i', 'κ': 'k', 'ρ': 'p', 'τ': 't', 'υ': 'u',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M',
+	'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+	// Latin lookalikes
+	'ı': 'i', 'ɡ': 'g', 'ℓ': 'l',
+}
+
+// normalizeName maps lookalike nicknames to a common form, so that impersonators that use
+// confusable glyphs or invisible characters match the same regexes as the original nickname.
+// The result is NFKC normalized, without zero width characters and with confusables replaced.
+func normalizeName(name string) string {
+	name = norm.NFKC.String(name)
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\u00ad', '\u034f', '\u180e', '\u200b', '\u200c', '\u200d', '\u200e', '\u200f', '\u2060', '\ufeff':
+			// soft hyphen, zero width and direction marks
+			return -1
+		}
+		if r >= '\ufe00' && r <= '\ufe0f' {
+			// variation selectors
+			return -1
+		}
+		if latin, ok := confusables[r]; ok {
+			return latin
+		}
+		return r
+	}, name)
+}
+
+// stripClanTags removes the clan tags at the start and end of the nickname, nicknames that
+// consist only of a clan tag are kept.
+func stripClanTags(name string, patterns []*regexp.Regexp) string {
+	stripped := name
+	for changed := true; changed; {
+		changed = false
+		for _, re := range patterns {
+			s := re.ReplaceAllString(stripped, "")
+			if s != stripped && strings.TrimSpace(s) != "" {
+				stripped = s
+				changed = true
+			}
+		}
+	}
+	return strings.TrimSpace(stripped)
+}
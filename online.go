@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// onlineCacheDuration is the time the master server list is reused before it is downloaded again.
+// The econ mode enriches every single match, which must not download the list every time.
+const onlineCacheDuration = time.Minute
+
+// masterServerList is the subset of the ddnet http master server list that is needed
+// in order to find out on which servers a player is currently online.
+type masterServerList struct {
+	Servers []struct {
+		Addresses []string `json:"addresses"`
+		Info      struct {
+			Name    string `json:"name"`
+			Clients []struct {
+				Name string `json:"name"`
+			} `json:"clients"`
+		} `json:"info"`
+	} `json:"servers"`
+}
+
+// onlineLookup maps nicknames to the servers they are currently playing on.
+type onlineLookup struct {
+	url string
+
+	mu        sync.Mutex
+	fetchedAt time.Time
+	servers   map[string][]string
+}
+
+func newOnlineLookup(url string) *onlineLookup {
+	return &onlineLookup{
+		url: url,
+	}
+}
+
+// Servers returns the nicknames that are currently online and the servers they are online on.
+// The previous list is kept in case the master server cannot be reached.
+func (o *onlineLookup) Servers(ctx context.Context) (map[string][]string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.servers != nil && time.Since(o.fetchedAt) < onlineCacheDuration {
+		return o.servers, nil
+	}
+
+	servers, err := o.fetch(ctx)
+	if err != nil {
+		return o.servers, err
+	}
+	o.servers = servers
+	o.fetchedAt = time.Now()
+	return o.servers, nil
+}
+
+func (o *onlineLookup) fetch(ctx context.Context) (map[string][]string, error) {
+	r, err := openSource(ctx, o.url)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var list masterServerList
+	err = json.NewDecoder(r).Decode(&list)
+	if err != nil {
+		return nil, fmt.Errorf("invalid master server list: %w", err)
+	}
+
+	servers := make(map[string][]string, 1024)
+	for _, server := range list.Servers {
+		description := server.Info.Name
+		if len(server.Addresses) > 0 {
+			description = fmt.Sprintf("%s (%s)", server.Info.Name, serverAddress(server.Addresses[0]))
+		}
+
+		for _, client := range server.Info.Clients {
+			if !slices.Contains(servers[client.Name], description) {
+				servers[client.Name] = append(servers[client.Name], description)
+			}
+		}
+	}
+	slog.Debug("fetched master server list", "servers", len(list.Servers), "players", len(servers))
+	return servers, nil
+}
+
+// serverAddress strips the protocol prefix of a master server address,
+// e.g. tw-0.6+udp://1.2.3.4:8303 becomes 1.2.3.4:8303
+func serverAddress(address string) string {
+	_, hostport, found := strings.Cut(address, "://")
+	if !found {
+		return address
+	}
+	return hostport
+}
+
+// lookupOnline adds the servers the nicknames of the players are currently online on to the players.
+// The nicknames are the only connection between the logs and the master server list, as it contains no IPs.
+func (cli *CLI) lookupOnline(ctx context.Context, players PlayerExtendedList) {
+	if cli.online == nil {
+		cli.online = newOnlineLookup(cli.cfg.MasterURL)
+	}
+
+	servers, err := cli.online.Servers(ctx)
+	if err != nil {
+		slog.Warn("failed to fetch master server list", "url", cli.cfg.MasterURL, "error", err)
+	}
+
+	for idx := range players {
+		players[idx].Online = servers[players[idx].Nickname]
+	}
+}
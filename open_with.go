@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// maxPickerTextLength is the number of characters of a message that are shown in the picker.
+const maxPickerTextLength = 60
+
+// openMatches lists the matches and opens the source file of the picked match
+// at the matched line with the --open-with command until the picker is left.
+func (cli *CLI) openMatches(cmd *cobra.Command, players PlayerExtendedList) error {
+	if !isTerminal(cmd.InOrStdin()) {
+		slog.Warn("open with requires an interactive terminal, skipping the picker")
+		return nil
+	}
+
+	// files inside of archives cannot be opened by editors
+	matches := make(PlayerExtendedList, 0, len(players))
+	for _, p := range players {
+		if p.Archive == "" && p.File != "" {
+			matches = append(matches, p)
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	p := &prompter{
+		r: bufio.NewReader(cmd.InOrStdin()),
+		w: cmd.ErrOrStderr(),
+	}
+	for {
+		fmt.Fprintln(p.w)
+		for i, m := range matches {
+			fmt.Fprintf(p.w, "%4d) %s:%d %s: %s\n", i+1, m.File, m.Line, m.Nickname, truncateText(m.Text, maxPickerTextLength))
+		}
+
+		var idx int
+		answer, err := p.ask("Open match, empty to quit", "", func(s string) error {
+			if s == "" {
+				return nil
+			}
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > len(matches) {
+				return fmt.Errorf("enter a number between 1 and %d", len(matches))
+			}
+			idx = n - 1
+			return nil
+		})
+		if err != nil || answer == "" {
+			// leaving the picker with ctrl+d is not an error
+			return nil
+		}
+
+		err = cli.openMatch(cmd, matches[idx])
+		if err != nil {
+			fmt.Fprintf(p.w, "  %v\n", err)
+		}
+	}
+}
+
+// openMatch runs the --open-with command with the file and line of the match.
+func (cli *CLI) openMatch(cmd *cobra.Command, p PlayerExtended) error {
+	line := max(p.Line, 1)
+	replacer := strings.NewReplacer("{file}", p.File, "{line}", strconv.Itoa(line))
+
+	args := make([]string, 0, len(cli.cfg.OpenWithArgs))
+	for _, arg := range cli.cfg.OpenWithArgs {
+		args = append(args, replacer.Replace(arg))
+	}
+
+	c := exec.CommandContext(cli.ctx, args[0], args[1:]...)
+	// terminal editors need the terminal, stdout may be redirected to a file
+	c.Stdin = cmd.InOrStdin()
+	c.Stdout = cmd.ErrOrStderr()
+	c.Stderr = cmd.ErrOrStderr()
+	err := c.Run()
+	if err != nil {
+		return fmt.Errorf("failed to open %s:%d: %w", p.File, line, err)
+	}
+	return nil
+}
+
+// truncateText shortens the text to n characters.
+func truncateText(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// printResults writes the matches of every query to the query's output file
+// or to stdout in case the query does not define one.
+// The start time of the scan replaces the {time} placeholder of the output file path.
+func (cli *CLI) printResults(cmd *cobra.Command, extendedPlayerList PlayerExtendedList, start time.Time) error {
+	if cli.cfg.CollapseDummies && (cli.cfg.SQL != "" || cli.cfg.Graph != "") {
+		extendedPlayerList = extendedPlayerList.CollapseDummies()
+	}
+
+	if cli.cfg.SQL != "" {
+		// the sql query aggregates the matches of all queries
+		result, err := querySQL(cli.ctx, extendedPlayerList, cli.cfg.SQL)
+		if err != nil {
+			return err
+		}
+		return cli.print(cmd.OutOrStdout(), cli.cfg.Output, result)
+	}
+
+	if cli.cfg.Graph != "" {
+		return cli.printGraph(cmd.OutOrStdout(), cli.cfg.Graph, extendedPlayerList)
+	}
+
+	var errs []error
+	for _, q := range cli.cfg.Queries() {
+		queryPlayers := extendedPlayerList.ForQuery(q.Name)
+
+		err := cli.printQueryResult(cmd.OutOrStdout(), q, queryPlayers, start)
+		if err != nil {
+			if q.Name != "" {
+				err = fmt.Errorf("query %s: %w", q.Name, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (cli *CLI) printQueryResult(stdout io.Writer, q *config.Query, extendedPlayerList PlayerExtendedList, start time.Time) (err error) {
+	w := stdout
+	if q.OutputFile != "" {
+		f, createErr := os.Create(outputPath(q.OutputFile, start))
+		if createErr != nil {
+			return fmt.Errorf("failed to create output file: %w", createErr)
+		}
+		defer func() {
+			err = errors.Join(err, f.Close())
+		}()
+		w = f
+	}
+
+	if cli.cfg.BestFinishes {
+		return cli.print(w, q.Format, extendedPlayerList.ToFinishList())
+	}
+
+	if q.CountOnly {
+		if q.Deduplicate {
+			extendedPlayerList = deduplicateFunc(extendedPlayerList.WithoutPosition(), PlayerExtended.key)
+		}
+		return cli.print(w, q.Format, extendedPlayerList.ToFileCountList())
+	}
+
+	if q.Scores {
+		if q.Deduplicate {
+			// e.g. the same log file inside and outside of an archive
+			extendedPlayerList = deduplicateFunc(extendedPlayerList.WithoutPosition(), PlayerExtended.key)
+		}
+		if cli.cfg.CollapseDummies {
+			extendedPlayerList = extendedPlayerList.CollapseDummies()
+		}
+		return cli.print(w, q.Format, extendedPlayerList.ToScoreList())
+	}
+
+	if q.SortBy != "" {
+		extendedPlayerList = extendedPlayerList.SortBy(q.SortBy)
+	}
+
+	if q.GroupBy != "" {
+		groups := extendedPlayerList.GroupBy(q.GroupBy)
+		result := make(MatchGroupList, 0, len(groups))
+		for _, group := range groups {
+			matches, count := queryMatches(q, group)
+			result = append(result, MatchGroup{
+				By:      q.GroupBy,
+				Key:     group[0].groupKey(q.GroupBy),
+				Count:   count,
+				Matches: matches,
+			})
+		}
+		return cli.print(w, q.Format, result)
+	}
+
+	matches, _ := queryMatches(q, extendedPlayerList)
+	return cli.print(w, q.Format, matches)
+}
+
+// streamsJSON returns true in case the matches can be printed as json array while they are scanned.
+// Results that are sorted, aggregated or deduplicated need all matches and are printed after the scan.
+func (cli *CLI) streamsJSON() bool {
+	queries := cli.cfg.Queries()
+	if len(queries) != 1 {
+		return false
+	}
+	q := queries[0]
+	if q.Format != config.FormatJSON || q.OutputFile != "" {
+		return false
+	}
+	if q.CountOnly || q.Scores || q.IPsOnly || q.GroupBy != "" || q.SortBy != "" || q.Deduplicate || q.MaxPerPlayer > 0 {
+		return false
+	}
+	return cli.cfg.SQL == "" &&
+		cli.cfg.Graph == "" &&
+		!cli.cfg.BestFinishes &&
+		cli.cfg.OpenWith == "" &&
+		cli.cfg.CheckpointFile == "" &&
+		cli.cfg.RotationOverlap != config.OverlapDedupe &&
+		len(cli.cfg.WorkerURLs) == 0
+}
+
+// queryMatches converts the players into the output mode of the query and returns the number of matches.
+func queryMatches(q *config.Query, extendedPlayerList PlayerExtendedList) (fmt.Stringer, int) {
+	if q.IPsOnly {
+		// unique and sorted, e.g. for firewall scripts
+		ipList := StringList(deduplicate(extendedPlayerList.ToIPList()))
+		// matches of players whose join line is not part of the logs have no IP
+		ipList = slices.DeleteFunc(ipList, func(ip string) bool { return ip == "" })
+		slices.SortFunc(ipList, compareIP)
+		if q.AggregateCIDR > 0 {
+			ipList = aggregateCIDR(ipList, q.AggregateCIDR)
+		}
+		return ipList, len(ipList)
+	}
+
+	if q.MaxPerPlayer > 0 {
+		extendedPlayerList = extendedPlayerList.MaxPerPlayer(q.MaxPerPlayer)
+	}
+	if q.Extended {
+		if !q.WithPosition {
+			extendedPlayerList = extendedPlayerList.WithoutPosition()
+		}
+		if !q.Hashes {
+			extendedPlayerList = extendedPlayerList.WithoutHashes()
+		}
+		if q.RelativeTime {
+			extendedPlayerList = extendedPlayerList.WithRelativeTime(time.Now())
+		}
+		if q.Deduplicate {
+			extendedPlayerList = deduplicateFunc(extendedPlayerList, PlayerExtended.key)
+		}
+		return extendedPlayerList, len(extendedPlayerList)
+	}
+
+	// not extended list of players
+	playerList := extendedPlayerList.ToPlayerList()
+	if q.Deduplicate {
+		playerList = deduplicateFunc(playerList, Player.key)
+	}
+	return playerList, len(playerList)
+}
+
+// printMatch writes a single match in the output mode of the query, e.g. in live modes.
+// Json matches are written as one object per line.
+func (cli *CLI) printMatch(w io.Writer, q *config.Query, p PlayerExtended) error {
+	a := matchOutput(q, p)
+	if q.Format == config.FormatJSON || q.Format == config.FormatNDJSON {
+		data, err := json.Marshal(a)
+		if err != nil {
+			return fmt.Errorf("failed to marshal json result: %w", err)
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+
+	_, err := fmt.Fprintln(w, cli.sanitize(fmt.Sprint(a)))
+	return err
+}
+
+// matchOutput converts a single match into the output mode of the query.
+func matchOutput(q *config.Query, p PlayerExtended) any {
+	switch {
+	case q.IPsOnly:
+		return p.IP
+	case q.Extended:
+		if !q.WithPosition {
+			p.Line, p.Offset = 0, 0
+		}
+		if !q.Hashes {
+			p.LineSHA256, p.FileSHA256 = "", ""
+		}
+		if q.RelativeTime && p.Time != nil {
+			p.relativeTime = relativeTime(*p.Time, time.Now())
+		}
+		return p
+	default:
+		return PlayerExtendedList{p}.ToPlayerList()[0]
+	}
+}
+
+// sanitize escapes control characters of the text output unless the raw output is requested.
+func (cli *CLI) sanitize(text string) string {
+	if cli.cfg.Raw {
+		return text
+	}
+	return sanitizeText(text)
+}
+
+// outputPath allows to create a new output file per scan, e.g. in scheduled mode.
+func outputPath(path string, start time.Time) string {
+	return strings.ReplaceAll(path, "{time}", start.Format("20060102-150405"))
+}
+
+func (cli *CLI) print(w io.Writer, format string, a any) error {
+	switch format {
+	case config.FormatText:
+		return cli.printText(w, a)
+	case config.FormatJSON:
+		return cli.printJSON(w, a)
+	case config.FormatCSV:
+		return cli.printCSV(w, a)
+	case config.FormatNDJSON:
+		return printNDJSON(w, a)
+	default:
+		// should never happen
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+func (cli *CLI) printText(w io.Writer, a any) error {
+	s := a.(fmt.Stringer) // will panic if used incorrectly
+	_, err := fmt.Fprintln(w, cli.sanitize(s.String()))
+	return err
+}
+
+// csvMarshaler is implemented by the results that support the csv output format.
+type csvMarshaler interface {
+	CSV() [][]string
+}
+
+func (cli *CLI) printCSV(w io.Writer, a any) error {
+	m, ok := a.(csvMarshaler)
+	if !ok {
+		return errors.New("the result does not support the csv output format")
+	}
+	cw := csv.NewWriter(w)
+	err := cw.WriteAll(m.CSV())
+	if err != nil {
+		return fmt.Errorf("failed to print csv result: %w", err)
+	}
+	return nil
+}
+
+func (cli *CLI) printJSON(w io.Writer, a any) error {
+	if _, ok := a.(json.Marshaler); !ok {
+		v := reflect.ValueOf(a)
+		if v.Kind() == reflect.Slice && v.Len() > 0 {
+			return printJSONArray(w, v)
+		}
+	}
+
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal json result: %w", err)
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to print json result: %w", err)
+	}
+	fmt.Fprint(w, "\n")
+	return nil
+}
+
+// printJSONArray writes the array element by element with the same indentation as json.MarshalIndent,
+// so that no second copy of the whole result is held in memory.
+func printJSONArray(w io.Writer, v reflect.Value) error {
+	a := &jsonArrayWriter{w: w}
+	for idx := range v.Len() {
+		err := a.Write(v.Index(idx).Interface())
+		if err != nil {
+			return err
+		}
+	}
+	return a.Close()
+}
+
+// jsonArrayWriter writes a json array element by element with the same indentation as json.MarshalIndent.
+// Every element is written at once, so that the output up to the last complete element can be
+// recovered in case the process is interrupted. Empty arrays are written as [].
+type jsonArrayWriter struct {
+	w        io.Writer
+	elements int
+	err      error
+}
+
+func (a *jsonArrayWriter) Write(v any) error {
+	if a.err != nil {
+		return a.err
+	}
+
+	data, err := json.MarshalIndent(v, "  ", "  ")
+	if err != nil {
+		a.err = fmt.Errorf("failed to marshal json result: %w", err)
+		return a.err
+	}
+	prefix := ",\n  "
+	if a.elements == 0 {
+		prefix = "[\n  "
+	}
+	_, err = a.w.Write(append([]byte(prefix), data...))
+	if err != nil {
+		a.err = fmt.Errorf("failed to print json result: %w", err)
+		return a.err
+	}
+	a.elements++
+	return nil
+}
+
+// Close terminates the array, the writer is not closed.
+func (a *jsonArrayWriter) Close() error {
+	if a.err != nil {
+		return a.err
+	}
+
+	end := "\n]\n"
+	if a.elements == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(a.w, end)
+	if err != nil {
+		a.err = fmt.Errorf("failed to print json result: %w", err)
+	}
+	return a.err
+}
+
+// printNDJSON writes every element of a slice as json object on its own line, e.g. for bulk imports.
+func printNDJSON(w io.Writer, a any) error {
+	elements := []any{a}
+	if v := reflect.ValueOf(a); v.Kind() == reflect.Slice {
+		elements = make([]any, 0, v.Len())
+		for idx := range v.Len() {
+			elements = append(elements, v.Index(idx).Interface())
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, e := range elements {
+		err := enc.Encode(e)
+		if err != nil {
+			return fmt.Errorf("failed to marshal json result: %w", err)
+		}
+	}
+	err := bw.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to print json result: %w", err)
+	}
+	return nil
+}
+
+func deduplicate[C comparable](items []C) []C {
+	return deduplicateFunc(items, func(item C) C { return item })
+}
+
+// deduplicateFunc removes items with the same key, the first occurrence is kept.
+func deduplicateFunc[C any, K comparable](items []C, key func(C) K) []C {
+	seen := make(map[K]struct{}, max(16, len(items)/16))
+	unique := make([]C, 0, len(items))
+
+	for _, item := range items {
+		k := key(item)
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		unique = append(unique, item)
+	}
+	return unique
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"cmp"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// overlapKey identifies a log line independent of the file it was found in.
+type overlapKey struct {
+	query, server string
+	event         string
+	time          time.Time
+	id            int
+	nickname, ip  string
+	text          string
+}
+
+// WithoutOverlap removes the matches that occur in multiple files with the same timestamp and content,
+// e.g. when copytruncate rotation copied the same lines into two files.
+// Matches without timestamps are kept, as identical lines cannot be told apart from repeated messages.
+// In case the same line occurs multiple times in a file, e.g. spam within the same second,
+// the occurrences of the file with the most occurrences are kept.
+func (p PlayerExtendedList) WithoutOverlap() PlayerExtendedList {
+	counts := make(map[overlapKey]map[string]int, len(p))
+	keys := make([]overlapKey, len(p))
+	for idx, player := range p {
+		if player.Time == nil {
+			continue
+		}
+		key := overlapKey{
+			query:    player.Query,
+			server:   player.Server,
+			event:    player.Event,
+			time:     *player.Time,
+			id:       player.ID,
+			nickname: player.Nickname,
+			ip:       player.IP,
+			text:     player.Text,
+		}
+		keys[idx] = key
+		files, ok := counts[key]
+		if !ok {
+			files = make(map[string]int, 1)
+			counts[key] = files
+		}
+		files[player.File]++
+	}
+
+	// the file whose occurrences are kept, loose files are preferred over archive members
+	// in case of equal counts, so the result does not depend on the order of the workers
+	kept := make(map[overlapKey]PlayerExtended, len(counts))
+	for idx, player := range p {
+		if player.Time == nil {
+			continue
+		}
+		key := keys[idx]
+		files := counts[key]
+		if len(files) < 2 {
+			continue
+		}
+		k, ok := kept[key]
+		if !ok || compareOverlap(files, player, k) < 0 {
+			kept[key] = player
+		}
+	}
+	if len(kept) == 0 {
+		return p
+	}
+
+	players := make(PlayerExtendedList, 0, len(p))
+	overlaps := make(map[[2]string]int, 1)
+	for idx, player := range p {
+		if player.Time != nil {
+			if k, ok := kept[keys[idx]]; ok && k.File != player.File {
+				overlaps[[2]string{k.File, player.File}]++
+				continue
+			}
+		}
+		players = append(players, player)
+	}
+	for files, n := range overlaps {
+		slog.Info("removed matches of overlapping log files", "kept", files[0], "file", files[1], "matches", n)
+	}
+	return players
+}
+
+func archived(p PlayerExtended) int {
+	if p.Archive != "" {
+		return 1
+	}
+	return 0
+}
+
+// compareOverlap sorts the file with more occurrences of a line first, then loose files before archive members.
+func compareOverlap(counts map[string]int, a, b PlayerExtended) int {
+	return cmp.Or(
+		cmp.Compare(counts[b.File], counts[a.File]),
+		cmp.Compare(archived(a), archived(b)),
+		strings.Compare(a.File, b.File),
+	)
+}
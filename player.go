@@ -0,0 +1,516 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Fields are the values of the named capture groups of the phrase regex.
+type Fields map[string]string
+
+// String returns the fields as space separated key=value pairs sorted by key.
+func (f Fields) String() string {
+	var sb strings.Builder
+	for idx, key := range slices.Sorted(maps.Keys(f)) {
+		if idx > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(key)
+		sb.WriteByte('=')
+		sb.WriteString(f[key])
+	}
+	return sb.String()
+}
+
+type PlayerExtended struct {
+	Query  string `json:"query,omitempty"`
+	Server string `json:"server,omitempty"`
+	File   string `json:"file"`
+	// Archive and Member are set when the file is located inside of an archive.
+	Archive string `json:"archive,omitempty"`
+	Member  string `json:"member,omitempty"`
+	// Event is the type of the log line, empty for chat messages.
+	Event string `json:"event,omitempty"`
+	// Map is the map that was played at the time of the match, empty when the log contains no map change before.
+	Map      string `json:"map,omitempty"`
+	Nickname string `json:"nickname"`
+	// RawNickname is the nickname including the clan tag that was removed by --strip-clan-tags.
+	RawNickname string `json:"raw_nickname,omitempty"`
+	// Dummy is set when the player was connected with another client from the same IP before,
+	// MainNickname is the nickname of that client.
+	Dummy        bool   `json:"dummy,omitempty"`
+	MainNickname string `json:"main_nickname,omitempty"`
+	// ClientVersion is the version that the client announced after connecting.
+	ClientVersion string `json:"client_version,omitempty"`
+	// FinishTime is the race time in seconds of finish events.
+	FinishTime float64 `json:"finish_time,omitempty"`
+	ID         int     `json:"id"`
+	IP         string  `json:"ip"`
+	// Port is the source port of the client, it is part of the IP with the keep-port ip format.
+	Port     int    `json:"port,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	VPN      bool   `json:"vpn,omitempty"`
+	// Banned is set when the IP is already banned in the known bans.
+	Banned bool `json:"banned,omitempty"`
+	// ASN and Organization are the autonomous system the IP belongs to.
+	ASN          uint32 `json:"asn,omitempty"`
+	Organization string `json:"organization,omitempty"`
+	// Online are the servers the nickname is currently playing on.
+	Online []string `json:"online,omitempty"`
+	Text   string   `json:"text"`
+	// TextBase64 is the original message in case it contains invalid UTF-8 and the raw policy is used.
+	TextBase64 []byte `json:"text_base64,omitempty"`
+	// Language is the detected language of the text, empty when it is not detected or unknown.
+	Language string `json:"language,omitempty"`
+	// Labels are the labels of the classifier with their scores, Toxicity is the highest score.
+	Labels   map[string]float64 `json:"labels,omitempty"`
+	Toxicity float64            `json:"toxicity,omitempty"`
+	// Line is the 1-based line number of the chat message, zero for live econ matches.
+	// It is always set for the sinks, the output only contains it with --with-position.
+	Line int `json:"line,omitempty"`
+	// Offset is the byte offset of the beginning of the chat message line.
+	Offset int64 `json:"offset,omitempty"`
+	// LineSHA256 is the checksum of the matched line without its line ending, FileSHA256 the checksum
+	// of the whole log file or archive member, empty when hashes are not requested.
+	LineSHA256 string `json:"line_sha256,omitempty"`
+	FileSHA256 string `json:"file_sha256,omitempty"`
+	// Time is the timestamp of the chat message line, nil when the log format has none.
+	Time   *time.Time `json:"time,omitempty"`
+	Fields Fields     `json:"fields,omitempty"`
+	// Category and Severity classify the match, e.g. by the pattern that matched.
+	Category string `json:"category,omitempty"`
+	Severity int    `json:"severity,omitempty"`
+	// PlayerTotal is the number of matches of the query and nickname in case --max-per-player removed some of them.
+	PlayerTotal int `json:"player_total,omitempty"`
+
+	// relativeTime replaces the timestamp in the text output
+	relativeTime string
+}
+
+// playerExtendedKey is the comparable representation of a PlayerExtended.
+type playerExtendedKey struct {
+	query, server, file string
+	nickname            string
+	archive, member     string
+	event, gameMap      string
+	dummy               bool
+	rawNickname         string
+	mainNickname        string
+	clientVersion       string
+	finishTime          float64
+	id                  int
+	ip, hostname, text  string
+	port                int
+	vpn                 bool
+	banned              bool
+	asn                 uint32
+	online              string
+	line                int
+	offset              int64
+	lineSHA256          string
+	fileSHA256          string
+	time                time.Time
+	fields              string
+	category            string
+	severity            int
+}
+
+func (p PlayerExtended) key() playerExtendedKey {
+	return playerExtendedKey{
+		query:         p.Query,
+		server:        p.Server,
+		file:          p.File,
+		archive:       p.Archive,
+		member:        p.Member,
+		event:         p.Event,
+		gameMap:       p.Map,
+		nickname:      p.Nickname,
+		rawNickname:   p.RawNickname,
+		dummy:         p.Dummy,
+		mainNickname:  p.MainNickname,
+		clientVersion: p.ClientVersion,
+		finishTime:    p.FinishTime,
+		id:            p.ID,
+		ip:            p.IP,
+		port:          p.Port,
+		hostname:      p.Hostname,
+		vpn:           p.VPN,
+		banned:        p.Banned,
+		asn:           p.ASN,
+		online:        strings.Join(p.Online, "\n"),
+		text:          p.Text,
+		line:          p.Line,
+		offset:        p.Offset,
+		lineSHA256:    p.LineSHA256,
+		fileSHA256:    p.FileSHA256,
+		time:          p.timestamp(),
+		fields:        p.Fields.String(),
+		category:      p.Category,
+		severity:      p.Severity,
+	}
+}
+
+func (p PlayerExtended) timestamp() time.Time {
+	if p.Time == nil {
+		return time.Time{}
+	}
+	return *p.Time
+}
+
+func (p PlayerExtended) String() string {
+	var sb strings.Builder
+	sb.WriteString(p.File)
+	if p.Line > 0 {
+		fmt.Fprintf(&sb, ":%d: offset=%d", p.Line, p.Offset)
+	} else {
+		sb.WriteByte(':')
+	}
+
+	if p.relativeTime != "" {
+		fmt.Fprintf(&sb, " time=%q", p.relativeTime)
+	} else if p.Time != nil {
+		sb.WriteString(" time=" + p.Time.Format(time.RFC3339))
+	}
+
+	if p.Event != "" {
+		sb.WriteString(" event=" + p.Event)
+	}
+	if p.Map != "" {
+		fmt.Fprintf(&sb, " map=%q", p.Map)
+	}
+	if p.Dummy && p.MainNickname != "" {
+		fmt.Fprintf(&sb, " dummy_of=%q", p.MainNickname)
+	} else if p.Dummy {
+		sb.WriteString(" dummy=true")
+	}
+	fmt.Fprintf(&sb, " id=%d ip=%s", p.ID, p.IP)
+	if p.ClientVersion != "" {
+		fmt.Fprintf(&sb, " version=%q", p.ClientVersion)
+	}
+	if p.FinishTime > 0 {
+		fmt.Fprintf(&sb, " finish_time=%.2fs", p.FinishTime)
+	}
+	if p.Hostname != "" {
+		sb.WriteString(" host=" + p.Hostname)
+	}
+	if p.VPN {
+		sb.WriteString(" vpn=true")
+	}
+	if p.Banned {
+		sb.WriteString(" banned=true")
+	}
+	if p.ASN != 0 {
+		fmt.Fprintf(&sb, " asn=%d org=%q", p.ASN, p.Organization)
+	}
+	if len(p.Online) > 0 {
+		fmt.Fprintf(&sb, " online=%q", strings.Join(p.Online, ", "))
+	}
+	if p.RawNickname != "" {
+		fmt.Fprintf(&sb, " raw_name=%q", p.RawNickname)
+	}
+	fmt.Fprintf(&sb, " name=%s text=%s", p.Nickname, p.Text)
+	if p.Language != "" {
+		sb.WriteString(" language=" + p.Language)
+	}
+	if len(p.Labels) > 0 {
+		labels := make([]string, 0, len(p.Labels))
+		for _, name := range slices.Sorted(maps.Keys(p.Labels)) {
+			labels = append(labels, fmt.Sprintf("%s:%.2f", name, p.Labels[name]))
+		}
+		fmt.Fprintf(&sb, " toxicity=%.2f labels=%s", p.Toxicity, strings.Join(labels, ","))
+	}
+	if p.Server != "" {
+		sb.WriteString(" server=" + p.Server)
+	}
+	if p.Category != "" {
+		sb.WriteString(" category=" + p.Category)
+	}
+	if p.Severity > 0 {
+		fmt.Fprintf(&sb, " severity=%d", p.Severity)
+	}
+	if len(p.Fields) > 0 {
+		sb.WriteString(" " + p.Fields.String())
+	}
+	if p.LineSHA256 != "" {
+		sb.WriteString(" line_sha256=" + p.LineSHA256)
+	}
+	if p.FileSHA256 != "" {
+		sb.WriteString(" file_sha256=" + p.FileSHA256)
+	}
+	if p.PlayerTotal > 0 {
+		fmt.Fprintf(&sb, " player_total=%d", p.PlayerTotal)
+	}
+	return sb.String()
+}
+
+type PlayerExtendedList []PlayerExtended
+
+func (p PlayerExtendedList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(p) * 512)
+	for _, player := range p {
+		sb.WriteString(player.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// decodePlayerExtendedList reads extended json results.
+// The results of multiple queries are printed as consecutive json arrays, which are concatenated.
+// Live modes print one json object per line, which are read as well.
+func decodePlayerExtendedList(r io.Reader) (PlayerExtendedList, error) {
+	players := make(PlayerExtendedList, 0, 64)
+	dec := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			return players, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(raw) > 0 && raw[0] == '{' {
+			var player PlayerExtended
+			err = json.Unmarshal(raw, &player)
+			if err != nil {
+				return nil, err
+			}
+			players = append(players, player)
+			continue
+		}
+
+		var list PlayerExtendedList
+		err = json.Unmarshal(raw, &list)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, list...)
+	}
+}
+
+// ForQuery returns the players that matched the query with the given name.
+func (p PlayerExtendedList) ForQuery(name string) PlayerExtendedList {
+	players := make(PlayerExtendedList, 0, len(p))
+	for _, player := range p {
+		if player.Query == name {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+// WithRelativeTime shows the timestamps of all players relative to now in the text output.
+func (p PlayerExtendedList) WithRelativeTime(now time.Time) PlayerExtendedList {
+	players := make(PlayerExtendedList, 0, len(p))
+	for _, player := range p {
+		if player.Time != nil {
+			player.relativeTime = relativeTime(*player.Time, now)
+		}
+		players = append(players, player)
+	}
+	return players
+}
+
+// WithoutHashes removes the checksums of the lines and files of all players.
+func (p PlayerExtendedList) WithoutHashes() PlayerExtendedList {
+	players := make(PlayerExtendedList, 0, len(p))
+	for _, player := range p {
+		player.LineSHA256 = ""
+		player.FileSHA256 = ""
+		players = append(players, player)
+	}
+	return players
+}
+
+// WithoutPosition removes the line numbers and byte offsets of all players.
+func (p PlayerExtendedList) WithoutPosition() PlayerExtendedList {
+	players := make(PlayerExtendedList, 0, len(p))
+	for _, player := range p {
+		player.Line = 0
+		player.Offset = 0
+		players = append(players, player)
+	}
+	return players
+}
+
+func (p PlayerExtendedList) ToPlayerList() PlayerList {
+	players := make([]Player, 0, len(p))
+	for _, player := range p {
+		players = append(players, Player{
+			Server:        player.Server,
+			Event:         player.Event,
+			Map:           player.Map,
+			Nickname:      player.Nickname,
+			Dummy:         player.Dummy,
+			ClientVersion: player.ClientVersion,
+			IP:            player.IP,
+			Hostname:      player.Hostname,
+			VPN:           player.VPN,
+			Banned:        player.Banned,
+			ASN:           player.ASN,
+			Organization:  player.Organization,
+			Online:        player.Online,
+			Text:          player.Text,
+			TextBase64:    player.TextBase64,
+			Fields:        player.Fields,
+			Category:      player.Category,
+			Severity:      player.Severity,
+			PlayerTotal:   player.PlayerTotal,
+			Language:      player.Language,
+			Labels:        player.Labels,
+			Toxicity:      player.Toxicity,
+		})
+	}
+	return players
+}
+
+func (p PlayerExtendedList) ToIPList() StringList {
+	ips := make(StringList, 0, len(p))
+	for _, player := range p {
+		ips = append(ips, player.IP)
+	}
+	return ips
+}
+
+type Player struct {
+	Server        string             `json:"server,omitempty"`
+	Event         string             `json:"event,omitempty"`
+	Map           string             `json:"map,omitempty"`
+	Nickname      string             `json:"nickname"`
+	Dummy         bool               `json:"dummy,omitempty"`
+	ClientVersion string             `json:"client_version,omitempty"`
+	IP            string             `json:"ip"`
+	Hostname      string             `json:"hostname,omitempty"`
+	VPN           bool               `json:"vpn,omitempty"`
+	Banned        bool               `json:"banned,omitempty"`
+	ASN           uint32             `json:"asn,omitempty"`
+	Organization  string             `json:"organization,omitempty"`
+	Online        []string           `json:"online,omitempty"`
+	Text          string             `json:"text"`
+	TextBase64    []byte             `json:"text_base64,omitempty"`
+	Language      string             `json:"language,omitempty"`
+	Labels        map[string]float64 `json:"labels,omitempty"`
+	Toxicity      float64            `json:"toxicity,omitempty"`
+	Fields        Fields             `json:"fields,omitempty"`
+	Category      string             `json:"category,omitempty"`
+	Severity      int                `json:"severity,omitempty"`
+	PlayerTotal   int                `json:"player_total,omitempty"`
+}
+
+// playerKey is the comparable representation of a Player.
+type playerKey struct {
+	server, event, gameMap, nickname, ip string
+	hostname, text                       string
+	fields                               string
+	category                             string
+	severity                             int
+	online                               string
+	vpn, banned, dummy                   bool
+	clientVersion                        string
+	asn                                  uint32
+}
+
+func (p Player) key() playerKey {
+	return playerKey{
+		server:        p.Server,
+		event:         p.Event,
+		gameMap:       p.Map,
+		nickname:      p.Nickname,
+		ip:            p.IP,
+		hostname:      p.Hostname,
+		vpn:           p.VPN,
+		banned:        p.Banned,
+		dummy:         p.Dummy,
+		clientVersion: p.ClientVersion,
+		asn:           p.ASN,
+		online:        strings.Join(p.Online, "\n"),
+		text:          p.Text,
+		fields:        p.Fields.String(),
+		category:      p.Category,
+		severity:      p.Severity,
+	}
+}
+
+func (p Player) String() string {
+	// additional information about the IP
+	annotations := make([]string, 0, 7)
+	if p.Hostname != "" {
+		annotations = append(annotations, p.Hostname)
+	}
+	if p.VPN {
+		annotations = append(annotations, "vpn")
+	}
+	if p.Banned {
+		annotations = append(annotations, "banned")
+	}
+	if p.Dummy {
+		annotations = append(annotations, "dummy")
+	}
+	if p.ClientVersion != "" {
+		annotations = append(annotations, "version "+p.ClientVersion)
+	}
+	if p.ASN != 0 {
+		annotations = append(annotations, fmt.Sprintf("AS%d %s", p.ASN, p.Organization))
+	}
+	if len(p.Online) > 0 {
+		annotations = append(annotations, "online on "+strings.Join(p.Online, ", "))
+	}
+
+	s := fmt.Sprintf("<{%s}> %s: %s", p.IP, p.Nickname, p.Text)
+	if len(annotations) > 0 {
+		s = fmt.Sprintf("<{%s}> (%s) %s: %s", p.IP, strings.Join(annotations, ", "), p.Nickname, p.Text)
+	}
+	if p.Server != "" {
+		s = fmt.Sprintf("[%s] %s", p.Server, s)
+	}
+	if p.Category != "" || p.Severity > 0 {
+		// classification of the chat message
+		s = fmt.Sprintf("%s [%s:%d]", s, p.Category, p.Severity)
+	}
+	if p.PlayerTotal > 0 {
+		s = fmt.Sprintf("%s (%d matches of the player in total)", s, p.PlayerTotal)
+	}
+	return s
+}
+
+type PlayerList []Player
+
+func (p PlayerList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(p) * 256)
+	for _, player := range p {
+		sb.WriteString(player.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+type StringList []string
+
+// CSV returns the strings as single column, e.g. the IPs of --ips-only for firewalls.
+func (s StringList) CSV() [][]string {
+	records := make([][]string, 0, len(s)+1)
+	records = append(records, []string{"ip"})
+	for _, str := range s {
+		records = append(records, []string{str})
+	}
+	return records
+}
+
+func (s StringList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(s) * 64)
+	for _, str := range s {
+		sb.WriteString(str)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
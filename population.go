@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// NewPopulationCmd derives the number of concurrently connected players over time from the join and leave lines.
+func NewPopulationCmd(ctx context.Context) *cobra.Command {
+	cfg := config.NewPopulationConfig()
+
+	cmd := &cobra.Command{
+		Use:   "population",
+		Short: "print the maximum number of concurrently connected players, joins and leaves per server and time interval as csv or json",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		searchDir, err := filepath.Abs(cfg.SearchDir)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path of search dir: %w", err)
+		}
+
+		files, err := listFiles(ctx, searchDir, cfg.FileRegexp)
+		if err != nil {
+			return err
+		}
+
+		p := newPopulation(cfg.Interval, cfg.BucketLocation)
+		for _, file := range files {
+			err = checkShutDown(ctx)
+			if err != nil {
+				return err
+			}
+
+			err = p.addFile(file, serverID(cfg.ServerIDRegexp, file))
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", file, err)
+			}
+		}
+
+		samples := p.Samples()
+		if len(samples) == 0 {
+			return fmt.Errorf("%w: no join or leave lines with timestamps found", ErrNoMatches)
+		}
+		if cfg.Output == config.FormatJSON {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(samples)
+		}
+		return writePopulationCSV(cmd.OutOrStdout(), samples)
+	}
+	return cmd
+}
+
+// populationSample is the population of a server within a single time interval.
+type populationSample struct {
+	// Time is the beginning and End the exclusive end of the interval in the bucket time zone.
+	Time   time.Time `json:"time"`
+	End    time.Time `json:"end"`
+	Server string    `json:"server,omitempty"`
+	// MaxPlayers is the maximum number of concurrently connected clients within the interval.
+	MaxPlayers int `json:"max_players"`
+	Joins      int `json:"joins"`
+	Leaves     int `json:"leaves"`
+}
+
+type populationKey struct {
+	server string
+	time   time.Time
+}
+
+// population aggregates the join and leave lines of all files into time buckets.
+type population struct {
+	interval time.Duration
+	location *time.Location
+	samples  map[populationKey]*populationSample
+}
+
+func newPopulation(interval time.Duration, location *time.Location) *population {
+	return &population{
+		interval: interval,
+		location: location,
+		samples:  make(map[populationKey]*populationSample, 1024),
+	}
+}
+
+// bucket returns the beginning of the interval of t. Intervals are aligned to the midnight of the bucket
+// time zone, multiples of a day are calendar days, which are 23 or 25 hours long when daylight saving time changes.
+func (p *population) bucket(t time.Time) time.Time {
+	t = t.In(p.location)
+	year, month, day := t.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, p.location)
+
+	const day24h = 24 * time.Hour
+	switch {
+	case p.interval < day24h:
+		return midnight.Add(t.Sub(midnight).Truncate(p.interval))
+	case p.interval%day24h == 0:
+		// days since the unix epoch in the bucket time zone
+		days := int(p.interval / day24h)
+		epochDay := int(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+		return midnight.AddDate(0, 0, -(epochDay % days))
+	default:
+		return t.Truncate(p.interval)
+	}
+}
+
+// next returns the beginning of the interval that follows the interval beginning at bucket.
+func (p *population) next(bucket time.Time) time.Time {
+	if p.interval%(24*time.Hour) == 0 {
+		return bucket.AddDate(0, 0, int(p.interval/(24*time.Hour)))
+	}
+	// the last interval of a day ends at midnight
+	return p.bucket(bucket.Add(p.interval))
+}
+
+func (p *population) sample(server string, t time.Time) *populationSample {
+	key := populationKey{server: server, time: t}
+	s, ok := p.samples[key]
+	if !ok {
+		s = &populationSample{Time: t, End: p.next(t), Server: server}
+		p.samples[key] = s
+	}
+	return s
+}
+
+// addFile counts the connected clients of a log file. Clients that joined before the
+// beginning of the file are unknown, which is why every file starts with zero players.
+func (p *population) addFile(file, server string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		connected = make(map[int]struct{}, 64)
+		last      time.Time
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		t, ok := parseLogTime(line)
+		if !ok {
+			continue
+		}
+		bucket := p.bucket(t)
+
+		if !last.IsZero() {
+			// intervals without join or leave lines keep the current population
+			for b := p.next(last); b.Before(bucket); b = p.next(b) {
+				s := p.sample(server, b)
+				s.MaxPlayers = max(s.MaxPlayers, len(connected))
+			}
+		}
+		last = bucket
+
+		event, ok := parseEvent(line)
+		if !ok {
+			continue
+		}
+
+		s := p.sample(server, bucket)
+		switch {
+		case event.Type == config.EventConnect && event.IP != "":
+			if _, ok := connected[event.ID]; !ok {
+				connected[event.ID] = struct{}{}
+				s.Joins++
+			}
+		case isLeaveLine(event.Text):
+			if _, ok := connected[event.ID]; ok {
+				delete(connected, event.ID)
+				s.Leaves++
+			}
+		}
+		s.MaxPlayers = max(s.MaxPlayers, len(connected))
+	}
+	return scanner.Err()
+}
+
+// Samples returns the samples sorted by server and time.
+func (p *population) Samples() []populationSample {
+	samples := make([]populationSample, 0, len(p.samples))
+	for _, s := range p.samples {
+		samples = append(samples, *s)
+	}
+	slices.SortFunc(samples, func(a, b populationSample) int {
+		return cmp.Or(
+			cmp.Compare(a.Server, b.Server),
+			a.Time.Compare(b.Time),
+		)
+	})
+	return samples
+}
+
+func writePopulationCSV(w io.Writer, samples []populationSample) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"time", "end", "server", "max_players", "joins", "leaves"})
+	if err != nil {
+		return err
+	}
+	for _, s := range samples {
+		err = cw.Write([]string{
+			s.Time.Format(time.RFC3339),
+			s.End.Format(time.RFC3339),
+			s.Server,
+			strconv.Itoa(s.MaxPlayers),
+			strconv.Itoa(s.Joins),
+			strconv.Itoa(s.Leaves),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
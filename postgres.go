@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// postgresInserter inserts matches into a postgres table that is shared by multiple scans.
+type postgresInserter struct {
+	dsn   string
+	table pgx.Identifier
+}
+
+func newPostgresInserter(dsn, table string) *postgresInserter {
+	return &postgresInserter{
+		dsn:   dsn,
+		table: pgx.Identifier{table},
+	}
+}
+
+var postgresColumns = []string{
+	"time", "query", "server", "file", "archive", "member",
+	"nickname", "client_id", "ip", "hostname", "vpn", "text",
+	"line", "byte_offset", "fields",
+}
+
+// Post inserts the matches with a single COPY statement,
+// the table is created in case it does not exist.
+func (p *postgresInserter) Post(ctx context.Context, players PlayerExtendedList) error {
+	conn, err := pgx.Connect(ctx, p.dsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id          BIGSERIAL PRIMARY KEY,
+	inserted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	time        TIMESTAMPTZ,
+	query       TEXT NOT NULL,
+	server      TEXT NOT NULL,
+	file        TEXT NOT NULL,
+	archive     TEXT NOT NULL,
+	member      TEXT NOT NULL,
+	nickname    TEXT NOT NULL,
+	client_id   INTEGER NOT NULL,
+	ip          INET,
+	hostname    TEXT NOT NULL,
+	vpn         BOOLEAN NOT NULL,
+	text        TEXT NOT NULL,
+	line        INTEGER NOT NULL,
+	byte_offset BIGINT NOT NULL,
+	fields      JSONB
+)`, p.table.Sanitize()))
+	if err != nil {
+		return fmt.Errorf("failed to create postgres table: %w", err)
+	}
+
+	rows := make([][]any, 0, len(players))
+	for _, player := range players {
+		var ip any
+		if addr, err := netip.ParseAddr(player.IP); err == nil {
+			ip = addr
+		}
+		var fields any
+		if len(player.Fields) > 0 {
+			fields = map[string]string(player.Fields)
+		}
+
+		rows = append(rows, []any{
+			player.Time, player.Query, player.Server, player.File, player.Archive, player.Member,
+			player.Nickname, player.ID, ip, player.Hostname, player.VPN, player.Text,
+			player.Line, player.Offset, fields,
+		})
+	}
+
+	_, err = conn.CopyFrom(ctx, p.table, postgresColumns, pgx.CopyFromRows(rows))
+	if err != nil {
+		return fmt.Errorf("failed to insert matches into postgres: %w", err)
+	}
+	return nil
+}
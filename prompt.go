@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// isTerminal returns true in case r is an interactive terminal, not a pipe or a file.
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// the null device is a character device as well, e.g. in cron jobs
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(fi, null)
+}
+
+// prompter asks questions on stderr, so that the results on stdout can still be redirected.
+type prompter struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+// ask returns the answer to the question or the default value in case the answer is empty.
+// The validation function is called until it accepts the answer.
+func (p *prompter) ask(question, defaultValue string, validate func(string) error) (string, error) {
+	for {
+		if defaultValue != "" {
+			fmt.Fprintf(p.w, "%s [%s]: ", question, defaultValue)
+		} else {
+			fmt.Fprintf(p.w, "%s: ", question)
+		}
+
+		line, err := p.r.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(p.w)
+				return "", errors.New("prompt aborted")
+			}
+			return "", err
+		}
+
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = defaultValue
+		}
+		if validate == nil {
+			return answer, nil
+		}
+		err = validate(answer)
+		if err == nil {
+			return answer, nil
+		}
+		fmt.Fprintf(p.w, "  %v\n", err)
+	}
+}
+
+// promptQuery asks for the phrase, the player, the time range and the search dir,
+// sets the corresponding flags and prints the equivalent command line.
+func (cli *CLI) promptQuery(cmd *cobra.Command) error {
+	p := &prompter{
+		r: bufio.NewReader(cmd.InOrStdin()),
+		w: cmd.ErrOrStderr(),
+	}
+	fmt.Fprintln(p.w, "No phrase regex given, please describe what you are looking for.")
+
+	phrase, err := p.ask("Phrase regex that a player said, e.g. (?i)free skins", "", func(s string) error {
+		if s == "" {
+			return errors.New("a phrase is required")
+		}
+		_, err := regexp.Compile(s)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	name, err := p.ask("Player name regex, empty for all players", "", func(s string) error {
+		_, err := regexp.Compile(s)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var period time.Duration
+	_, err = p.ask("Only the last hours or days, e.g. 24h or 7d, empty for all time", "", func(s string) error {
+		period, err = parsePeriod(s)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	dir, err := p.ask("Directory with the log files", cli.cfg.SearchDir, func(s string) error {
+		fi, err := os.Stat(s)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("%s is not a directory", s)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	flags := [][2]string{{"phrase-regex", phrase}}
+	if name != "" {
+		flags = append(flags, [2]string{"name-regex", name})
+	}
+	if period > 0 {
+		// matches of logs without timestamps have the zero time
+		d := period.String()
+		if period%time.Hour == 0 {
+			d = fmt.Sprintf("%dh", period/time.Hour)
+		}
+		flags = append(flags, [2]string{"where", fmt.Sprintf(`time >= now() - duration(%q)`, d)})
+	}
+	flags = append(flags, [2]string{"search-dir", dir})
+
+	args := []string{filepath.Base(os.Args[0])}
+	for _, f := range flags {
+		err = cmd.Flags().Set(f[0], f[1])
+		if err != nil {
+			return err
+		}
+		args = append(args, "--"+f[0], shellQuote(f[1]))
+	}
+	fmt.Fprintf(p.w, "\nEquivalent command line:\n  %s\n\n", strings.Join(args, " "))
+	return nil
+}
+
+// parsePeriod parses durations with an additional day unit, e.g. 7d, an empty period is zero.
+func parsePeriod(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid number of days %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid period %q, e.g. 24h or 7d", s)
+	}
+	return d, nil
+}
+
+// shellQuote quotes the argument for posix shells in case it contains special characters.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=,@%+", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostnameResolver resolves IPs to hostnames via reverse DNS lookups.
+// Results are cached for the lifetime of the process, including failed lookups.
+type hostnameResolver struct {
+	resolver    *net.Resolver
+	timeout     time.Duration
+	concurrency int
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+func newHostnameResolver(timeout time.Duration, concurrency int) *hostnameResolver {
+	return &hostnameResolver{
+		resolver:    net.DefaultResolver,
+		timeout:     timeout,
+		concurrency: concurrency,
+		cache:       make(map[string]string, 64),
+	}
+}
+
+// Resolve returns the hostnames of the given IPs.
+// IPs that could not be resolved are missing in the returned map.
+func (r *hostnameResolver) Resolve(ctx context.Context, ips []string) map[string]string {
+	hostnames := make(map[string]string, len(ips))
+	pending := make([]string, 0, len(ips))
+
+	r.mu.Lock()
+	for _, ip := range ips {
+		if _, found := hostnames[ip]; found {
+			continue
+		}
+		hostname, found := r.cache[ip]
+		if !found {
+			// mark as pending in order to deduplicate the lookups
+			hostnames[ip] = ""
+			pending = append(pending, ip)
+			continue
+		}
+		hostnames[ip] = hostname
+	}
+	r.mu.Unlock()
+
+	var (
+		wg          sync.WaitGroup
+		concurrency = make(chan struct{}, r.concurrency)
+	)
+	for _, ip := range pending {
+		if checkShutDown(ctx) != nil {
+			break
+		}
+
+		wg.Add(1)
+		concurrency <- struct{}{}
+		go func() {
+			defer func() {
+				<-concurrency
+				wg.Done()
+			}()
+
+			hostname := r.lookup(ctx, ip)
+			r.mu.Lock()
+			r.cache[ip] = hostname
+			hostnames[ip] = hostname
+			r.mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for ip, hostname := range hostnames {
+		if hostname == "" {
+			delete(hostnames, ip)
+		}
+	}
+	return hostnames
+}
+
+func (r *hostnameResolver) lookup(ctx context.Context, ip string) string {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	names, err := r.resolver.LookupAddr(ctx, ip)
+	if err != nil || len(names) == 0 {
+		slog.Debug("failed to resolve hostname", "ip", ip, "error", err)
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
+
+// resolveHostnames adds the hostnames of the IPs to the players.
+func (cli *CLI) resolveHostnames(ctx context.Context, players PlayerExtendedList) {
+	if cli.resolver == nil {
+		cli.resolver = newHostnameResolver(cli.cfg.DNSTimeout, cli.cfg.DNSConcurrency)
+	}
+
+	ips := make([]string, 0, len(players))
+	for _, player := range players {
+		ips = append(ips, player.IP)
+	}
+
+	hostnames := cli.resolver.Resolve(ctx, ips)
+	for idx := range players {
+		players[idx].Hostname = hostnames[players[idx].IP]
+	}
+}
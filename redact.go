@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+const redactedIP = "redacted"
+
+// ipRedactor masks IPs before they leave the process, e.g. in order to share findings publicly.
+type ipRedactor struct {
+	mode string
+	key  []byte
+}
+
+// newIPRedactor returns a redactor of the given mode.
+// Hashes are keyed, as the IPv4 address space is small enough to reverse plain hashes.
+func newIPRedactor(mode, key string) (*ipRedactor, error) {
+	r := &ipRedactor{
+		mode: mode,
+		key:  []byte(key),
+	}
+	if mode == config.RedactHash && key == "" {
+		r.key = make([]byte, 32)
+		_, err := rand.Read(r.key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate redaction key: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// Redact returns the redacted IP, the same IP always results in the same redacted value.
+func (r *ipRedactor) Redact(ip string) string {
+	if ip == "" {
+		return ip
+	}
+
+	switch r.mode {
+	case config.RedactPartial:
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return redactedIP
+		}
+		addr = addr.Unmap()
+		if addr.Is4() {
+			prefix, _ := addr.Prefix(24)
+			return strings.TrimSuffix(prefix.Addr().String(), "0") + "x"
+		}
+		prefix, _ := addr.Prefix(48)
+		return prefix.Addr().String() + "x"
+	case config.RedactHash:
+		mac := hmac.New(sha256.New, r.key)
+		mac.Write([]byte(ip))
+		return "h:" + hex.EncodeToString(mac.Sum(nil))[:16]
+	default:
+		return redactedIP
+	}
+}
+
+// redact returns copies of the players with redacted IPs.
+// Hostnames are removed, as they usually contain the IP.
+func (cli *CLI) redact(players PlayerExtendedList) PlayerExtendedList {
+	if cli.redactor == nil {
+		return players
+	}
+
+	redacted := make(PlayerExtendedList, 0, len(players))
+	for _, player := range players {
+		player.IP = cli.redactor.Redact(player.IP)
+		player.Hostname = ""
+		redacted = append(redacted, player)
+	}
+	return redacted
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/netip"
+	"os"
+	"slices"
+
+	"github.com/expr-lang/expr"
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// NewRefineCmd narrows down the results of an earlier scan, so iterative investigations
+// do not require another scan of the raw logs.
+func NewRefineCmd() *cobra.Command {
+	cfg := config.NewRefineConfig()
+
+	cmd := &cobra.Command{
+		Use:   "refine",
+		Short: "filter the json or ndjson results of an earlier scan by phrase, name, ip, query or where expression into a new result",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
+		cmd.SilenceUsage = true
+
+		players, err := readPlayersFile(cfg.Input, cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+		refined := refinePlayers(cfg, players)
+
+		w := cmd.OutOrStdout()
+		if cfg.OutputFile != "" {
+			f, err := os.Create(cfg.OutputFile)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer func() {
+				err = errors.Join(err, f.Close())
+			}()
+			w = f
+		}
+
+		err = writeRefined(w, cfg.Output, refined)
+		if err != nil {
+			return err
+		}
+		if len(refined) == 0 {
+			return fmt.Errorf("%w: no matches fulfill the filters", ErrNoMatches)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// refinePlayers keeps the matches that fulfill all filters.
+func refinePlayers(cfg config.RefineConfig, players PlayerExtendedList) PlayerExtendedList {
+	refined := make(PlayerExtendedList, 0, len(players))
+	for _, p := range players {
+		if len(cfg.Queries) > 0 && !slices.Contains(cfg.Queries, p.Query) {
+			continue
+		}
+		if cfg.PhraseRegexp != nil && !cfg.PhraseRegexp.MatchString(p.Text) {
+			continue
+		}
+		if cfg.NameRegexp != nil && !cfg.NameRegexp.MatchString(normalizeName(p.Nickname)) {
+			continue
+		}
+		if len(cfg.IPPrefixes) > 0 {
+			addrPort, err := parseIPOrAddrPort(p.IP)
+			if err != nil {
+				// redacted or unknown IPs
+				continue
+			}
+			addr := addrPort.Addr()
+			if !slices.ContainsFunc(cfg.IPPrefixes, func(prefix netip.Prefix) bool { return prefix.Contains(addr) }) {
+				continue
+			}
+		}
+		if cfg.WhereProgram != nil {
+			result, err := expr.Run(cfg.WhereProgram, newWhereEnv(p))
+			if err != nil {
+				slog.Warn("failed to evaluate where expression", "file", p.File, "name", p.Nickname, "error", err)
+				continue
+			}
+			if !result.(bool) {
+				continue
+			}
+		}
+		refined = append(refined, p)
+	}
+	return refined
+}
+
+func writeRefined(w io.Writer, format string, players PlayerExtendedList) error {
+	if format == config.FormatText {
+		_, err := fmt.Fprint(w, players)
+		return err
+	}
+
+	data, err := json.MarshalIndent(players, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal json result: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// NewReportCmd renders the matches of a single player as a report that can be attached to ban decisions.
+func NewReportCmd() *cobra.Command {
+	cfg := config.NewReportConfig()
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "render the matches of a player grouped by category with counts, date ranges and examples as markdown or html",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		r := cmd.InOrStdin()
+		if cfg.Input != "-" {
+			f, err := os.Open(cfg.Input)
+			if err != nil {
+				return fmt.Errorf("failed to open input: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		players, err := decodePlayerExtendedList(r)
+		if err != nil {
+			return fmt.Errorf("failed to parse json input, expected extended json results: %w", err)
+		}
+
+		report := newPlayerReport(cfg.Name, players, cfg.Examples, time.Now())
+		report.Lang = cfg.Lang
+		if len(report.Categories) == 0 {
+			return errors.New(translate(cfg.Lang, "no_matches", cfg.Name))
+		}
+		return report.Render(cmd.OutOrStdout(), cfg.Output)
+	}
+	return cmd
+}
+
+// playerReport summarizes all matches of a single player.
+type playerReport struct {
+	// Lang is the language of the texts of the rendered report.
+	Lang       string
+	Name       string
+	Generated  time.Time
+	Matches    int
+	IPs        []string
+	Servers    []string
+	Period     reportPeriod
+	Categories []*reportCategory
+}
+
+// reportCategory are the matches of a single category or query.
+type reportCategory struct {
+	Name     string
+	Matches  int
+	Period   reportPeriod
+	Examples []PlayerExtended
+}
+
+// reportPeriod is the time range of matches, zero in case the logs contain no timestamps.
+type reportPeriod struct {
+	First time.Time
+	Last  time.Time
+}
+
+func (p *reportPeriod) add(t *time.Time) {
+	if t == nil {
+		return
+	}
+	if p.First.IsZero() || t.Before(p.First) {
+		p.First = *t
+	}
+	if p.Last.IsZero() || t.After(p.Last) {
+		p.Last = *t
+	}
+}
+
+func (p reportPeriod) String() string {
+	return p.format("unknown")
+}
+
+func (p reportPeriod) format(unknown string) string {
+	const layout = "2006-01-02 15:04"
+	switch {
+	case p.First.IsZero():
+		return unknown
+	case p.First.Equal(p.Last):
+		return p.First.Format(layout)
+	default:
+		return p.First.Format(layout) + " – " + p.Last.Format(layout)
+	}
+}
+
+// newPlayerReport groups the matches of the player with the given nickname by category,
+// matches without category are grouped by query.
+func newPlayerReport(name string, players PlayerExtendedList, examples int, now time.Time) *playerReport {
+	report := &playerReport{
+		Name:      name,
+		Generated: now,
+	}
+
+	byCategory := make(map[string][]PlayerExtended, 4)
+	for _, p := range players {
+		if p.Nickname != name {
+			continue
+		}
+
+		report.Matches++
+		report.Period.add(p.Time)
+		if p.IP != "" && !slices.Contains(report.IPs, p.IP) {
+			report.IPs = append(report.IPs, p.IP)
+		}
+		if p.Server != "" && !slices.Contains(report.Servers, p.Server) {
+			report.Servers = append(report.Servers, p.Server)
+		}
+
+		category := p.Category
+		if category == "" {
+			category = p.Query
+		}
+		if category == "" {
+			category = "default"
+		}
+		byCategory[category] = append(byCategory[category], p)
+	}
+	slices.Sort(report.IPs)
+	slices.Sort(report.Servers)
+
+	for name, matches := range byCategory {
+		category := &reportCategory{
+			Name:     name,
+			Matches:  len(matches),
+			Examples: representativeExamples(matches, examples),
+		}
+		for _, m := range matches {
+			category.Period.add(m.Time)
+		}
+		report.Categories = append(report.Categories, category)
+	}
+
+	// most matches first
+	slices.SortFunc(report.Categories, func(a, b *reportCategory) int {
+		if a.Matches != b.Matches {
+			return b.Matches - a.Matches
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return report
+}
+
+// T returns the text of the key in the language of the report.
+func (r *playerReport) T(key string) string {
+	return translate(r.Lang, key)
+}
+
+// FormatPeriod returns the period with the unknown period in the language of the report.
+func (r *playerReport) FormatPeriod(p reportPeriod) string {
+	return p.format(r.T("unknown"))
+}
+
+// representativeExamples returns up to n matches with distinct messages that are spread evenly over time.
+func representativeExamples(matches []PlayerExtended, n int) []PlayerExtended {
+	distinct := make([]PlayerExtended, 0, len(matches))
+	seen := make(map[string]struct{}, len(matches))
+	for _, m := range matches {
+		if _, found := seen[m.Text]; found {
+			continue
+		}
+		seen[m.Text] = struct{}{}
+		distinct = append(distinct, m)
+	}
+
+	slices.SortStableFunc(distinct, func(a, b PlayerExtended) int {
+		return a.timestamp().Compare(b.timestamp())
+	})
+	if len(distinct) <= n {
+		return distinct
+	}
+
+	examples := make([]PlayerExtended, 0, n)
+	for i := range n {
+		idx := 0
+		if n > 1 {
+			idx = i * (len(distinct) - 1) / (n - 1)
+		}
+		examples = append(examples, distinct[idx])
+	}
+	return examples
+}
+
+// exampleLine describes where and when the example was said.
+func exampleLine(p PlayerExtended) string {
+	var sb strings.Builder
+	if p.Time != nil {
+		sb.WriteString(p.Time.Format(time.DateTime) + " ")
+	}
+	if p.Server != "" {
+		sb.WriteString("[" + p.Server + "] ")
+	}
+	if p.File != "" {
+		sb.WriteString(p.File)
+		if p.Line > 0 {
+			fmt.Fprintf(&sb, ":%d", p.Line)
+		}
+		sb.WriteString(" ")
+	}
+	sb.WriteString(p.IP + ": " + p.Text)
+	return sb.String()
+}
+
+var reportFuncs = map[string]any{
+	"example": exampleLine,
+	"join":    strings.Join,
+	// cell escapes the column separator of markdown tables
+	"cell": func(s string) string {
+		return strings.ReplaceAll(s, "|", `\|`)
+	},
+	"date": func(t time.Time) string {
+		return t.Format(time.DateTime)
+	},
+}
+
+var markdownReportTemplate = template.Must(template.New("markdown").Funcs(reportFuncs).Parse(`# {{ .T "title" }}: {{ .Name }}
+
+{{ .T "generated" }} {{ date .Generated }}
+
+| | |
+|---|---|
+| {{ .T "matches" }} | {{ .Matches }} |
+| {{ .T "period" }} | {{ .FormatPeriod .Period }} |
+| {{ .T "ips" }} | {{ cell (join .IPs ", ") }} |
+{{- if .Servers }}
+| {{ .T "servers" }} | {{ cell (join .Servers ", ") }} |
+{{- end }}
+
+| {{ .T "category" }} | {{ .T "matches" }} | {{ .T "period" }} |
+|---|---|---|
+{{- range .Categories }}
+| {{ cell .Name }} | {{ .Matches }} | {{ $.FormatPeriod .Period }} |
+{{- end }}
+{{ range .Categories }}
+## {{ .Name }}
+
+{{ .Matches }} {{ $.T "matches" }}, {{ $.FormatPeriod .Period }}
+{{ if .Examples }}
+` + "```text" + `
+{{- range .Examples }}
+{{ example . }}
+{{- end }}
+` + "```" + `
+{{ end }}
+{{- end }}`))
+
+var htmlReportTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html lang="{{ .Lang }}">
+<head>
+<meta charset="utf-8">
+<title>{{ .T "title" }}: {{ .Name }}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: auto; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; }
+pre { background: #f4f4f4; padding: 0.5em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>{{ .T "title" }}: {{ .Name }}</h1>
+<p>{{ .T "generated" }} {{ date .Generated }}</p>
+<table>
+<tr><th>{{ .T "matches" }}</th><td>{{ .Matches }}</td></tr>
+<tr><th>{{ .T "period" }}</th><td>{{ .FormatPeriod .Period }}</td></tr>
+<tr><th>{{ .T "ips" }}</th><td>{{ join .IPs ", " }}</td></tr>
+{{- if .Servers }}
+<tr><th>{{ .T "servers" }}</th><td>{{ join .Servers ", " }}</td></tr>
+{{- end }}
+</table>
+<h2>{{ .T "summary" }}</h2>
+<table>
+<tr><th>{{ .T "category" }}</th><th>{{ .T "matches" }}</th><th>{{ .T "period" }}</th></tr>
+{{- range .Categories }}
+<tr><td>{{ .Name }}</td><td>{{ .Matches }}</td><td>{{ $.FormatPeriod .Period }}</td></tr>
+{{- end }}
+</table>
+{{- range .Categories }}
+<h2>{{ .Name }}</h2>
+<p>{{ .Matches }} {{ $.T "matches" }}, {{ $.FormatPeriod .Period }}</p>
+{{- if .Examples }}
+<pre>
+{{- range .Examples }}
+{{ example . }}
+{{- end }}
+</pre>
+{{- end }}
+{{- end }}
+</body>
+</html>
+`))
+
+// Render writes the report in the given format.
+func (r *playerReport) Render(w io.Writer, format string) error {
+	var err error
+	if format == config.FormatHTML {
+		err = htmlReportTemplate.Execute(w, r)
+	} else {
+		err = markdownReportTemplate.Execute(w, r)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// isTransientReadError returns true for errors of network file systems that may succeed
+// when the file is read again, e.g. NFS timeouts, stale handles or SMB disconnects.
+func isTransientReadError(err error) bool {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	for _, transient := range transientErrnos {
+		if errno == transient {
+			return true
+		}
+	}
+	return false
+}
+
+// searchFileWithRetries searches the file and reads it again from the start after transient read errors
+// with a delay that doubles with every retry. The matches of failed attempts are discarded,
+// only the matches of the last attempt are returned together with its error.
+// The bytes of attempts that failed with read errors are not counted as scanned.
+func (cli *CLI) searchFileWithRetries(ctx context.Context, stats *ScanStats, file string, queries []*config.Query) (PlayerExtendedList, error) {
+	delay := cli.cfg.ReadRetryDelay
+	for attempt := 0; ; attempt++ {
+		var read atomic.Int64
+		players, err := searchPhraseInFile(ctx, file, queries, cli.cfg.InvalidUTF8, cli.readLimiter, &stats.Bytes, &read)
+		if err != nil && ctx.Err() == nil {
+			stats.Bytes.Add(-read.Load())
+		}
+		if err == nil || attempt >= cli.cfg.ReadRetries || !isTransientReadError(err) {
+			return players, err
+		}
+
+		slog.Warn("failed to read file, retrying", "file", file, "error", err, "attempt", attempt+1, "delay", delay)
+		stats.Retries.Add(1)
+		select {
+		case <-ctx.Done():
+			return players, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+func TestIsTransientReadError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "deadline",
+			err:  fmt.Errorf("read: %w", os.ErrDeadlineExceeded),
+			want: true,
+		},
+		{
+			name: "transient errno",
+			err:  &fs.PathError{Op: "read", Path: "server.log", Err: transientErrnos[0]},
+			want: true,
+		},
+		{
+			name: "missing file",
+			err:  &fs.PathError{Op: "open", Path: "server.log", Err: fs.ErrNotExist},
+		},
+		{
+			name: "permission",
+			err:  fs.ErrPermission,
+		},
+		{
+			name: "other",
+			err:  errors.New("bufio.Scanner: token too long"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientReadError(tt.err); got != tt.want {
+				t.Fatalf("isTransientReadError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
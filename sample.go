@@ -0,0 +1,42 @@
+package main
+
+import "log/slog"
+
+// playerQueryKey identifies the matches of a nickname within a query.
+type playerQueryKey struct {
+	query, nickname string
+}
+
+// MaxPerPlayer keeps the first n matches per query and nickname, e.g. of a spammer who hit a word list thousands of times.
+// The kept matches of capped players contain the true number of matches of the player.
+func (p PlayerExtendedList) MaxPerPlayer(n int) PlayerExtendedList {
+	totals := make(map[playerQueryKey]int, 16)
+	for _, player := range p {
+		totals[playerQueryKey{player.Query, player.Nickname}]++
+	}
+
+	kept := make(map[playerQueryKey]int, len(totals))
+	players := make(PlayerExtendedList, 0, len(p))
+	for _, player := range p {
+		key := playerQueryKey{player.Query, player.Nickname}
+		if kept[key] >= n {
+			continue
+		}
+		kept[key]++
+		if total := totals[key]; total > n {
+			player.PlayerTotal = total
+		}
+		players = append(players, player)
+	}
+
+	if dropped := len(p) - len(players); dropped > 0 {
+		capped := 0
+		for _, total := range totals {
+			if total > n {
+				capped++
+			}
+		}
+		slog.Info("limited the matches per player", "max", n, "players", capped, "dropped", dropped)
+	}
+	return players
+}
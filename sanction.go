@@ -0,0 +1,193 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+var (
+	// actor id, command and arguments of sanctions issued via rcon, e.g. ClientID=0 rcon='ban 3 10 spam'
+	rconSanctionRegexp = regexp.MustCompile(`^ClientID=(\d+) rcon='(ban|kick|mute|muteid|muteip|voteban) (.*)'$`)
+	// ip, duration and reason of net_ban lines, e.g. '1.2.3.4' banned for 5 minutes (spam) or banned '1.2.3.4' for life (spam)
+	netBanRegexp = regexp.MustCompile(`^(?:banned '([^']+)'|'([^']+)' banned) for (life|\d+ minutes?)(?: \((.*)\))?$`)
+	// id, ip and reason of kicked clients, e.g. client dropped. cid=3 addr=<{1.2.3.4:8303}> reason='Kicked (spam)'
+	kickRegexp = regexp.MustCompile(`^client dropped\. cid=(\d+) addr=<?\{?([^}>]+?)(?::\d+)?\}?>? reason='Kicked(.*)'$`)
+)
+
+// sanction is a ban, kick or mute of a player.
+type sanction struct {
+	Action string
+	// ActorID is the client that issued the sanction via rcon, -1 for the console or votes.
+	ActorID int
+	Actor   string
+	// TargetID is -1 when only the IP of the target is known.
+	TargetID int
+	TargetIP string
+	Reason   string
+	Duration string
+	rcon     bool
+}
+
+// parseSanction parses the message of rcon commands, net_ban lines and dropped clients
+// that sanction a player.
+func parseSanction(message string) (*sanction, bool) {
+	if m := rconSanctionRegexp.FindStringSubmatch(message); m != nil {
+		actorID, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, false
+		}
+		s := &sanction{
+			ActorID:  actorID,
+			TargetID: -1,
+			rcon:     true,
+		}
+
+		args := strings.Fields(m[3])
+		if len(args) == 0 {
+			return nil, false
+		}
+		if id, err := strconv.Atoi(args[0]); err == nil {
+			s.TargetID = id
+		} else {
+			s.TargetIP = args[0]
+		}
+		args = args[1:]
+
+		switch m[2] {
+		case "kick":
+			s.Action = "kick"
+		case "ban":
+			s.Action = "ban"
+			if len(args) > 0 && isNumber(args[0]) {
+				s.Duration = args[0] + "m"
+				args = args[1:]
+			}
+		default:
+			// mutes and vote bans are given in seconds
+			s.Action = "mute"
+			if len(args) > 0 && isNumber(args[0]) {
+				s.Duration = args[0] + "s"
+				args = args[1:]
+			}
+		}
+		s.Reason = strings.Join(args, " ")
+		return s, true
+	}
+
+	if m := netBanRegexp.FindStringSubmatch(message); m != nil {
+		ip := m[1]
+		if ip == "" {
+			ip = m[2]
+		}
+		duration := "permanent"
+		if m[3] != "life" {
+			minutes, _, _ := strings.Cut(m[3], " ")
+			duration = minutes + "m"
+		}
+		return &sanction{
+			Action:   "ban",
+			ActorID:  -1,
+			TargetID: -1,
+			TargetIP: ip,
+			Reason:   m[4],
+			Duration: duration,
+		}, true
+	}
+
+	if m := kickRegexp.FindStringSubmatch(message); m != nil {
+		id, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, false
+		}
+		reason := strings.TrimSpace(m[3])
+		reason = strings.TrimSuffix(strings.TrimPrefix(reason, "("), ")")
+		return &sanction{
+			Action:   "kick",
+			ActorID:  -1,
+			TargetID: id,
+			TargetIP: m[2],
+			Reason:   reason,
+		}, true
+	}
+	return nil, false
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// Fields adds the action, actor, reason and duration of the sanction to the fields of a match.
+func (s *sanction) Fields(fields Fields) Fields {
+	merged := make(Fields, len(fields)+4)
+	for k, v := range fields {
+		merged[k] = v
+	}
+	merged["action"] = s.Action
+	for k, v := range map[string]string{
+		"actor":    s.Actor,
+		"reason":   s.Reason,
+		"duration": s.Duration,
+	} {
+		if v != "" {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// Resolve adds the client id of finishes and the nicknames of the actor and the target of sanctions
+// and reports whether the event is searched. The net_ban and kick lines that follow a sanction
+// issued via rcon are not searched, as the sanction was already found with its actor.
+func (c *connections) Resolve(event *logEvent) bool {
+	if event.Type == config.EventFinish {
+		event.ID = c.idOf(event.Nickname)
+		return true
+	}
+
+	s := event.Sanction
+	if s == nil {
+		return true
+	}
+
+	if s.ActorID >= 0 {
+		if actor, ok := c.clients[s.ActorID]; ok {
+			s.Actor = actor.nickname
+		}
+		if s.Actor == "" {
+			s.Actor = strconv.Itoa(s.ActorID)
+		}
+	}
+
+	if s.TargetID >= 0 {
+		if target, ok := c.clients[s.TargetID]; ok {
+			event.Nickname = target.nickname
+			if s.TargetIP == "" {
+				s.TargetIP = target.ip
+			}
+		}
+	} else {
+		for id, target := range c.clients {
+			if target.ip == s.TargetIP {
+				event.ID = id
+				event.Nickname = target.nickname
+				break
+			}
+		}
+	}
+	event.IP = s.TargetIP
+
+	last := c.lastSanction
+	c.lastSanction = nil
+	if s.rcon {
+		c.lastSanction = s
+		return true
+	}
+	if last != nil && last.Action == s.Action && last.TargetIP != "" && last.TargetIP == s.TargetIP {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// needsEscape reports whether the rune is a control character or a bidirectional formatting character
+// that players may abuse to inject ANSI escape sequences or to reorder the text of the terminal.
+// Newlines and tabs are kept, as they are part of the text output itself.
+func needsEscape(r rune) bool {
+	switch {
+	case r == '\n' || r == '\t':
+		return false
+	case unicode.IsControl(r):
+		return true
+	case r == '\u061c', r == '\u200e', r == '\u200f',
+		r >= '\u202a' && r <= '\u202e',
+		r >= '\u2066' && r <= '\u2069':
+		return true
+	}
+	return false
+}
+
+// sanitizeText escapes control characters in the text output, e.g. ESC becomes \x1b,
+// so that nicknames and messages cannot mess up the terminal of moderators.
+func sanitizeText(s string) string {
+	if !strings.ContainsFunc(s, needsEscape) {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s) + 16)
+	for _, r := range s {
+		switch {
+		case !needsEscape(r):
+			sb.WriteRune(r)
+		case r < 0x100:
+			fmt.Fprintf(&sb, `\x%02x`, r)
+		default:
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		}
+	}
+	return sb.String()
+}
+
+// validLine applies the invalid UTF-8 policy to a log line. It returns the line with invalid bytes
+// replaced by U+FFFD, the original line in case it is to be passed through and false in case the line is skipped.
+func validLine(line, policy string) (valid, raw string, ok bool) {
+	if utf8.ValidString(line) {
+		return line, "", true
+	}
+	switch policy {
+	case config.InvalidUTF8Skip:
+		return "", "", false
+	case config.InvalidUTF8Raw:
+		raw = line
+	}
+	return strings.ToValidUTF8(line, "\uFFFD"), raw, true
+}
+
+// rawText returns the original bytes of the message of a raw line, nil when there are no invalid bytes in it.
+func rawText(raw string) []byte {
+	if raw == "" {
+		return nil
+	}
+	event, ok := parseEvent(raw)
+	if !ok || utf8.ValidString(event.Text) {
+		return nil
+	}
+	return []byte(event.Text)
+}
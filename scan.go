@@ -0,0 +1,765 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/icza/backscanner"
+	"github.com/jxsl13/twlog-who-said/archive"
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// collect returns the sorted log file and archive paths in the search dir
+// that were modified after since.
+func (cli *CLI) collect(ctx context.Context, since time.Time) (files, archives []string, err error) {
+	files = make([]string, 0, 16)
+	archives = make([]string, 0, 1)
+	infos := make(map[string]fs.FileInfo, 16)
+
+	entryDir := cli.cfg.SearchDir
+	entryDir, err = filepath.Abs(entryDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get absolute path of search dir: %w", err)
+	}
+
+	w := &walker{
+		ctx:            ctx,
+		followSymlinks: cli.cfg.FollowSymlinks,
+		oneFileSystem:  cli.cfg.OneFileSystem,
+		maxDepth:       cli.cfg.MaxDepth,
+	}
+
+	// collect log file and archive paths
+	w.walkFunc = func(path string, fi fs.FileInfo) error {
+		if !since.IsZero() && !fi.ModTime().After(since) {
+			return nil
+		}
+
+		if cli.cfg.IncludeArchives && cli.cfg.ArchiveRegexp.MatchString(path) {
+			archives = append(archives, path)
+			infos[path] = fi
+			return nil
+		}
+
+		if !cli.cfg.FileRegexp.MatchString(path) {
+			return nil
+		}
+
+		files = append(files, path)
+		infos[path] = fi
+		return nil
+	}
+
+	err = w.Walk(entryDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	sortFiles(files, infos, cli.cfg.Order)
+	sortFiles(archives, infos, cli.cfg.Order)
+	if cli.cfg.ReplayManifest != nil {
+		files, archives, err = cli.replayFiles(files, archives)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	slog.Info("collected files", "dir", entryDir, "files", len(files), "archives", len(archives))
+	return files, archives, nil
+}
+
+// sortFiles sorts the paths in the order in which they are dispatched to the workers.
+// Files that compare equal are sorted by their path.
+func sortFiles(paths []string, infos map[string]fs.FileInfo, order string) {
+	slices.SortFunc(paths, func(a, b string) int {
+		fa, fb := infos[a], infos[b]
+
+		var c int
+		switch order {
+		case config.OrderNewest:
+			c = fb.ModTime().Compare(fa.ModTime())
+		case config.OrderOldest:
+			c = fa.ModTime().Compare(fb.ModTime())
+		case config.OrderLargest:
+			c = cmp.Compare(fb.Size(), fa.Size())
+		case config.OrderSmallest:
+			c = cmp.Compare(fa.Size(), fb.Size())
+		}
+		if c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+}
+
+// queriesFor returns the queries that are applied to the file at path.
+func (cli *CLI) queriesFor(path string) []*config.Query {
+	queries := make([]*config.Query, 0, len(cli.cfg.Queries()))
+	for _, q := range cli.cfg.Queries() {
+		if q.MatchesFile(path) {
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
+
+// ErrFilesFailed is returned after a scan in which single files or archives could not be read,
+// the matches of all other files are still returned.
+var ErrFilesFailed = errors.New("files or archives could not be scanned")
+
+// scan searches all files and archives for the phrases of all queries in a single pass.
+// Only files that were modified after since are scanned, a zero since scans all files.
+// In case the scan is interrupted, the players found up to that point are returned
+// together with the cause of the interruption.
+func (cli *CLI) scan(ctx context.Context, stats *ScanStats, since time.Time) (PlayerExtendedList, error) {
+	start := time.Now()
+
+	files, archives, err := cli.collect(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	stats.TotalFiles = len(files)
+	stats.TotalArchives = len(archives)
+	stats.Paths = append(slices.Clone(files), archives...)
+
+	cp, err := cli.newCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	cli.checkpoint = cp
+	// files of the resumed checkpoint count as scanned
+	files, skipped := cp.skip(files)
+	stats.Files.Add(int64(skipped))
+	archives, skipped = cp.skip(archives)
+	stats.Archives.Add(int64(skipped))
+
+	if cli.cfg.StatusInterval > 0 {
+		stopStatus := cli.logStatus(ctx, stats, cli.cfg.StatusInterval)
+		defer stopStatus()
+	}
+
+	wg := &sync.WaitGroup{}
+	mu := &sync.Mutex{}
+	extendedPlayerList := make(PlayerExtendedList, 0, 16)
+	// keep collects the matches of a file or archive, or passes them to emit in case it is set.
+	// It must be called with mu locked.
+	keep := func(players PlayerExtendedList) {
+		if cli.emit != nil {
+			cli.emit(players)
+			return
+		}
+		extendedPlayerList = append(extendedPlayerList, players...)
+	}
+	if cp != nil {
+		extendedPlayerList = append(extendedPlayerList, cp.Matches...)
+		stats.Found.Add(int64(len(cp.Matches)))
+		stopCheckpoints := saveCheckpoints(ctx, cp, cli.cfg.CheckpointInterval)
+		defer stopCheckpoints()
+	}
+
+	// files and archives that failed are skipped, the other workers continue
+	var failed atomic.Int64
+	concurrency := make(chan struct{}, cli.cfg.Concurrency)
+	tempSpace := archive.NewTempSpace(cli.cfg.TempDir, cli.cfg.MaxTempBytes)
+	var (
+		duplicates *duplicateFiles
+		// duplicateOf maps duplicate loose files to their original
+		duplicateOf map[string]string
+	)
+	if cli.cfg.SkipDuplicateFiles {
+		// loose files are fingerprinted up front, so they are kept over their copies in archives
+		// regardless of the order in which the workers finish
+		duplicates = newDuplicateFiles()
+		duplicateOf = make(map[string]string)
+		for _, file := range files {
+			fp, err := fingerprintFile(file)
+			if err != nil {
+				// unreadable files fail when they are scanned
+				continue
+			}
+			if original, ok := duplicates.claim(fp, file); ok {
+				duplicateOf[file] = original
+			}
+		}
+	}
+
+	for _, file := range files {
+		if checkShutDown(ctx) != nil {
+			break
+		}
+
+		wg.Add(1)
+		// acquired before starting the worker, so files are dispatched in the configured order
+		concurrency <- struct{}{}
+		exec := func() {
+			defer func() {
+				<-concurrency
+				wg.Done()
+			}()
+
+			if checkShutDown(ctx) != nil {
+				return
+			}
+
+			queries := cli.queriesFor(file)
+			if len(queries) == 0 {
+				slog.Info("skipping file, no query applies to it", "file", file)
+				stats.Files.Add(1)
+				return
+			}
+
+			if original, ok := duplicateOf[file]; ok {
+				slog.Info("skipping duplicate file", "file", file, "original", original)
+				stats.Duplicates.Add(1)
+				stats.Files.Add(1)
+				return
+			}
+
+			slog.Debug("scanning file", "file", file)
+			info := cli.reported.stat(file)
+			filePlayers, err := cli.searchFileWithRetries(ctx, stats, file, queries)
+			if err != nil && checkShutDown(ctx) == nil {
+				// the file is scanned again by the next scheduled run or --resume,
+				// its partial matches would be reported twice
+				stats.addError(newFileError(file, err, FileErrorUnreadable))
+				failed.Add(1)
+				slog.Error("failed to search phrase in file, skipping it", "file", file, "error", err)
+				return
+			}
+			filePlayers = cli.reported.unreported(file, info, filePlayers)
+			setServer(filePlayers, cli.serverID(file))
+			mu.Lock()
+			keep(filePlayers)
+			mu.Unlock()
+			stats.Found.Add(int64(len(filePlayers)))
+			if err != nil {
+				// the partial matches of interrupted scans are printed
+				return
+			}
+			stats.Files.Add(1)
+			cli.reported.advance(file, info)
+			cp.complete(file, filePlayers)
+		}
+
+		if cli.cfg.Concurrency > 1 {
+			// only run in parallel if concurrency is greater than 1
+			go exec()
+		} else {
+			exec()
+		}
+	}
+
+	for _, file := range archives {
+		if checkShutDown(ctx) != nil {
+			break
+		}
+
+		wg.Add(1)
+		// acquired before starting the worker, so files are dispatched in the configured order
+		concurrency <- struct{}{}
+		exec := func() {
+			defer func() {
+				<-concurrency
+				wg.Done()
+			}()
+
+			if checkShutDown(ctx) != nil {
+				return
+			}
+
+			slog.Debug("scanning archive", "archive", file)
+			// errors of members are recorded with the member, all others with the archive
+			memberFailed := false
+			// matches of all members, members are walked sequentially
+			var archivePlayers PlayerExtendedList
+			err := archive.Walk(ctx, file, func(path string, info fs.FileInfo, r io.Reader, err error) error {
+				if err != nil {
+					return err
+				}
+
+				err = checkShutDown(ctx)
+				if err != nil {
+					return err
+				}
+
+				if !info.Mode().IsRegular() {
+					// skip dirs & symlinks
+					return nil
+				}
+
+				if !cli.cfg.FileRegexp.MatchString(path) {
+					return nil
+				}
+
+				queries := cli.queriesFor(path)
+				if len(queries) == 0 {
+					slog.Info("skipping archive file, no query applies to it", "archive", file, "file", path)
+					return nil
+				}
+
+				slog.Debug("scanning archive file", "archive", file, "file", path)
+
+				// matching file in archive
+				// extract file only if the file path matches the regex
+				memFile, cleanup, err := tempSpace.NewFile(r, info.Size())
+				if err != nil {
+					if checkShutDown(ctx) == nil {
+						memberFailed = true
+						stats.addError(newMemberError(file, path, err, FileErrorUndecodable))
+					}
+					return fmt.Errorf("failed to read file %s from archive: %w", path, err)
+				}
+
+				filePath := archivePath(file, path)
+				if duplicates != nil {
+					fp, err := fingerprint(memFile, info.Size())
+					if err == nil {
+						if original, ok := duplicates.claim(fp, filePath); ok {
+							slog.Info("skipping duplicate archive file", "archive", file, "file", path, "original", original)
+							stats.Duplicates.Add(1)
+							return cleanup()
+						}
+					}
+				}
+
+				filePlayers, err := searchPhrase(ctx, filePath, countingFile{memFile, &stats.Bytes}, queries, cli.cfg.InvalidUTF8)
+				cleanupErr := cleanup()
+				for idx := range filePlayers {
+					filePlayers[idx].Archive = file
+					filePlayers[idx].Member = path
+				}
+				setServer(filePlayers, cli.serverID(filePath))
+				archivePlayers = append(archivePlayers, filePlayers...)
+				stats.Found.Add(int64(len(filePlayers)))
+
+				if err != nil {
+					if checkShutDown(ctx) == nil {
+						memberFailed = true
+						stats.addError(newMemberError(file, path, err, FileErrorUnreadable))
+					}
+					return fmt.Errorf("failed to search phrase in archive file %s: %w", filePath, err)
+				}
+				if cleanupErr != nil {
+					return fmt.Errorf("failed to remove extracted archive file %s: %w", filePath, cleanupErr)
+				}
+				stats.Members.Add(1)
+				return nil
+			}, archive.WithReadLimit(cli.readLimiter.wait))
+			if err != nil {
+				if errors.Is(err, archive.ErrUnsupportedArchive) {
+					slog.Info("skipping unsupported archive", "archive", file)
+					stats.addError(newFileError(file, err, FileErrorUndecodable))
+					return
+				}
+				if checkShutDown(ctx) == nil {
+					if !memberFailed {
+						stats.addError(newFileError(file, err, FileErrorUndecodable))
+					}
+					failed.Add(1)
+					slog.Error("failed to walk archive, skipping it", "archive", file, "error", err)
+					// the archive is scanned again as a whole, its partial matches would be reported twice
+					stats.Found.Add(-int64(len(archivePlayers)))
+					return
+				}
+				// the partial matches of interrupted scans are printed
+				mu.Lock()
+				keep(archivePlayers)
+				mu.Unlock()
+				return
+			}
+			mu.Lock()
+			keep(archivePlayers)
+			mu.Unlock()
+			stats.Archives.Add(1)
+			cp.complete(file, archivePlayers)
+		}
+
+		if cli.cfg.Concurrency > 1 {
+			// only run in parallel if concurrency is greater than 1
+			go exec()
+		} else {
+			exec()
+		}
+	}
+	wg.Wait()
+
+	stats.ScanDuration = time.Since(start)
+	stats.Matches = len(extendedPlayerList)
+	var failedErr error
+	if n := failed.Load(); n > 0 {
+		failedErr = fmt.Errorf("%w: %d of %d", ErrFilesFailed, n, stats.TotalFiles+stats.TotalArchives)
+	}
+	// the final checkpoint allows to resume interrupted scans and is removed once the results are printed
+	return extendedPlayerList, errors.Join(checkShutDown(ctx), cp.save(), failedErr)
+}
+
+// serverID extracts the server identifier from the file path with the server id regex.
+// The first non-empty capture group is used, the whole match in case the regex has no groups.
+func (cli *CLI) serverID(path string) string {
+	return serverID(cli.cfg.ServerIDRegexp, path)
+}
+
+// serverID extracts the server from the file path, empty without server id regex.
+func serverID(re *regexp.Regexp, path string) string {
+	if re == nil {
+		return ""
+	}
+
+	matches := re.FindStringSubmatch(path)
+	if len(matches) == 0 {
+		return ""
+	}
+	for _, group := range matches[1:] {
+		if group != "" {
+			return group
+		}
+	}
+	return matches[0]
+}
+
+func setServer(players PlayerExtendedList, server string) {
+	for idx := range players {
+		players[idx].Server = server
+	}
+}
+
+// archivePath returns the path of a file inside of an archive, e.g. backups/jan.zip!server_8303/chat.log
+func archivePath(archive, member string) string {
+	return archive + "!" + member
+}
+
+// searchPhraseInFile searches the file at filePath and adds the number of read bytes to every counter.
+// The reads are throttled by the limiter, nil does not limit them.
+func searchPhraseInFile(ctx context.Context, filePath string, queries []*config.Query, invalidUTF8 string, limiter *bandwidthLimiter, counters ...*atomic.Int64) (PlayerExtendedList, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var file archive.File = f
+	for _, read := range counters {
+		file = countingFile{file, read}
+	}
+	if limiter != nil {
+		file = throttledFile{file, ctx, limiter}
+	}
+	return searchPhrase(ctx, filePath, file, queries, invalidUTF8)
+}
+
+// countingFile counts the bytes that are read sequentially, e.g. for the status lines of long scans.
+type countingFile struct {
+	archive.File
+	read *atomic.Int64
+}
+
+func (f countingFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	f.read.Add(int64(n))
+	return n, err
+}
+
+// searchPhrase returns the players that said the phrase of any of the queries in the given file.
+// Every matching query yields its own entry which is tagged with the query name.
+// When the context is canceled, the players found so far are returned together with the cause.
+func searchPhrase(ctx context.Context, filePath string, f archive.File, queries []*config.Query, invalidUTF8 string) (PlayerExtendedList, error) {
+
+	players := make(PlayerExtendedList, 0, 16)
+
+	var (
+		beginSearchOffset = 0
+		lineNumber        = 0
+		lineOffset        int64
+		consumed          int64
+		// currentMap is unknown until the first map change of the file
+		currentMap string
+		// clients are tracked in order to detect dummies
+		clients = newConnections()
+	)
+	defer func() {
+		linesScanned.Add(float64(lineNumber))
+		filesScanned.Inc()
+	}()
+	// the checksum of the file is only known once the whole file was read
+	var fileHash hash.Hash
+	if slices.ContainsFunc(queries, func(q *config.Query) bool { return q.Hashes }) {
+		fileHash = sha256.New()
+		f = hashingFile{f, fileHash}
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		advance, token, err = bufio.ScanLines(data, atEOF)
+		beginSearchOffset = -len(data)
+		if token != nil {
+			lineOffset = consumed
+		}
+		consumed += int64(advance)
+		return advance, token, err
+	})
+
+	for scanner.Scan() {
+		select {
+		case <-ctx.Done():
+			return players, context.Cause(ctx)
+		default:
+		}
+
+		lineNumber++
+		rawLine := scanner.Text()
+		line, raw, ok := validLine(rawLine, invalidUTF8)
+		if !ok {
+			continue
+		}
+		if name, ok := parseMapLine(line); ok {
+			currentMap = name
+			continue
+		}
+
+		event, ok := parseEvent(line)
+		if !ok {
+			continue
+		}
+		// sanctions are resolved before their targets leave
+		searched := clients.Resolve(&event)
+		clients.Update(line, event)
+		if !searched {
+			continue
+		}
+
+		matchedQueries := matchQueries(queries, event.Type, event.Text)
+		if len(matchedQueries) == 0 {
+			continue
+		}
+
+		ip := event.IP
+		if ip == "" {
+			offset, err := f.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return players, fmt.Errorf("failed to get current offset: %w", err)
+			}
+
+			ip, ok, err = seekJoinLineBackwards(f, offset, beginSearchOffset, event.ID)
+			if err != nil {
+				return players, err
+			}
+
+			if !ok {
+				slog.Warn("could not find join line", "file", filePath, "name", event.Nickname, "id", event.ID)
+				continue
+			}
+		}
+
+		var timestamp *time.Time
+		if t, ok := parseLogTime(line); ok {
+			timestamp = &t
+		}
+
+		var lineSHA256 string
+		if fileHash != nil {
+			sum := sha256.Sum256([]byte(rawLine))
+			lineSHA256 = hex.EncodeToString(sum[:])
+		}
+
+		dummy, mainNickname := clients.MainPlayer(event.ID)
+		for _, m := range matchedQueries {
+			fields := m.fields
+			if event.Sanction != nil {
+				fields = event.Sanction.Fields(fields)
+			}
+			players = append(players, PlayerExtended{
+				Query:         m.name,
+				File:          filePath,
+				Event:         eventName(event.Type),
+				Map:           currentMap,
+				Nickname:      event.Nickname,
+				Dummy:         dummy,
+				MainNickname:  mainNickname,
+				ClientVersion: clients.Version(event.ID),
+				FinishTime:    event.FinishTime.Seconds(),
+				ID:            event.ID,
+				IP:            ip,
+				Port:          clients.Port(event.ID),
+				Text:          event.Text,
+				TextBase64:    rawText(raw),
+				Line:          lineNumber,
+				Offset:        lineOffset,
+				LineSHA256:    lineSHA256,
+				Time:          timestamp,
+				Fields:        fields,
+				Category:      m.category,
+				Severity:      m.severity,
+			})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if !errors.Is(err, io.EOF) {
+			return players, err
+		}
+	}
+
+	if fileHash != nil {
+		fileSHA256 := hex.EncodeToString(fileHash.Sum(nil))
+		for idx := range players {
+			players[idx].FileSHA256 = fileSHA256
+		}
+	}
+	return players, nil
+}
+
+// hashingFile adds the sequentially read bytes to the hash.
+type hashingFile struct {
+	archive.File
+	h hash.Hash
+}
+
+func (f hashingFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	f.h.Write(p[:n])
+	return n, err
+}
+
+// queryMatch is a query whose phrase regex matched a chat message.
+type queryMatch struct {
+	name     string
+	fields   Fields
+	category string
+	severity int
+}
+
+// matchQueries returns the queries of the event type whose phrase regex matches the text of the event.
+func matchQueries(queries []*config.Query, eventType, chat string) []queryMatch {
+	matchedQueries := make([]queryMatch, 0, 1)
+	for _, q := range queries {
+		if !q.MatchesEvent(eventType) {
+			continue
+		}
+		groups := q.PhraseRegexp.FindStringSubmatch(chat)
+		if groups == nil {
+			continue
+		}
+
+		category, severity := q.Classify(chat)
+		if severity < q.MinSeverity {
+			continue
+		}
+		matchedQueries = append(matchedQueries, queryMatch{
+			name:     q.Name,
+			fields:   namedGroups(q.PhraseRegexp, groups),
+			category: category,
+			severity: severity,
+		})
+	}
+	return matchedQueries
+}
+
+// namedGroups returns the values of the named capture groups that participated in the match.
+func namedGroups(re *regexp.Regexp, groups []string) Fields {
+	var fields Fields
+	for idx, name := range re.SubexpNames() {
+		if name == "" || groups[idx] == "" {
+			continue
+		}
+		if fields == nil {
+			fields = make(Fields, 1)
+		}
+		fields[name] = groups[idx]
+	}
+	return fields
+}
+
+func seekJoinLineBackwards(f archive.File, resetOffset int64, beginSearchOffset int, id int) (ip string, ok bool, err error) {
+	defer func() {
+		// return back to the position from which we started searching backwards
+		_, returnErr := f.Seek(resetOffset, io.SeekStart)
+		if returnErr != nil {
+			err = errors.Join(err, returnErr)
+		}
+	}()
+
+	// begin searching in reverse before the matched line
+	scanOffset := int(resetOffset + int64(beginSearchOffset))
+	backScanner := backscanner.New(f, scanOffset)
+	for {
+		line, _, err := backScanner.Line()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", false, nil
+			}
+			return "", false, err
+		}
+
+		ip, ok := matchJoinLineWithID(line, id)
+		if ok {
+			return ip, true, nil
+		}
+	}
+}
+
+func matchJoinLineWithID(line string, id int) (ip string, ok bool) {
+	joinID, joinIP, _, ok := parseJoinLine(line)
+	if !ok || joinID != id {
+		return "", false
+	}
+	return joinIP, true
+}
+
+// parseJoinLine returns the client id, IP and port of a join line, the port is zero when it is unknown.
+func parseJoinLine(line string) (id int, ip string, port int, ok bool) {
+
+	var (
+		joinIDStr   string
+		joinIP      string
+		joinPortStr string
+	)
+	if matches := ddnetJoinRegex.FindStringSubmatch(line); len(matches) != 0 {
+		joinIDStr = matches[1]
+		joinIP = matches[2]
+		joinPortStr = matches[3]
+	} else if matches := playerzCatchJoinRegex.FindStringSubmatch(line); len(matches) != 0 {
+		joinIDStr = matches[1]
+		joinIP = matches[2]
+		joinPortStr = matches[3]
+	} else if matches := playerVanillaJoinRegex.FindStringSubmatch(line); len(matches) != 0 {
+		joinIDStr = matches[1]
+		joinIP = matches[2]
+		joinPortStr = matches[3]
+	} else {
+		return 0, "", 0, false
+	}
+
+	joinID, err := strconv.Atoi(joinIDStr)
+	if err != nil {
+		return 0, "", 0, false
+	}
+	joinPort, _ := strconv.Atoi(joinPortStr)
+	return joinID, joinIP, joinPort, true
+}
+
+var (
+	// 0: full 1: ID 2: IP 3: port
+	ddnetJoinRegex = regexp.MustCompile(`(?i)player has entered the game\. ClientID=([\d]+) addr=[^\d]{0,2}([\d]{1,3}\.[\d]{1,3}\.[\d]{1,3}\.[\d]{1,3})(?::(\d+))?[^\d]{0,2}`)
+
+	// 0: full 1: ID 2: IP 3: port 4: version 5: name 6: clan 7: country
+	playerzCatchJoinRegex = regexp.MustCompile(`(?i)id=([\d]+) addr=([a-fA-F0-9\.\:\[\]]+):([\d]+) version=(\d+) name='(.{0,20})' clan='(.{0,16})' country=([-\d]+)$`)
+
+	// 0: full 1: ID 2: IP 3: port
+	playerVanillaJoinRegex = regexp.MustCompile(`(?i)player is ready\. ClientID=([\d]+) addr=[^\d]{0,2}([\d]{1,3}\.[\d]{1,3}\.[\d]{1,3}\.[\d]{1,3})(?::(\d+))?[^\d]{0,2}`)
+)
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// runScheduled keeps running and scans the files that were modified since the previous run
+// whenever the cron schedule fires. The first run scans the files that were modified
+// after the program was started. Only the lines that were appended to a file since the
+// previous run are reported.
+func (cli *CLI) runScheduled(cmd *cobra.Command) error {
+	since := time.Now()
+	cli.reported = newReportedOffsets()
+	files, _, err := cli.collect(cli.ctx, time.Time{})
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		// lines that were written before the start are not reported
+		cli.reported.advance(file, cli.reported.stat(file))
+	}
+	cli.reported.commit()
+
+	for {
+		next := cli.cfg.ScheduleSpec.Next(time.Now())
+		slog.Info("waiting for next scheduled scan", "next", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-cli.ctx.Done():
+			timer.Stop()
+			slog.Info("stopping scheduled scans", "reason", context.Cause(cli.ctx))
+			return nil
+		case <-timer.C:
+		}
+
+		start := time.Now()
+		err = cli.run(cmd, &ScanStats{}, since)
+		// offsets of runs that did not print their results are reported again
+		cli.reported.discard()
+		if err != nil {
+			if checkShutDown(cli.ctx) != nil {
+				return err
+			}
+			// a failed run must not stop future runs, the next run scans the same files again
+			// and reports the lines that were not printed by this run
+			slog.Error("scheduled scan failed", "error", err)
+			continue
+		}
+		since = start
+	}
+}
+
+// reportedOffset is the offset of a file up to which its lines were reported.
+type reportedOffset struct {
+	info   fs.FileInfo
+	offset int64
+}
+
+// reportedOffsets remembers up to which offset the lines of growing log files were reported by
+// scheduled runs. Files are still read from their start, so that join lines, map changes and
+// dummies before the offset are known, but only the matches of the new lines are reported.
+// The offsets of scanned files are pending until the results of the run were printed.
+type reportedOffsets struct {
+	mu      sync.Mutex
+	files   map[string]reportedOffset
+	pending map[string]reportedOffset
+}
+
+func newReportedOffsets() *reportedOffsets {
+	return &reportedOffsets{
+		files:   make(map[string]reportedOffset),
+		pending: make(map[string]reportedOffset),
+	}
+}
+
+// stat returns the file info of the file before it is read, nil when offsets are not tracked.
+// Lines that are appended after the stat are reported by the next run.
+func (o *reportedOffsets) stat(file string) fs.FileInfo {
+	if o == nil {
+		return nil
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		// the scan of the file reports the error
+		return nil
+	}
+	return info
+}
+
+// unreported removes the matches of lines that were reported by previous runs and of lines
+// that were appended after info was taken. Truncated and replaced files, e.g. after log rotation,
+// are reported from their start.
+func (o *reportedOffsets) unreported(file string, info fs.FileInfo, players PlayerExtendedList) PlayerExtendedList {
+	if o == nil || info == nil {
+		return players
+	}
+
+	o.mu.Lock()
+	prev, found := o.files[file]
+	o.mu.Unlock()
+
+	var start int64
+	if found && os.SameFile(prev.info, info) && info.Size() >= prev.offset {
+		start = prev.offset
+	}
+	return slices.DeleteFunc(players, func(p PlayerExtended) bool {
+		return p.Offset < start || p.Offset >= info.Size()
+	})
+}
+
+// advance marks the lines of the file up to its size at info as reported once the offsets are committed.
+func (o *reportedOffsets) advance(file string, info fs.FileInfo) {
+	if o == nil || info == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending[file] = reportedOffset{
+		info:   info,
+		offset: info.Size(),
+	}
+}
+
+// commit applies the pending offsets after the results of the run were printed.
+func (o *reportedOffsets) commit() {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for file, offset := range o.pending {
+		o.files[file] = offset
+	}
+	clear(o.pending)
+}
+
+// discard drops the pending offsets of a run whose results were not printed.
+func (o *reportedOffsets) discard() {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	clear(o.pending)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// PlayerScore is the sum of the severities of all matches of a player.
+type PlayerScore struct {
+	Nickname string   `json:"nickname"`
+	IPs      []string `json:"ips"`
+	Score    int      `json:"score"`
+	Matches  int      `json:"matches"`
+	// Categories is the score per category.
+	Categories map[string]int `json:"categories,omitempty"`
+}
+
+func (p PlayerScore) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "score=%d matches=%d name=%s ips=%s", p.Score, p.Matches, p.Nickname, strings.Join(p.IPs, ","))
+	if len(p.Categories) > 0 {
+		sb.WriteString(" categories=")
+		for idx, category := range slices.Sorted(maps.Keys(p.Categories)) {
+			if idx > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(&sb, "%s:%d", category, p.Categories[category])
+		}
+	}
+	return sb.String()
+}
+
+type PlayerScoreList []PlayerScore
+
+func (p PlayerScoreList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(p) * 128)
+	for _, score := range p {
+		sb.WriteString(score.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// ToScoreList aggregates the severities of the matches per nickname, highest scores first.
+func (p PlayerExtendedList) ToScoreList() PlayerScoreList {
+	byName := make(map[string]*PlayerScore, len(p))
+	for _, player := range p {
+		score, found := byName[player.Nickname]
+		if !found {
+			score = &PlayerScore{
+				Nickname: player.Nickname,
+			}
+			byName[player.Nickname] = score
+		}
+
+		score.Score += player.Severity
+		score.Matches++
+		if player.IP != "" && !slices.Contains(score.IPs, player.IP) {
+			score.IPs = append(score.IPs, player.IP)
+		}
+		if player.Category != "" {
+			if score.Categories == nil {
+				score.Categories = make(map[string]int, 1)
+			}
+			score.Categories[player.Category] += player.Severity
+		}
+	}
+
+	scores := make(PlayerScoreList, 0, len(byName))
+	for _, score := range byName {
+		slices.Sort(score.IPs)
+		scores = append(scores, *score)
+	}
+	slices.SortFunc(scores, func(a, b PlayerScore) int {
+		if a.Score != b.Score {
+			return b.Score - a.Score
+		}
+		return strings.Compare(a.Nickname, b.Nickname)
+	})
+	return scores
+}
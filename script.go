@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// matchScript is a lua script whose global function match is called for every match.
+// The function receives the match as table and returns false or nil to drop it,
+// true to keep it or a table with the modified match.
+type matchScript struct {
+	state *lua.LState
+	fn    lua.LValue
+}
+
+func loadMatchScript(path string) (*matchScript, error) {
+	state := lua.NewState()
+	err := state.DoFile(path)
+	if err != nil {
+		state.Close()
+		return nil, fmt.Errorf("failed to load script %s: %w", path, err)
+	}
+
+	fn := state.GetGlobal("match")
+	if fn.Type() != lua.LTFunction {
+		state.Close()
+		return nil, fmt.Errorf("script %s does not define a function match", path)
+	}
+	return &matchScript{
+		state: state,
+		fn:    fn,
+	}, nil
+}
+
+func (s *matchScript) Close() {
+	s.state.Close()
+}
+
+// Apply calls the script for every match. Matches whose script call fails are dropped.
+func (s *matchScript) Apply(players PlayerExtendedList) PlayerExtendedList {
+	kept := make(PlayerExtendedList, 0, len(players))
+	for _, p := range players {
+		err := s.state.CallByParam(lua.P{
+			Fn:      s.fn,
+			NRet:    1,
+			Protect: true,
+		}, s.toTable(p))
+		if err != nil {
+			slog.Warn("failed to execute script", "file", p.File, "name", p.Nickname, "error", err)
+			continue
+		}
+		ret := s.state.Get(-1)
+		s.state.Pop(1)
+
+		switch v := ret.(type) {
+		case *lua.LTable:
+			kept = append(kept, fromTable(p, v))
+		case lua.LBool:
+			if v {
+				kept = append(kept, p)
+			}
+		case *lua.LNilType:
+			// dropped
+		default:
+			slog.Warn("script returned an invalid value, expected a table, boolean or nil", "type", ret.Type().String())
+		}
+	}
+	return kept
+}
+
+func (s *matchScript) toTable(p PlayerExtended) *lua.LTable {
+	t := s.state.NewTable()
+	t.RawSetString("query", lua.LString(p.Query))
+	t.RawSetString("server", lua.LString(p.Server))
+	t.RawSetString("file", lua.LString(p.File))
+	t.RawSetString("event", lua.LString(p.Event))
+	t.RawSetString("map", lua.LString(p.Map))
+	t.RawSetString("nickname", lua.LString(p.Nickname))
+	t.RawSetString("dummy", lua.LBool(p.Dummy))
+	t.RawSetString("main_nickname", lua.LString(p.MainNickname))
+	t.RawSetString("client_version", lua.LString(p.ClientVersion))
+	t.RawSetString("finish_time", lua.LNumber(p.FinishTime))
+	t.RawSetString("id", lua.LNumber(p.ID))
+	t.RawSetString("ip", lua.LString(p.IP))
+	t.RawSetString("hostname", lua.LString(p.Hostname))
+	t.RawSetString("vpn", lua.LBool(p.VPN))
+	t.RawSetString("banned", lua.LBool(p.Banned))
+	t.RawSetString("asn", lua.LNumber(p.ASN))
+	t.RawSetString("organization", lua.LString(p.Organization))
+	t.RawSetString("text", lua.LString(p.Text))
+	t.RawSetString("line", lua.LNumber(p.Line))
+	if p.Time != nil {
+		t.RawSetString("time", lua.LString(p.Time.Format(time.RFC3339)))
+	}
+	t.RawSetString("category", lua.LString(p.Category))
+	t.RawSetString("severity", lua.LNumber(p.Severity))
+
+	fields := s.state.NewTable()
+	for k, v := range p.Fields {
+		fields.RawSetString(k, lua.LString(v))
+	}
+	t.RawSetString("fields", fields)
+	return t
+}
+
+// fromTable applies the modifiable fields of the table to the match.
+func fromTable(p PlayerExtended, t *lua.LTable) PlayerExtended {
+	p.Server = lua.LVAsString(t.RawGetString("server"))
+	p.Nickname = lua.LVAsString(t.RawGetString("nickname"))
+	p.Text = lua.LVAsString(t.RawGetString("text"))
+	p.Category = lua.LVAsString(t.RawGetString("category"))
+	p.Severity = int(lua.LVAsNumber(t.RawGetString("severity")))
+
+	p.Fields = nil
+	if fields, ok := t.RawGetString("fields").(*lua.LTable); ok {
+		fields.ForEach(func(k, v lua.LValue) {
+			if p.Fields == nil {
+				p.Fields = make(Fields, 1)
+			}
+			p.Fields[k.String()] = v.String()
+		})
+	}
+	return p
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// sinkTimeout limits the time a sink may take to deliver a batch of matches.
+const sinkTimeout = 30 * time.Second
+
+// Sink receives the matches in addition to the regular output, e.g. to notify moderators.
+type Sink interface {
+	// Send queues the matches for delivery, it must not block.
+	Send(players PlayerExtendedList)
+	// Close delivers the remaining matches.
+	Close() error
+}
+
+// newSinks creates the sinks that are enabled in the config.
+func (cli *CLI) newSinks() ([]Sink, error) {
+	sinks := make([]Sink, 0, 1)
+	if cli.cfg.NotifyDiscord != "" {
+		sinks = append(sinks, cli.alertFilter(newBatchSink("discord", cli.cfg.NotifyInterval, cli.notifyFilter(newDiscordWebhook(cli.cfg.NotifyDiscord).Post))))
+	}
+	if cli.cfg.NotifyTelegram != "" {
+		bot := newTelegramBot(cli.cfg.TelegramToken, cli.cfg.TelegramChatID)
+		sinks = append(sinks, cli.alertFilter(newBatchSink("telegram", cli.cfg.NotifyInterval, cli.notifyFilter(bot.Post))))
+	}
+	if cli.cfg.WebhookURL != "" {
+		hook := newWebhook(cli.cfg.WebhookURL, cli.cfg.WebhookHTTPHeaders, cli.cfg.WebhookBodyTemplate, cli.cfg.WebhookRetries)
+		sinks = append(sinks, cli.alertFilter(newBatchSink("webhook", cli.cfg.NotifyInterval, hook.Post)))
+	}
+	if cli.cfg.ElasticsearchURL != "" {
+		indexer := newElasticsearchIndexer(cli.cfg.ElasticsearchURL, cli.cfg.ElasticsearchIndex, cli.cfg.ElasticsearchUsername, cli.cfg.ElasticsearchPassword)
+		sinks = append(sinks, newBatchSink("elasticsearch", cli.cfg.NotifyInterval, indexer.Post))
+	}
+	if cli.cfg.LokiURL != "" {
+		pusher := newLokiPusher(cli.cfg.LokiURL, cli.cfg.LokiTenant)
+		sinks = append(sinks, newBatchSink("loki", cli.cfg.NotifyInterval, pusher.Post))
+	}
+	if cli.cfg.PostgresDSN != "" {
+		inserter := newPostgresInserter(cli.cfg.PostgresDSN, cli.cfg.PostgresTable)
+		sinks = append(sinks, newBatchSink("postgres", cli.cfg.NotifyInterval, inserter.Post))
+	}
+	if cli.cfg.ClickhouseURL != "" {
+		inserter := newClickhouseInserter(cli.cfg.ClickhouseURL, cli.cfg.ClickhouseTable, cli.cfg.ClickhouseUsername, cli.cfg.ClickhousePassword)
+		sinks = append(sinks, newBatchSink("clickhouse", cli.cfg.NotifyInterval, inserter.Post))
+	}
+	if cli.cfg.StreamAddress != "" {
+		stream, err := newMatchStream(cli.cfg.StreamAddress, cli.cfg.StreamToken)
+		if err != nil {
+			return nil, errors.Join(err, closeSinks(sinks))
+		}
+		sinks = append(sinks, stream)
+	}
+	if cli.cfg.NATSURL != "" {
+		publisher, err := newNATSPublisher(cli.cfg.NATSURL, cli.cfg.NATSSubject)
+		if err != nil {
+			return nil, errors.Join(err, closeSinks(sinks))
+		}
+		sinks = append(sinks, publisher)
+	}
+	for _, q := range cli.cfg.Queries() {
+		// the webhooks of a query share the headers, template and retries of the webhook flags
+		if q.Webhook != "" {
+			hook := newWebhook(q.Webhook, cli.cfg.WebhookHTTPHeaders, cli.cfg.WebhookBodyTemplate, cli.cfg.WebhookRetries)
+			sinks = append(sinks, cli.alertFilter(newBatchSink("webhook:"+q.Name, cli.cfg.NotifyInterval, queryFilter(q, hook.Post))))
+		}
+		if q.Discord != "" {
+			sinks = append(sinks, cli.alertFilter(newBatchSink("discord:"+q.Name, cli.cfg.NotifyInterval, queryFilter(q, newDiscordWebhook(q.Discord).Post))))
+		}
+	}
+	return sinks, nil
+}
+
+// queryFilter removes the matches of other queries before delivering them to the sink of a query.
+// Muting the query only affects the notifications of the flags, the threshold applies as well.
+func queryFilter(q *config.Query, deliver func(ctx context.Context, players PlayerExtendedList) error) func(ctx context.Context, players PlayerExtendedList) error {
+	return func(ctx context.Context, players PlayerExtendedList) error {
+		matches := make(PlayerExtendedList, 0, len(players))
+		for _, p := range players {
+			if p.Query == q.Name {
+				matches = append(matches, p)
+			}
+		}
+
+		if len(matches) == 0 || len(matches) < q.Threshold {
+			return nil
+		}
+		return deliver(ctx, matches)
+	}
+}
+
+// notifyFilter removes the matches of muted queries and of queries
+// that did not reach their threshold within a batch before notifying moderators.
+func (cli *CLI) notifyFilter(deliver func(ctx context.Context, players PlayerExtendedList) error) func(ctx context.Context, players PlayerExtendedList) error {
+	return func(ctx context.Context, players PlayerExtendedList) error {
+		counts := make(map[string]int, len(cli.cfg.Queries()))
+		for _, p := range players {
+			counts[p.Query]++
+		}
+
+		notify := make(PlayerExtendedList, 0, len(players))
+		for _, p := range players {
+			q := cli.cfg.Query(p.Query)
+			if q != nil && (q.Mute || counts[p.Query] < q.Threshold) {
+				continue
+			}
+			notify = append(notify, p)
+		}
+
+		if len(notify) == 0 {
+			return nil
+		}
+		return deliver(ctx, notify)
+	}
+}
+
+func (cli *CLI) sendToSinks(players PlayerExtendedList) {
+	if len(players) == 0 {
+		return
+	}
+	for _, s := range cli.sinks {
+		s.Send(players)
+	}
+}
+
+func closeSinks(sinks []Sink) error {
+	var errs []error
+	for _, s := range sinks {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// batchSink collects matches and delivers them at most once per interval,
+// which keeps external services from rate limiting us.
+type batchSink struct {
+	name     string
+	interval time.Duration
+	deliver  func(ctx context.Context, players PlayerExtendedList) error
+
+	mu      sync.Mutex
+	pending PlayerExtendedList
+	// lastErr is the result of the last delivery, reported on close
+	lastErr error
+	notify  chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newBatchSink(name string, interval time.Duration, deliver func(ctx context.Context, players PlayerExtendedList) error) *batchSink {
+	s := &batchSink{
+		name:     name,
+		interval: interval,
+		deliver:  deliver,
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+func (s *batchSink) Send(players PlayerExtendedList) {
+	s.mu.Lock()
+	s.pending = append(s.pending, players...)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *batchSink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	lastErr := s.lastErr
+	s.mu.Unlock()
+	return errors.Join(lastErr, s.flush())
+}
+
+func (s *batchSink) run() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.notify:
+		}
+
+		err := s.flush()
+		if err != nil {
+			slog.Error("failed to deliver matches", "sink", s.name, "error", err)
+		}
+		s.mu.Lock()
+		s.lastErr = err
+		s.mu.Unlock()
+
+		// wait before the next delivery
+		select {
+		case <-s.done:
+			return
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+func (s *batchSink) flush() error {
+	s.mu.Lock()
+	players := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if len(players) == 0 {
+		return nil
+	}
+
+	// deliveries must not be interrupted by a shutdown, otherwise the last matches would be lost
+	ctx, cancel := context.WithTimeout(context.Background(), sinkTimeout)
+	defer cancel()
+
+	slog.Debug("delivering matches", "sink", s.name, "matches", len(players))
+	return s.deliver(ctx, players)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// minSpamRateDuration is the minimum length of a session, so that a few messages
+// right before leaving do not result in an extreme rate.
+const minSpamRateDuration = time.Minute
+
+// NewSpamRateCmd computes the chat messages per minute of every nickname and IP over their sessions.
+func NewSpamRateCmd(ctx context.Context) *cobra.Command {
+	cfg := config.NewSpamRateConfig()
+
+	cmd := &cobra.Command{
+		Use:   "spamrate",
+		Short: "print the chat messages per minute of every nickname and IP over their sessions and flag the outliers above a threshold",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		searchDir, err := filepath.Abs(cfg.SearchDir)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path of search dir: %w", err)
+		}
+
+		files, err := listFiles(ctx, searchDir, cfg.FileRegexp)
+		if err != nil {
+			return err
+		}
+
+		rates := make(spamRates, 256)
+		for _, file := range files {
+			err = checkShutDown(ctx)
+			if err != nil {
+				return err
+			}
+
+			err = rates.addFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", file, err)
+			}
+		}
+
+		list := rates.List(cfg.Name, cfg.Threshold, cfg.MinMessages, cfg.All)
+		if len(list) == 0 {
+			return fmt.Errorf("%w: no chat messages above the threshold found", ErrNoMatches)
+		}
+		if cfg.Output == config.FormatText {
+			_, err := fmt.Fprint(cmd.OutOrStdout(), list)
+			return err
+		}
+
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal json result: %w", err)
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s\n", data)
+		return err
+	}
+	return cmd
+}
+
+// SpamRate is the chat activity of a nickname from an IP over all of its sessions.
+type SpamRate struct {
+	Nickname string `json:"nickname"`
+	IP       string `json:"ip"`
+	Sessions int    `json:"sessions"`
+	Messages int    `json:"messages"`
+	// Minutes is the connected time of all sessions.
+	Minutes float64 `json:"minutes"`
+	// Rate is the number of messages per connected minute.
+	Rate float64 `json:"rate"`
+	// PeakRate is the highest rate of a single session.
+	PeakRate float64 `json:"peak_rate"`
+	Outlier  bool    `json:"outlier"`
+}
+
+func (s SpamRate) String() string {
+	outlier := ""
+	if s.Outlier {
+		outlier = " outlier"
+	}
+	return fmt.Sprintf("rate=%.2f/min peak=%.2f/min messages=%d minutes=%.1f sessions=%d name=%s ip=%s%s",
+		s.Rate, s.PeakRate, s.Messages, s.Minutes, s.Sessions, s.Nickname, s.IP, outlier)
+}
+
+type SpamRateList []SpamRate
+
+func (l SpamRateList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(l) * 128)
+	for _, s := range l {
+		sb.WriteString(s.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+type spamRateKey struct {
+	nickname string
+	ip       string
+}
+
+// spamRates are the chat messages and connected time per nickname and IP.
+type spamRates map[spamRateKey]*SpamRate
+
+// chatSession is a connection of a client whose chat messages are counted.
+type chatSession struct {
+	nickname string
+	ip       string
+	start    time.Time
+	messages int
+}
+
+// addFile counts the chat messages of the sessions of a log file. Sessions that started
+// before the beginning of the file and lines without timestamps are not counted.
+// Sessions without leave line end with the last timestamp of the file.
+func (s spamRates) addFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		open = make(map[int]*chatSession, 64)
+		last time.Time
+	)
+	closeSession := func(id int, end time.Time) {
+		session, ok := open[id]
+		if !ok {
+			return
+		}
+		delete(open, id)
+		s.add(session, end)
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		t, ok := parseLogTime(line)
+		if !ok {
+			continue
+		}
+		last = t
+
+		event, ok := parseEvent(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case event.Type == config.EventConnect && event.IP != "":
+			if session, ok := open[event.ID]; ok && session.ip == event.IP {
+				// e.g. player is ready and player has entered the game
+				continue
+			}
+			closeSession(event.ID, t)
+			open[event.ID] = &chatSession{
+				nickname: event.Nickname,
+				ip:       event.IP,
+				start:    t,
+			}
+		case isLeaveLine(event.Text):
+			closeSession(event.ID, t)
+		case event.Type == config.EventChat:
+			session, ok := open[event.ID]
+			if !ok {
+				continue
+			}
+			if session.nickname == "" {
+				// the first nickname of the session, name changes are not followed
+				session.nickname = event.Nickname
+			}
+			session.messages++
+		}
+	}
+
+	for id := range open {
+		closeSession(id, last)
+	}
+	return scanner.Err()
+}
+
+func (s spamRates) add(session *chatSession, end time.Time) {
+	if session.nickname == "" {
+		return
+	}
+
+	key := spamRateKey{nickname: session.nickname, ip: session.ip}
+	rate, ok := s[key]
+	if !ok {
+		rate = &SpamRate{Nickname: session.nickname, IP: session.ip}
+		s[key] = rate
+	}
+
+	minutes := max(end.Sub(session.start), minSpamRateDuration).Minutes()
+	rate.Sessions++
+	rate.Messages += session.messages
+	rate.Minutes += minutes
+	rate.PeakRate = max(rate.PeakRate, float64(session.messages)/minutes)
+}
+
+// List returns the outliers or all rates, highest rate first.
+func (s spamRates) List(name string, threshold float64, minMessages int, all bool) SpamRateList {
+	list := make(SpamRateList, 0, 16)
+	for _, rate := range s {
+		if name != "" && rate.Nickname != name {
+			continue
+		}
+		rate.Rate = float64(rate.Messages) / rate.Minutes
+		rate.Outlier = rate.Rate > threshold && rate.Messages >= minMessages
+		if !all && !rate.Outlier {
+			continue
+		}
+		list = append(list, *rate)
+	}
+	slices.SortFunc(list, func(a, b SpamRate) int {
+		return cmp.Or(
+			cmp.Compare(b.Rate, a.Rate),
+			strings.Compare(a.Nickname, b.Nickname),
+			strings.Compare(a.IP, b.IP),
+		)
+	})
+	return list
+}
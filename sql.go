@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqlMatchesTable is created in memory with one row per match before the sql query is executed.
+const sqlMatchesTable = `CREATE TABLE matches (
+	query TEXT,
+	server TEXT,
+	file TEXT,
+	archive TEXT,
+	member TEXT,
+	event TEXT,
+	map TEXT,
+	nickname TEXT,
+	raw_nickname TEXT,
+	dummy BOOLEAN,
+	main_nickname TEXT,
+	client_version TEXT,
+	finish_time REAL,
+	id INTEGER,
+	ip TEXT,
+	port INTEGER,
+	hostname TEXT,
+	vpn BOOLEAN,
+	banned BOOLEAN,
+	asn INTEGER,
+	organization TEXT,
+	text TEXT,
+	language TEXT,
+	labels TEXT,
+	toxicity REAL,
+	line INTEGER,
+	offset INTEGER,
+	line_sha256 TEXT,
+	file_sha256 TEXT,
+	time TEXT,
+	category TEXT,
+	severity INTEGER,
+	player_total INTEGER,
+	fields TEXT,
+	online TEXT
+)`
+
+// sqlResult contains the rows of a sql query in the order of its columns.
+type sqlResult struct {
+	columns []string
+	rows    [][]any
+}
+
+func (r sqlResult) String() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(r.columns, "\t"))
+	for _, row := range r.rows {
+		for idx, value := range row {
+			if idx > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			if value != nil {
+				fmt.Fprint(w, value)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	_ = w.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// CSV returns the columns as header followed by the rows, NULL values are empty.
+func (r sqlResult) CSV() [][]string {
+	records := make([][]string, 0, len(r.rows)+1)
+	records = append(records, r.columns)
+	for _, row := range r.rows {
+		record := make([]string, len(row))
+		for idx, value := range row {
+			if value != nil {
+				record[idx] = fmt.Sprint(value)
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// MarshalJSON encodes the rows as objects whose keys keep the order of the columns.
+func (r sqlResult) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for rowIdx, row := range r.rows {
+		if rowIdx > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('{')
+		for idx, value := range row {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(r.columns[idx])
+			if err != nil {
+				return nil, err
+			}
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(data)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// querySQL loads the matches into an in-memory sqlite table and executes the sql query on it.
+func querySQL(ctx context.Context, players PlayerExtendedList, query string) (result sqlResult, err error) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return result, fmt.Errorf("failed to open sql database: %w", err)
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+	// every connection would get its own in-memory database
+	db.SetMaxOpenConns(1)
+
+	_, err = db.ExecContext(ctx, sqlMatchesTable)
+	if err != nil {
+		return result, fmt.Errorf("failed to create sql matches table: %w", err)
+	}
+
+	err = insertSQLMatches(ctx, db, players)
+	if err != nil {
+		return result, err
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return result, fmt.Errorf("failed to execute sql query: %w", err)
+	}
+	defer rows.Close()
+
+	result.columns, err = rows.Columns()
+	if err != nil {
+		return result, err
+	}
+	for rows.Next() {
+		row := make([]any, len(result.columns))
+		pointers := make([]any, len(row))
+		for idx := range row {
+			pointers[idx] = &row[idx]
+		}
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return result, fmt.Errorf("failed to read sql result: %w", err)
+		}
+		for idx, value := range row {
+			if b, ok := value.([]byte); ok {
+				row[idx] = string(b)
+			}
+		}
+		result.rows = append(result.rows, row)
+	}
+	if err = rows.Err(); err != nil {
+		return result, fmt.Errorf("failed to read sql result: %w", err)
+	}
+	return result, nil
+}
+
+func insertSQLMatches(ctx context.Context, db *sql.DB, players PlayerExtendedList) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO matches VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, p := range players {
+		var t, labels, fields, online any
+		if p.Time != nil {
+			t = p.Time.UTC().Format(time.RFC3339)
+		}
+		if len(p.Labels) > 0 {
+			labels = sqlJSON(p.Labels)
+		}
+		if len(p.Fields) > 0 {
+			fields = sqlJSON(p.Fields)
+		}
+		if len(p.Online) > 0 {
+			online = sqlJSON(p.Online)
+		}
+		_, err = stmt.ExecContext(ctx,
+			p.Query, p.Server, p.File, p.Archive, p.Member, p.Event, p.Map,
+			p.Nickname, p.RawNickname, p.Dummy, p.MainNickname, p.ClientVersion, p.FinishTime, p.ID, p.IP, p.Port, p.Hostname,
+			p.VPN, p.Banned, p.ASN, p.Organization, p.Text, p.Language, labels, p.Toxicity,
+			p.Line, p.Offset, p.LineSHA256, p.FileSHA256, t,
+			p.Category, p.Severity, p.PlayerTotal, fields, online,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert match into sql table: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// sqlJSON encodes maps and lists as json text, which can be accessed with the sqlite json functions.
+func sqlJSON(v any) string {
+	data, _ := json.Marshal(v) // maps of strings and string slices cannot fail
+	return string(data)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// scanStatsFile is the machine-readable summary of a scan that allows pipelines
+// to verify that a scan covered all files.
+type scanStatsFile struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Mode      string    `json:"mode"`
+	SearchDir string    `json:"search_dir"`
+	// Complete is false when the scan was interrupted or aborted.
+	Complete bool `json:"complete"`
+	// Truncated is true when the scan was stopped by the timeout and the results contain only the matches found until then.
+	Truncated bool             `json:"truncated"`
+	Queries   []scanStatsQuery `json:"queries"`
+	Files     scanStatsCount   `json:"files"`
+	Archives  scanStatsCount   `json:"archives"`
+	// ArchiveMembers is the number of scanned files inside of archives.
+	ArchiveMembers int64 `json:"archive_members"`
+	// DuplicateFiles is the number of skipped files and archive members with the content of another file.
+	DuplicateFiles int64 `json:"duplicate_files"`
+	// Retries is the number of times that files were read again after transient read errors.
+	Retries int64 `json:"retries"`
+	Bytes   int64 `json:"bytes"`
+	// ScanSeconds is the duration of reading the files, Seconds additionally includes
+	// the lookups of enrichments and printing the results.
+	ScanSeconds float64 `json:"scan_seconds"`
+	Seconds     float64 `json:"seconds"`
+	Matches     int     `json:"matches"`
+	// Errors are the errors of single files, archives and archive members.
+	Errors []FileError `json:"errors"`
+	Error  string      `json:"error,omitempty"`
+}
+
+type scanStatsQuery struct {
+	Name     string `json:"name,omitempty"`
+	Phrase   string `json:"phrase,omitempty"`
+	Patterns string `json:"patterns,omitempty"`
+	Matches  int    `json:"matches"`
+}
+
+type scanStatsCount struct {
+	Total   int   `json:"total"`
+	Scanned int64 `json:"scanned"`
+}
+
+// writeStatsFile writes the statistics of a scan to the stats file, in case one is configured.
+// Existing files are replaced, the start time of the scan replaces the {time} placeholder.
+func (cli *CLI) writeStatsFile(stats *ScanStats, players PlayerExtendedList, start time.Time, runErr error) error {
+	if cli.cfg.StatsFile == "" {
+		return nil
+	}
+
+	matchesPerQuery := make(map[string]int, len(cli.cfg.Queries()))
+	for _, p := range players {
+		matchesPerQuery[p.Query]++
+	}
+	if queries := cli.cfg.Queries(); len(queries) == 1 {
+		// streamed matches are not collected
+		matchesPerQuery[queries[0].Name] = stats.Matches
+	}
+
+	end := time.Now()
+	s := scanStatsFile{
+		Start:     start.UTC(),
+		End:       end.UTC(),
+		Mode:      cli.scanMode(),
+		Complete:  runErr == nil,
+		Truncated: errors.Is(runErr, ErrScanTimeout),
+		Files: scanStatsCount{
+			Total:   stats.TotalFiles,
+			Scanned: stats.Files.Load(),
+		},
+		Archives: scanStatsCount{
+			Total:   stats.TotalArchives,
+			Scanned: stats.Archives.Load(),
+		},
+		ArchiveMembers: stats.Members.Load(),
+		DuplicateFiles: stats.Duplicates.Load(),
+		Retries:        stats.Retries.Load(),
+		Bytes:          stats.Bytes.Load(),
+		ScanSeconds:    stats.ScanDuration.Seconds(),
+		Seconds:        end.Sub(start).Seconds(),
+		Matches:        stats.Matches,
+		Errors:         stats.Errors(),
+	}
+	s.SearchDir, _ = filepath.Abs(cli.cfg.SearchDir)
+	if s.Errors == nil {
+		s.Errors = []FileError{}
+	}
+	for _, q := range cli.cfg.Queries() {
+		sq := scanStatsQuery{
+			Name:     q.Name,
+			Patterns: q.PatternsFile,
+			Matches:  matchesPerQuery[q.Name],
+		}
+		if q.PatternsFile == "" {
+			sq.Phrase = q.PhraseRegex
+		}
+		s.Queries = append(s.Queries, sq)
+	}
+	if runErr != nil {
+		s.Error = runErr.Error()
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal stats file: %w", err)
+	}
+	err = os.WriteFile(outputPath(cli.cfg.StatsFile, start), append(data, '\n'), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write stats file: %w", err)
+	}
+	return nil
+}
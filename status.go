@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// logStatus logs the progress of the scan at every interval until the returned function is called,
+// so a stuck network file system can be told apart from a slow scan in environments without a terminal.
+func (cli *CLI) logStatus(ctx context.Context, stats *ScanStats, interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := time.Now()
+		lastBytes := stats.Bytes.Load()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				bytes := stats.Bytes.Load()
+				bytesPerSec := float64(bytes-lastBytes) / now.Sub(last).Seconds()
+				last, lastBytes = now, bytes
+
+				cli.statusLogger.Info("scan status",
+					"files", stats.Files.Load(),
+					"total_files", stats.TotalFiles,
+					"archives", stats.Archives.Load(),
+					"total_archives", stats.TotalArchives,
+					"bytes", bytes,
+					"bytes_per_sec", int64(bytesPerSec),
+					"matches", stats.Found.Load(),
+				)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	streamPath = "/matches"
+	// streamBuffer is the number of matches that are queued per client before matches are dropped
+	streamBuffer = 256
+	// streamKeepAlive keeps proxies from closing idle connections
+	streamKeepAlive = 30 * time.Second
+)
+
+// matchStream serves the live matches as server-sent events to dashboards and bots.
+type matchStream struct {
+	srv   *http.Server
+	token string
+
+	mu          sync.Mutex
+	subscribers map[*streamSubscriber]struct{}
+	done        chan struct{}
+}
+
+// streamSubscriber is a connected client, without queries it receives the matches of all queries.
+type streamSubscriber struct {
+	queries []string
+	matches chan PlayerExtended
+}
+
+func (s *streamSubscriber) wants(query string) bool {
+	return len(s.queries) == 0 || slices.Contains(s.queries, query)
+}
+
+func newMatchStream(address, token string) (*matchStream, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for match stream clients: %w", err)
+	}
+
+	s := &matchStream{
+		token:       token,
+		subscribers: make(map[*streamSubscriber]struct{}, 1),
+		done:        make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+streamPath, s.handle)
+	s.srv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		err := s.srv.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("match stream server stopped", "error", err)
+		}
+	}()
+	slog.Info("streaming matches", "address", listener.Addr().String(), "path", streamPath)
+	return s, nil
+}
+
+// Send passes the matches to the subscribed clients, matches of clients that do not keep up are dropped.
+func (s *matchStream) Send(players PlayerExtendedList) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dropped := 0
+	for sub := range s.subscribers {
+		for _, p := range players {
+			if !sub.wants(p.Query) {
+				continue
+			}
+			select {
+			case sub.matches <- p:
+			default:
+				dropped++
+			}
+		}
+	}
+	if dropped > 0 {
+		slog.Warn("dropped matches of slow match stream clients", "matches", dropped)
+	}
+}
+
+// Close disconnects all clients and stops the server.
+func (s *matchStream) Close() error {
+	close(s.done)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.srv.Shutdown(ctx)
+}
+
+func (s *matchStream) subscribe(queries []string) *streamSubscriber {
+	sub := &streamSubscriber{
+		queries: queries,
+		matches: make(chan PlayerExtended, streamBuffer),
+	}
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+	return sub
+}
+
+func (s *matchStream) unsubscribe(sub *streamSubscriber) {
+	s.mu.Lock()
+	delete(s.subscribers, sub)
+	s.mu.Unlock()
+}
+
+// handle streams every match as json in a 'match' event, the query parameter may be repeated
+// in order to subscribe to specific queries, e.g. /matches?query=ads&query=insults
+func (s *matchStream) handle(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			slog.Warn("rejected match stream client with invalid token", "remote", r.RemoteAddr)
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := s.subscribe(r.URL.Query()["query"])
+	defer s.unsubscribe(sub)
+	slog.Info("match stream client connected", "remote", r.RemoteAddr, "queries", sub.queries)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			slog.Info("match stream client disconnected", "remote", r.RemoteAddr)
+			return
+		case <-s.done:
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case p := <-sub.matches:
+			data, merr := json.Marshal(p)
+			if merr != nil {
+				slog.Error("failed to marshal match", "sink", "stream", "error", merr)
+				continue
+			}
+			_, err = fmt.Fprintf(w, "event: match\ndata: %s\n\n", data)
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	telegramAPIURL = "https://api.telegram.org"
+	// telegramMaxMessageLength is the maximum length of a telegram message.
+	telegramMaxMessageLength = 4096
+)
+
+// telegramBot sends matches to a telegram chat.
+type telegramBot struct {
+	url    string
+	chatID string
+	client *http.Client
+}
+
+func newTelegramBot(token, chatID string) *telegramBot {
+	return &telegramBot{
+		url:    fmt.Sprintf("%s/bot%s/sendMessage", telegramAPIURL, token),
+		chatID: chatID,
+		client: &http.Client{Timeout: sinkTimeout},
+	}
+}
+
+// Post sends the matches as one or more messages, every line contains a single match.
+func (t *telegramBot) Post(ctx context.Context, players PlayerExtendedList) error {
+	var sb strings.Builder
+	for _, p := range players {
+		line := formatTelegramLine(p)
+		if sb.Len() > 0 && sb.Len()+1+len(line) > telegramMaxMessageLength {
+			err := t.send(ctx, sb.String())
+			if err != nil {
+				return err
+			}
+			sb.Reset()
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(line)
+	}
+	return t.send(ctx, sb.String())
+}
+
+func formatTelegramLine(p PlayerExtended) string {
+	var sb strings.Builder
+	if p.Server != "" {
+		fmt.Fprintf(&sb, "[%s] ", html.EscapeString(p.Server))
+	}
+	if p.Query != "" {
+		fmt.Fprintf(&sb, "(%s) ", html.EscapeString(p.Query))
+	}
+	fmt.Fprintf(&sb, "<b>%s</b> <code>%s</code>: %s", html.EscapeString(p.Nickname), p.IP, html.EscapeString(p.Text))
+	return sb.String()
+}
+
+func (t *telegramBot) send(ctx context.Context, text string) error {
+	body, err := json.Marshal(map[string]any{
+		"chat_id":                  t.chatID,
+		"text":                     text,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := t.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to send telegram message: %w", redactTelegramError(err))
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		var result struct {
+			Description string `json:"description"`
+			Parameters  struct {
+				RetryAfter int `json:"retry_after"`
+			} `json:"parameters"`
+		}
+		_ = json.Unmarshal(data, &result)
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			retryAfter := max(result.Parameters.RetryAfter, 1)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(retryAfter) * time.Second):
+			}
+		case resp.StatusCode >= 300:
+			return fmt.Errorf("failed to send telegram message: unexpected status code: %s: %s", resp.Status, result.Description)
+		default:
+			return nil
+		}
+	}
+}
+
+// redactTelegramError removes the request url, as it contains the bot token.
+func redactTelegramError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// NewTestRegexCmd allows to verify the regexes against a sample line and path
+// before starting a long running scan.
+func NewTestRegexCmd() *cobra.Command {
+	cfg := config.NewTestRegexConfig()
+
+	cmd := &cobra.Command{
+		Use:   "test-regex",
+		Short: "report whether and where the phrase, file and archive regexes match a sample",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		w := cmd.OutOrStdout()
+		if cfg.Sample != "" {
+			testPhrase(w, cfg.PhraseRegexp, cfg.Sample)
+		}
+		if cfg.Path != "" {
+			if cfg.Sample != "" {
+				fmt.Fprintln(w)
+			}
+			testPath(w, cfg.FileRegexp, cfg.ArchiveRegexp, cfg.Path)
+		}
+		return nil
+	}
+	return cmd
+}
+
+func testPhrase(w io.Writer, phraseRegexp *regexp.Regexp, sample string) {
+	event, ok := parseEvent(sample)
+	if !ok {
+		fmt.Fprintln(w, "sample is not a log line of a player, the phrase regex is only applied to chat messages and game events")
+		fmt.Fprintf(w, "log line regex: %s\n", logLineRegexp)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "phrase regex applied to the whole sample:")
+		printMatches(w, phraseRegexp, sample)
+		return
+	}
+
+	fmt.Fprintf(w, "%s line: id=%d name=%s\n", event.Type, event.ID, event.Nickname)
+	if event.Type == config.EventChat {
+		fmt.Fprintln(w, "phrase regex applied to the chat message:")
+	} else {
+		fmt.Fprintf(w, "phrase regex applied to the message, requires --event-type %s:\n", event.Type)
+	}
+	printMatches(w, phraseRegexp, event.Text)
+}
+
+func testPath(w io.Writer, fileRegexp, archiveRegexp *regexp.Regexp, path string) {
+	fmt.Fprintln(w, "file regex applied to the path:")
+	printMatches(w, fileRegexp, path)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "archive regex applied to the path:")
+	printMatches(w, archiveRegexp, path)
+}
+
+// printMatches prints the text and underlines every match as well as its capture groups.
+func printMatches(w io.Writer, re *regexp.Regexp, text string) {
+	indexes := re.FindAllStringSubmatchIndex(text, -1)
+	if len(indexes) == 0 {
+		fmt.Fprintf(w, "  no match for %s\n", re)
+		return
+	}
+
+	names := re.SubexpNames()
+	for i, idx := range indexes {
+		fmt.Fprintf(w, "  match %d at [%d:%d]\n", i+1, idx[0], idx[1])
+		fmt.Fprintf(w, "    %s\n", text)
+		fmt.Fprintf(w, "    %s\n", underline(text, idx[0], idx[1], '^'))
+
+		for g := 1; g < len(idx)/2; g++ {
+			start, end := idx[2*g], idx[2*g+1]
+			name := fmt.Sprintf("group %d", g)
+			if names[g] != "" {
+				name = fmt.Sprintf("group %d (%s)", g, names[g])
+			}
+
+			if start < 0 {
+				fmt.Fprintf(w, "    %s: did not participate in the match\n", name)
+				continue
+			}
+			fmt.Fprintf(w, "    %s\n", underline(text, start, end, '~'))
+			fmt.Fprintf(w, "    %s at [%d:%d]: %q\n", name, start, end, text[start:end])
+		}
+	}
+}
+
+// underline returns a line that marks the byte range [start:end] of text.
+// Positions are counted in runes so that the marker lines up with the printed text.
+func underline(text string, start, end int, marker rune) string {
+	prefix := utf8.RuneCountInString(text[:start])
+	width := max(1, utf8.RuneCountInString(text[start:end]))
+	return strings.Repeat(" ", prefix) + strings.Repeat(string(marker), width)
+}
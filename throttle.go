@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/twlog-who-said/archive"
+)
+
+// bandwidthLimiter limits the bytes per second that all workers read in total,
+// so that scans do not starve other processes that use the same storage.
+// A nil limiter does not limit the reads.
+type bandwidthLimiter struct {
+	mu             sync.Mutex
+	bytesPerSecond float64
+	// next is the point in time at which the next read may happen
+	next time.Time
+}
+
+func newBandwidthLimiter(bytesPerSecond int64) *bandwidthLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &bandwidthLimiter{bytesPerSecond: float64(bytesPerSecond)}
+}
+
+// wait blocks until n further bytes may be read, the bytes were already read
+// and delay the reads that follow.
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		// unused bandwidth of idle periods is not saved up for bursts
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(float64(n) / l.bytesPerSecond * float64(time.Second)))
+	delay := l.next.Sub(now)
+	l.mu.Unlock()
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-t.C:
+		return nil
+	}
+}
+
+// throttledFile limits the bandwidth of sequential reads of a file.
+type throttledFile struct {
+	archive.File
+	ctx     context.Context
+	limiter *bandwidthLimiter
+}
+
+func (f throttledFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	waitErr := f.limiter.wait(f.ctx, n)
+	if err != nil {
+		return n, err
+	}
+	return n, waitErr
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// updateTimeout is the maximum duration of the binary download, which is larger than the other downloads.
+const updateTimeout = 10 * time.Minute
+
+const exitCodeUpdateAvailable = 1
+
+var (
+	ErrUpdateAvailable = errors.New("update available")
+)
+
+// NewUpdateCmd replaces the running binary with the binary of a github release.
+func NewUpdateCmd(ctx context.Context) *cobra.Command {
+	cfg := config.NewUpdateConfig()
+
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "replace the binary with the newest github release after verifying its sha256 checksum",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		release, err := fetchRelease(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		current, _ := buildVersion()
+		w := cmd.OutOrStdout()
+		upToDate := isReleaseVersion(current) && semver.Compare(current, release.TagName) >= 0
+		if cfg.Check {
+			if upToDate {
+				_, err = fmt.Fprintf(w, "%s is up to date, the newest release is %s\n", current, release.TagName)
+				return err
+			}
+			_, err = fmt.Fprintf(w, "%s can be updated to %s\n", current, release.TagName)
+			if err != nil {
+				return err
+			}
+			return &ExitCodeError{Code: exitCodeUpdateAvailable, Err: fmt.Errorf("%w: %s", ErrUpdateAvailable, release.TagName)}
+		}
+
+		if !cfg.Force {
+			if !isReleaseVersion(current) {
+				return fmt.Errorf("%s is a development build, use --force in order to replace it with %s", current, release.TagName)
+			}
+			if upToDate {
+				_, err = fmt.Fprintf(w, "%s is up to date\n", current)
+				return err
+			}
+		}
+
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to locate the running binary: %w", err)
+		}
+		executable, err = filepath.EvalSymlinks(executable)
+		if err != nil {
+			return fmt.Errorf("failed to locate the running binary: %w", err)
+		}
+
+		err = installRelease(ctx, release, executable)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "updated %s from %s to %s\n", executable, current, release.TagName)
+		return err
+	}
+	return cmd
+}
+
+// githubRelease is the subset of a github release that is needed in order to download its assets.
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// assetURL returns the download URL of the asset with the given name.
+func (r githubRelease) assetURL(name string) (string, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.BrowserDownloadURL, true
+		}
+	}
+	return "", false
+}
+
+// fetchRelease returns the release of the configured version or the newest release with a semantic version tag.
+// Releases are published as pre-releases, which is why the latest release endpoint cannot be used.
+func fetchRelease(ctx context.Context, cfg config.UpdateConfig) (githubRelease, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases?per_page=100", cfg.APIURL, cfg.Repository)
+	if cfg.Version != "" {
+		url = fmt.Sprintf("%s/repos/%s/releases/tags/%s", cfg.APIURL, cfg.Repository, cfg.Version)
+	}
+
+	r, err := openSource(ctx, url)
+	if err != nil {
+		return githubRelease{}, fmt.Errorf("failed to fetch releases of %s: %w", cfg.Repository, err)
+	}
+	defer r.Close()
+
+	if cfg.Version != "" {
+		var release githubRelease
+		err = json.NewDecoder(r).Decode(&release)
+		if err != nil {
+			return githubRelease{}, fmt.Errorf("invalid release %s: %w", cfg.Version, err)
+		}
+		return release, nil
+	}
+
+	var releases []githubRelease
+	err = json.NewDecoder(r).Decode(&releases)
+	if err != nil {
+		return githubRelease{}, fmt.Errorf("invalid releases of %s: %w", cfg.Repository, err)
+	}
+
+	var newest *githubRelease
+	for idx, release := range releases {
+		if release.Draft || !semver.IsValid(release.TagName) {
+			continue
+		}
+		if newest == nil || semver.Compare(release.TagName, newest.TagName) > 0 {
+			newest = &releases[idx]
+		}
+	}
+	if newest == nil {
+		return githubRelease{}, fmt.Errorf("no releases of %s found", cfg.Repository)
+	}
+	return *newest, nil
+}
+
+// isReleaseVersion returns false for binaries that were not built from a release tag,
+// e.g. (devel), pseudo versions and builds with uncommitted changes.
+func isReleaseVersion(version string) bool {
+	return semver.IsValid(version) && !module.IsPseudoVersion(version) && semver.Build(version) == ""
+}
+
+// releaseAssetName is the name of the binary of the current platform, e.g. twlog-who-said.linux.amd64
+func releaseAssetName() string {
+	name := fmt.Sprintf("twlog-who-said.%s.%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// installRelease downloads the binary of the current platform next to the executable, verifies its checksum and
+// replaces the executable. The running binary on windows cannot be overwritten, it is moved aside instead.
+func installRelease(ctx context.Context, release githubRelease, executable string) (err error) {
+	name := releaseAssetName()
+	binaryURL, ok := release.assetURL(name)
+	if !ok {
+		return fmt.Errorf("release %s has no binary %s", release.TagName, name)
+	}
+	checksumURL, ok := release.assetURL(name + ".sha256")
+	if !ok {
+		return fmt.Errorf("release %s has no checksum %s.sha256", release.TagName, name)
+	}
+
+	expected, err := fetchChecksum(ctx, checksumURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch checksum of %s: %w", name, err)
+	}
+
+	fi, err := os.Stat(executable)
+	if err != nil {
+		return err
+	}
+
+	// the same directory allows to rename the file, which is atomic
+	tmp, err := os.CreateTemp(filepath.Dir(executable), ".twlog-who-said-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file next to %s: %w", executable, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	slog.Info("downloading release", "version", release.TagName, "url", binaryURL)
+	actual, err := downloadFile(ctx, binaryURL, tmp)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	if actual != expected {
+		return fmt.Errorf("checksum mismatch of %s: expected %s, got %s", name, expected, actual)
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(tmp.Name(), fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		old := executable + ".old"
+		// left over from the previous update
+		_ = os.Remove(old)
+		err = os.Rename(executable, old)
+		if err != nil {
+			return fmt.Errorf("failed to move %s aside: %w", executable, err)
+		}
+		err = os.Rename(tmp.Name(), executable)
+		if err != nil {
+			return errors.Join(fmt.Errorf("failed to replace %s: %w", executable, err), os.Rename(old, executable))
+		}
+		return nil
+	}
+
+	err = os.Rename(tmp.Name(), executable)
+	if err != nil {
+		return fmt.Errorf("failed to replace %s: %w", executable, err)
+	}
+	return nil
+}
+
+// fetchChecksum returns the hex encoded sha256 checksum of a sha256sum file.
+func fetchChecksum(ctx context.Context, url string) (string, error) {
+	r, err := openSource(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	line, err := bufio.NewReader(io.LimitReader(r, 4096)).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", errors.New("empty checksum file")
+	}
+
+	checksum := strings.ToLower(fields[0])
+	decoded, err := hex.DecodeString(checksum)
+	if err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("invalid sha256 checksum %q", fields[0])
+	}
+	return checksum, nil
+}
+
+// downloadFile writes the file at url to w and returns its hex encoded sha256 checksum.
+func downloadFile(ctx context.Context, url string, w io.Writer) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %s", resp.Status)
+	}
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(w, h), resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
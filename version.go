@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/jxsl13/twlog-who-said/archive"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+// outputSchemaVersion is incremented on incompatible changes of the json output,
+// e.g. renamed or removed fields, new fields are compatible.
+const outputSchemaVersion = 1
+
+// features are the optional capabilities of the binary, which scripts can check before using them.
+var features = []string{
+	"asn-lookup",
+	"classify",
+	"econ",
+	"language-detection",
+	"lua-scripts",
+	"rdns",
+	"replay-manifests",
+	"sql",
+	"vpn-flagging",
+	"where-expressions",
+	"workers",
+}
+
+// sinkNames are the external systems that matches can be sent to.
+var sinkNames = []string{"clickhouse", "discord", "elasticsearch", "loki", "nats", "postgres", "stream", "telegram", "webhook"}
+
+// VersionInfo describes the build and the capabilities of the binary.
+type VersionInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	// BuildDate is the commit time of the vcs revision.
+	BuildDate string `json:"build_date,omitempty"`
+	// Modified is true for builds with uncommitted changes.
+	Modified       bool     `json:"modified"`
+	GoVersion      string   `json:"go_version"`
+	Platform       string   `json:"platform"`
+	OutputSchema   int      `json:"output_schema"`
+	ArchiveFormats []string `json:"archive_formats"`
+	Sinks          []string `json:"sinks"`
+	Features       []string `json:"features"`
+}
+
+func (v VersionInfo) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "version:         %s\n", v.Version)
+	if v.Commit != "" {
+		modified := ""
+		if v.Modified {
+			modified = " (modified)"
+		}
+		fmt.Fprintf(&sb, "commit:          %s%s\n", v.Commit, modified)
+	}
+	if v.BuildDate != "" {
+		fmt.Fprintf(&sb, "build date:      %s\n", v.BuildDate)
+	}
+	fmt.Fprintf(&sb, "go version:      %s\n", v.GoVersion)
+	fmt.Fprintf(&sb, "platform:        %s\n", v.Platform)
+	fmt.Fprintf(&sb, "output schema:   %d\n", v.OutputSchema)
+	fmt.Fprintf(&sb, "archive formats: %s\n", strings.Join(v.ArchiveFormats, ", "))
+	fmt.Fprintf(&sb, "sinks:           %s\n", strings.Join(v.Sinks, ", "))
+	fmt.Fprintf(&sb, "features:        %s\n", strings.Join(v.Features, ", "))
+	return sb.String()
+}
+
+// readVersionInfo reads the version and the vcs information that the go toolchain embeds into the binary.
+func readVersionInfo() VersionInfo {
+	v := VersionInfo{
+		Version:        "unknown",
+		GoVersion:      runtime.Version(),
+		Platform:       runtime.GOOS + "/" + runtime.GOARCH,
+		OutputSchema:   outputSchemaVersion,
+		ArchiveFormats: archive.Formats,
+		Sinks:          sinkNames,
+		Features:       features,
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	v.Version = info.Main.Version
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			v.Commit = s.Value
+		case "vcs.time":
+			v.BuildDate = s.Value
+		case "vcs.modified":
+			v.Modified = s.Value == "true"
+		}
+	}
+	return v
+}
+
+// versionTemplate prints the version info in the output format of the output flag, e.g. --version -o json
+func versionTemplate(cmd *cobra.Command) string {
+	info := readVersionInfo()
+
+	output := cmd.Flags().Lookup("output")
+	if output == nil || !strings.EqualFold(output.Value.String(), config.FormatJSON) {
+		return info.String()
+	}
+
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err.Error() + "\n"
+	}
+	return string(data) + "\n"
+}
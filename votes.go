@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// caller, type and target of called votes, e.g. '0:nameless tee' voted kick '3:brainless tee' reason='spam'
+	voteCallRegexp = regexp.MustCompile(`'(\d+):(?:-?\d+:)?([^']*)' voted (kick|spectate|option) '([^']*)'`)
+	// outcome of the running vote announced by the server, e.g. chat: *** Vote passed
+	voteResultRegexp = regexp.MustCompile(`(?i)chat\]?: \*\*\* vote (passed|failed|aborted)\b`)
+)
+
+// NewVotesCmd links called votes with their results and prints statistics per caller.
+func NewVotesCmd(ctx context.Context) *cobra.Command {
+	cfg := config.NewVotesConfig()
+
+	cmd := &cobra.Command{
+		Use:   "votes",
+		Short: "print the number of called votes, their targets and success rates per player",
+		Args:  cobra.NoArgs,
+	}
+	parser := cliconfig.RegisterFlags(&cfg, false, cmd, cliconfig.WithEnvPrefix(config.EnvPrefix))
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr())
+		return parser()
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		searchDir, err := filepath.Abs(cfg.SearchDir)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path of search dir: %w", err)
+		}
+
+		files, err := listFiles(ctx, searchDir, cfg.FileRegexp)
+		if err != nil {
+			return err
+		}
+
+		stats := make(voteStats, 64)
+		for _, file := range files {
+			err = checkShutDown(ctx)
+			if err != nil {
+				return err
+			}
+
+			err = stats.addFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", file, err)
+			}
+		}
+
+		list := stats.List()
+		if len(list) == 0 {
+			return fmt.Errorf("%w: no called votes found", ErrNoMatches)
+		}
+		if cfg.Output == config.FormatText {
+			_, err := fmt.Fprint(cmd.OutOrStdout(), list)
+			return err
+		}
+
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal json result: %w", err)
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s\n", data)
+		return err
+	}
+	return cmd
+}
+
+// PlayerVotes are the votes called by a player.
+type PlayerVotes struct {
+	Nickname string `json:"nickname"`
+	Called   int    `json:"called"`
+	Passed   int    `json:"passed"`
+	Failed   int    `json:"failed"`
+	Aborted  int    `json:"aborted"`
+	// Unknown votes have no result line, e.g. because the log ended.
+	Unknown int `json:"unknown"`
+	// SuccessRate is the ratio of passed votes to votes with a result.
+	SuccessRate float64 `json:"success_rate"`
+	// Targets are the kicked or moved players and the options with the number of votes.
+	Targets map[string]int `json:"targets,omitempty"`
+}
+
+func (p PlayerVotes) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "called=%d passed=%d failed=%d aborted=%d unknown=%d success=%.0f%% name=%s",
+		p.Called, p.Passed, p.Failed, p.Aborted, p.Unknown, p.SuccessRate*100, p.Nickname)
+	if len(p.Targets) > 0 {
+		sb.WriteString(" targets=")
+		for idx, target := range slices.Sorted(maps.Keys(p.Targets)) {
+			if idx > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(&sb, "%q:%d", target, p.Targets[target])
+		}
+	}
+	return sb.String()
+}
+
+type PlayerVotesList []PlayerVotes
+
+func (p PlayerVotesList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(p) * 128)
+	for _, votes := range p {
+		sb.WriteString(votes.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// voteStats are the votes per caller nickname.
+type voteStats map[string]*PlayerVotes
+
+func (s voteStats) caller(nickname string) *PlayerVotes {
+	votes, ok := s[nickname]
+	if !ok {
+		votes = &PlayerVotes{
+			Nickname: nickname,
+			Targets:  make(map[string]int, 1),
+		}
+		s[nickname] = votes
+	}
+	return votes
+}
+
+// addFile links the votes of a log file with the result lines that follow them.
+// Only a single vote can run at a time.
+func (s voteStats) addFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var running *PlayerVotes
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if event, ok := parseEvent(line); ok && event.Type == config.EventVote {
+			// players cannot fake vote lines in the chat
+			m := voteCallRegexp.FindStringSubmatch(event.Text)
+			if m == nil {
+				continue
+			}
+			if running != nil {
+				running.Unknown++
+			}
+			running = s.caller(m[2])
+			running.Called++
+
+			target := m[4]
+			if m[3] != "option" {
+				// strip the client id of kicked or moved players
+				if _, name, found := strings.Cut(target, ":"); found {
+					target = name
+				}
+			}
+			running.Targets[m[3]+" "+target]++
+			continue
+		}
+
+		m := voteResultRegexp.FindStringSubmatch(line)
+		if m == nil || running == nil {
+			continue
+		}
+		switch strings.ToLower(m[1]) {
+		case "passed":
+			running.Passed++
+		case "failed":
+			running.Failed++
+		default:
+			running.Aborted++
+		}
+		running = nil
+	}
+	if running != nil {
+		running.Unknown++
+	}
+	return scanner.Err()
+}
+
+// List returns the votes of all callers, most called votes first.
+func (s voteStats) List() PlayerVotesList {
+	list := make(PlayerVotesList, 0, len(s))
+	for _, votes := range s {
+		decided := votes.Passed + votes.Failed + votes.Aborted
+		if decided > 0 {
+			votes.SuccessRate = float64(votes.Passed) / float64(decided)
+		}
+		list = append(list, *votes)
+	}
+	slices.SortFunc(list, func(a, b PlayerVotes) int {
+		return cmp.Or(
+			cmp.Compare(b.Called, a.Called),
+			strings.Compare(a.Nickname, b.Nickname),
+		)
+	})
+	return list
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+)
+
+const downloadTimeout = 30 * time.Second
+
+// ipRanges is a list of IP ranges, e.g. of VPN providers, proxies and data centers.
+type ipRanges []netip.Prefix
+
+// loadIPRanges reads IP ranges from local files or http(s) URLs.
+func loadIPRanges(ctx context.Context, sources []string) (ipRanges, error) {
+	ranges := make(ipRanges, 0, 1024)
+	for _, source := range sources {
+		r, err := openSource(ctx, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open ip list %s: %w", source, err)
+		}
+
+		sourceRanges, err := parseIPRanges(r)
+		closeErr := r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ip list %s: %w", source, err)
+		}
+		if closeErr != nil {
+			return nil, fmt.Errorf("failed to close ip list %s: %w", source, closeErr)
+		}
+
+		slog.Info("loaded ip list", "source", source, "ranges", len(sourceRanges))
+		ranges = append(ranges, sourceRanges...)
+	}
+	return ranges, nil
+}
+
+// openSource opens a local file or downloads the file at an http(s) URL.
+func openSource(ctx context.Context, source string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.Open(source)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("unexpected status code: %s", resp.Status)
+	}
+	return &cancelReadCloser{resp.Body, cancel}, nil
+}
+
+// cancelReadCloser cancels the download context when the body is closed.
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// parseIPRanges parses one IP or CIDR per line, empty lines and # comments are ignored.
+func parseIPRanges(r io.Reader) (ipRanges, error) {
+	ranges := make(ipRanges, 0, 256)
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if !strings.Contains(line, "/") {
+			addr, err := netip.ParseAddr(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			ranges = append(ranges, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		ranges = append(ranges, prefix.Masked())
+	}
+	return ranges, scanner.Err()
+}
+
+// Contains returns true in case the IP is located in any of the ranges.
+func (r ipRanges) Contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range r {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
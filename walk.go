@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+)
+
+// walker walks the search dir and calls walkFunc for every regular file.
+// Directories are accessed via extended paths, which supports long paths on windows,
+// and files with reserved names on windows are passed to walkFunc with their extended path.
+type walker struct {
+	ctx            context.Context
+	followSymlinks bool
+	oneFileSystem  bool
+	// maxDepth limits the directory levels below the search dir, 0 means unlimited
+	maxDepth int
+	walkFunc func(path string, fi fs.FileInfo) error
+
+	rootDevice uint64
+	ignored    *ignoreRules
+	// real paths of the visited directories prevent symlink loops and scanning directories twice
+	visited map[string]struct{}
+}
+
+func (w *walker) Walk(root string) error {
+	fi, err := os.Stat(extendedPath(root))
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+
+	w.rootDevice, _ = deviceID(fi)
+	w.ignored = newIgnoreRules(root)
+	w.visited = make(map[string]struct{})
+	return w.walkDir(root, 1)
+}
+
+// walkDir visits the entries of dir, which are depth levels below the search dir.
+func (w *walker) walkDir(dir string, depth int) error {
+	realDir, err := realPath(dir)
+	if err != nil {
+		return err
+	}
+	if _, found := w.visited[realDir]; found {
+		slog.Info("skipping already visited directory", "dir", dir, "target", realDir)
+		return nil
+	}
+	w.visited[realDir] = struct{}{}
+
+	err = w.ignored.load(dir)
+	if err != nil {
+		return fmt.Errorf("failed to load ignore file: %w", err)
+	}
+
+	entries, err := os.ReadDir(extendedPath(dir))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		err = checkShutDown(w.ctx)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		fi, ok, err := w.stat(path, entry)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		if w.ignored.Ignored(path, fi.IsDir()) {
+			slog.Info("skipping ignored path", "path", path)
+			continue
+		}
+
+		if fi.IsDir() {
+			if w.maxDepth > 0 && depth >= w.maxDepth {
+				slog.Info("skipping directory, max depth reached", "dir", path)
+				continue
+			}
+
+			if w.oneFileSystem {
+				if dev, ok := deviceID(fi); ok && dev != w.rootDevice {
+					slog.Info("skipping directory on a different file system", "dir", path)
+					continue
+				}
+			}
+
+			err = w.walkDir(path, depth+1)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// skip non-files
+		if !fi.Mode().IsRegular() || isIgnoreFile(entry) {
+			continue
+		}
+
+		err = w.walkFunc(openPath(path), fi)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stat returns the file info of the entry or of the symlink target in case symlinks are followed.
+// Symlinks that are not followed as well as broken symlinks are skipped.
+func (w *walker) stat(path string, entry os.DirEntry) (fi fs.FileInfo, ok bool, err error) {
+	if entry.Type()&fs.ModeSymlink == 0 {
+		fi, err = entry.Info()
+		if err != nil {
+			return nil, false, err
+		}
+		return fi, true, nil
+	}
+
+	if !w.followSymlinks {
+		slog.Debug("skipping symlink", "path", path)
+		return nil, false, nil
+	}
+
+	fi, err = os.Stat(extendedPath(path))
+	if err != nil {
+		slog.Warn("skipping broken symlink", "path", path, "error", err)
+		return nil, false, nil
+	}
+	return fi, true, nil
+}
+
+// listFiles returns the sorted paths of all files in the search dir that match the file regex.
+func listFiles(ctx context.Context, searchDir string, fileRegexp *regexp.Regexp) ([]string, error) {
+	files := make([]string, 0, 16)
+	w := &walker{
+		ctx: ctx,
+		walkFunc: func(path string, fi fs.FileInfo) error {
+			if fileRegexp.MatchString(path) {
+				files = append(files, path)
+			}
+			return nil
+		},
+	}
+	err := w.Walk(searchDir)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+	return files, nil
+}
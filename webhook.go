@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+// webhookBackoff is the delay before the first retry, it doubles with every further retry.
+const webhookBackoff = time.Second
+
+// webhook posts matches as json to an arbitrary http endpoint.
+type webhook struct {
+	url     string
+	headers http.Header
+	body    *template.Template
+	retries int
+	client  *http.Client
+}
+
+func newWebhook(url string, headers http.Header, body *template.Template, retries int) *webhook {
+	return &webhook{
+		url:     url,
+		headers: headers,
+		body:    body,
+		retries: retries,
+		client:  &http.Client{Timeout: sinkTimeout},
+	}
+}
+
+// Post sends a batch of matches, by default as json array of the extended matches.
+func (h *webhook) Post(ctx context.Context, players PlayerExtendedList) error {
+	var (
+		body []byte
+		err  error
+	)
+	if h.body != nil {
+		var buf bytes.Buffer
+		err = h.body.Execute(&buf, players)
+		body = buf.Bytes()
+	} else {
+		body, err = json.Marshal(players)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to render webhook body: %w", err)
+	}
+
+	backoff := webhookBackoff
+	for attempt := 0; ; attempt++ {
+		err = h.post(ctx, body)
+		if err == nil || attempt >= h.retries {
+			return err
+		}
+
+		slog.Warn("failed to post webhook, retrying", "error", err, "attempt", attempt+1, "delay", backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (h *webhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range h.headers {
+		req.Header[key] = values
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to post webhook: unexpected status code: %s", resp.Status)
+	}
+	return nil
+}
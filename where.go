@@ -0,0 +1,61 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/jxsl13/twlog-who-said/config"
+)
+
+// where keeps the matches that fulfill the where expression.
+// Matches whose evaluation fails are dropped.
+func (cli *CLI) where(players PlayerExtendedList) PlayerExtendedList {
+	filtered := make(PlayerExtendedList, 0, len(players))
+	for _, p := range players {
+		result, err := expr.Run(cli.cfg.WhereProgram, newWhereEnv(p))
+		if err != nil {
+			slog.Warn("failed to evaluate where expression", "file", p.File, "name", p.Nickname, "error", err)
+			continue
+		}
+		if result.(bool) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+func newWhereEnv(p PlayerExtended) config.WhereEnv {
+	var t time.Time
+	if p.Time != nil {
+		t = *p.Time
+	}
+	return config.WhereEnv{
+		Query:         p.Query,
+		Server:        p.Server,
+		File:          p.File,
+		Event:         p.Event,
+		Map:           p.Map,
+		Nickname:      p.Nickname,
+		Dummy:         p.Dummy,
+		MainNickname:  p.MainNickname,
+		ClientVersion: p.ClientVersion,
+		FinishTime:    p.FinishTime,
+		ID:            p.ID,
+		IP:            p.IP,
+		Hostname:      p.Hostname,
+		VPN:           p.VPN,
+		Banned:        p.Banned,
+		ASN:           int(p.ASN),
+		Organization:  p.Organization,
+		Text:          p.Text,
+		Language:      p.Language,
+		Labels:        p.Labels,
+		Toxicity:      p.Toxicity,
+		Line:          p.Line,
+		Time:          t,
+		Fields:        p.Fields,
+		Category:      p.Category,
+		Severity:      p.Severity,
+	}
+}
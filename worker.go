@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/twlog-who-said/config"
+	"github.com/spf13/cobra"
+)
+
+const workerScanPath = "/scan"
+
+// workerRequest is sent by the coordinator to every worker.
+type workerRequest struct {
+	Since   time.Time     `json:"since"`
+	Queries []workerQuery `json:"queries"`
+}
+
+// workerQuery is the part of a query that is needed in order to scan, the compiled regexes cannot be serialized.
+type workerQuery struct {
+	Name        string          `json:"name,omitempty"`
+	Phrase      string          `json:"phrase"`
+	File        string          `json:"file,omitempty"`
+	Patterns    []workerPattern `json:"patterns,omitempty"`
+	Category    string          `json:"category,omitempty"`
+	Severity    int             `json:"severity,omitempty"`
+	MinSeverity int             `json:"min_severity,omitempty"`
+	EventTypes  []string        `json:"event_types,omitempty"`
+	Hashes      bool            `json:"hashes,omitempty"`
+}
+
+type workerPattern struct {
+	Category string `json:"category"`
+	Severity int    `json:"severity"`
+	Regex    string `json:"regex"`
+}
+
+// workerMessage is a single line of the ndjson response of a worker.
+// The last line contains the error in case the scan did not complete.
+type workerMessage struct {
+	Match *PlayerExtended `json:"match,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+func newWorkerQuery(q *config.Query) workerQuery {
+	wq := workerQuery{
+		Name:        q.Name,
+		Phrase:      q.PhraseRegex,
+		File:        q.FileRegex,
+		Category:    q.Category,
+		Severity:    q.Severity,
+		MinSeverity: q.MinSeverity,
+		EventTypes:  q.EventTypes,
+		Hashes:      q.Hashes,
+	}
+	for _, p := range q.Patterns {
+		wq.Patterns = append(wq.Patterns, workerPattern{
+			Category: p.Category,
+			Severity: p.Severity,
+			Regex:    p.Regexp.String(),
+		})
+	}
+	return wq
+}
+
+func (wq workerQuery) toQuery() (*config.Query, error) {
+	q := &config.Query{
+		Name:        wq.Name,
+		PhraseRegex: wq.Phrase,
+		FileRegex:   wq.File,
+		Format:      config.FormatJSON,
+		Category:    wq.Category,
+		Severity:    wq.Severity,
+		MinSeverity: wq.MinSeverity,
+		EventTypes:  wq.EventTypes,
+		Hashes:      wq.Hashes,
+	}
+	for _, p := range wq.Patterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern regex: %w", err)
+		}
+		q.Patterns = append(q.Patterns, config.Pattern{
+			Category: p.Category,
+			Severity: p.Severity,
+			Regexp:   re,
+		})
+	}
+	return q, q.Validate()
+}
+
+// scanWorkers sends the queries to all workers and collects their matches.
+// The matches of the other workers are kept in case a worker fails.
+func (cli *CLI) scanWorkers(ctx context.Context, since time.Time) (PlayerExtendedList, error) {
+	req := workerRequest{
+		Since:   since,
+		Queries: make([]workerQuery, 0, len(cli.cfg.Queries())),
+	}
+	for _, q := range cli.cfg.Queries() {
+		req.Queries = append(req.Queries, newWorkerQuery(q))
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal worker request: %w", err)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		players = make(PlayerExtendedList, 0, 64)
+		errs    = make([]error, 0)
+	)
+	for _, workerURL := range cli.cfg.WorkerURLs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			workerPlayers, err := cli.scanWorker(ctx, workerURL, body)
+			mu.Lock()
+			defer mu.Unlock()
+			players = append(players, workerPlayers...)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("worker %s: %w", workerURL, err))
+			}
+		}()
+	}
+	wg.Wait()
+	return players, errors.Join(errs...)
+}
+
+func (cli *CLI) scanWorker(ctx context.Context, workerURL string, body []byte) (PlayerExtendedList, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(workerURL, "/")+workerScanPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cli.cfg.WorkerToken)
+
+	slog.Info("scanning on worker", "worker", workerURL)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %s", resp.Status)
+	}
+
+	players := make(PlayerExtendedList, 0, 64)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg workerMessage
+		err = json.Unmarshal(scanner.Bytes(), &msg)
+		if err != nil {
+			return players, fmt.Errorf("invalid worker response: %w", err)
+		}
+		if msg.Error != "" {
+			return players, errors.New(msg.Error)
+		}
+		if msg.Match != nil {
+			players = append(players, *msg.Match)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return players, fmt.Errorf("failed to read worker response: %w", err)
+	}
+	slog.Info("worker scan completed", "worker", workerURL, "matches", len(players))
+	return players, nil
+}
+
+// runWorker serves scans of the local search dir to a coordinator until the process is stopped.
+func (cli *CLI) runWorker(cmd *cobra.Command) error {
+	listener, err := net.Listen("tcp", cli.cfg.WorkerListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen for coordinator requests: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+workerScanPath, cli.handleWorkerScan)
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return cli.ctx
+		},
+	}
+
+	stop := context.AfterFunc(cli.ctx, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	})
+	defer stop()
+
+	slog.Info("waiting for coordinator requests", "address", listener.Addr().String(), "search_dir", cli.cfg.SearchDir)
+	err = srv.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		slog.Info("stopping worker", "reason", context.Cause(cli.ctx))
+		return nil
+	}
+	return err
+}
+
+func (cli *CLI) handleWorkerScan(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(cli.cfg.WorkerToken)) != 1 {
+		slog.Warn("rejected coordinator request with invalid token", "remote", r.RemoteAddr)
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	var req workerRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	queries := make([]*config.Query, 0, len(req.Queries))
+	for _, wq := range req.Queries {
+		q, err := wq.toQuery()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid query %s: %v", wq.Name, err), http.StatusBadRequest)
+			return
+		}
+		queries = append(queries, q)
+	}
+	if len(queries) == 0 {
+		http.Error(w, "no queries", http.StatusBadRequest)
+		return
+	}
+
+	// every request scans with its own queries
+	worker := *cli
+	worker.cfg.SetQueries(queries)
+
+	slog.Info("scanning for coordinator", "remote", r.RemoteAddr, "queries", len(queries))
+	start := time.Now()
+	// matches must not be exposed without audit log entry
+	err = worker.auditStarted("worker", start)
+	if err != nil {
+		slog.Error("failed to write audit log", "error", err)
+		http.Error(w, "failed to write audit log", http.StatusInternalServerError)
+		return
+	}
+
+	// the matches of every file are sent as soon as the file is scanned, so the memory
+	// of the worker does not grow with the number of matches
+	ctx, cancel := context.WithCancelCause(r.Context())
+	defer cancel(nil)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	matches := 0
+	worker.emit = func(players PlayerExtendedList) {
+		if ctx.Err() != nil {
+			return
+		}
+		for idx := range players {
+			err := enc.Encode(workerMessage{Match: &players[idx]})
+			if err != nil {
+				// the coordinator is gone, there is no point in scanning further
+				cancel(fmt.Errorf("failed to send matches to coordinator: %w", err))
+				return
+			}
+			matches++
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	stats := &ScanStats{}
+	_, scanErr := worker.scan(ctx, stats, req.Since)
+	if scanErr != nil && ctx.Err() == nil {
+		_ = enc.Encode(workerMessage{Error: scanErr.Error()})
+	}
+	err = worker.audit("worker", start, matches, scanErr)
+	if err != nil {
+		slog.Error("failed to write audit log", "error", err)
+	}
+	if scanErr != nil {
+		slog.Warn("scan for coordinator did not complete", "remote", r.RemoteAddr, "error", scanErr, "stats", stats.String())
+		return
+	}
+	slog.Info("scan for coordinator completed", "remote", r.RemoteAddr, "stats", stats.String())
+}